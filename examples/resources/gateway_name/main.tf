@@ -29,9 +29,15 @@ data "grid_gateway_domain" "domain" {
   name = "example2"
 }
 
+# reserving the name separately keeps it when the gateway is replaced or moved to another node
+resource "grid_name_contract" "name" {
+  name = "example2"
+}
+
 resource "grid_name_proxy" "p1" {
   node            = grid_scheduler.sched.nodes["gateway"]
-  name            = "example2"
+  name            = grid_name_contract.name.name
+  name_contract   = grid_name_contract.name.contract_id
   backends        = [format("http://69.164.223.208")]
   tls_passthrough = false
 }
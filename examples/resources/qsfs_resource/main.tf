@@ -0,0 +1,75 @@
+terraform {
+  required_providers {
+    grid = {
+      source = "threefoldtech/grid"
+    }
+  }
+}
+provider "grid" {
+}
+
+locals {
+  name = "qsfstest"
+}
+
+resource "random_bytes" "mycelium_key" {
+  length = 32
+}
+
+resource "grid_network" "net1" {
+  name     = local.name
+  nodes    = [11]
+  ip_range = "10.1.0.0/16"
+  mycelium_keys = {
+    "11" = random_bytes.mycelium_key.hex
+  }
+  description = "qsfs network"
+}
+
+resource "grid_qsfs" "qsfs" {
+  name                  = local.name
+  node                  = 11
+  network_name          = grid_network.net1.name
+  cache                 = 2048
+  minimal_shards        = 2
+  expected_shards       = 4
+  redundant_groups      = 0
+  redundant_nodes       = 0
+  max_zdb_data_dir_size = 512
+  encryption_key        = "4d778ba3216e4da4231540c92a55f06157cabba802f9b68fb0f78375d2e825af"
+  zdb_size              = 10
+  zdb_password          = "pass"
+
+  // a dead backend node could be replaced in place by another node
+  metadata_nodes = [11, 12, 13, 14]
+  // the group zdbs are named after the group name, so groups could be added or removed in place
+  groups {
+    name  = "group_a"
+    nodes = [11, 12, 13, 14]
+  }
+
+  vm {
+    name        = "vm"
+    flist       = "https://hub.grid.tf/tf-official-apps/base:latest.flist"
+    cpu         = 2
+    memory      = 1024
+    entrypoint  = "/sbin/zinit init"
+    planetary   = true
+    mount_point = "/qsfs"
+    env_vars = {
+      SSH_KEY = file("~/.ssh/id_rsa.pub")
+    }
+  }
+}
+
+output "metrics" {
+  value = grid_qsfs.qsfs.metrics_endpoint
+}
+
+output "ygg_ip" {
+  value = grid_qsfs.qsfs.vm[0].planetary_ip
+}
+
+output "zdb_deployment_ids" {
+  value = grid_qsfs.qsfs.zdb_deployment_ids
+}
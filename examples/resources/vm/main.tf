@@ -0,0 +1,77 @@
+terraform {
+  required_providers {
+    grid = {
+      source = "threefoldtech/grid"
+    }
+  }
+}
+provider "grid" {
+}
+
+locals {
+  name = "testvm"
+}
+
+resource "random_bytes" "mycelium_ip_seed" {
+  length = 6
+}
+
+resource "random_bytes" "mycelium_key" {
+  length = 32
+}
+
+resource "grid_scheduler" "sched" {
+  requests {
+    name      = "node1"
+    cru       = 2
+    sru       = 1024 * 6
+    mru       = 1024
+    yggdrasil = false
+    wireguard = false
+  }
+}
+
+resource "grid_network" "net1" {
+  name     = local.name
+  nodes    = [grid_scheduler.sched.nodes["node1"]]
+  ip_range = "10.1.0.0/16"
+  mycelium_keys = {
+    format("%s", grid_scheduler.sched.nodes["node1"]) = random_bytes.mycelium_key.hex
+  }
+  description = "vm network"
+}
+
+resource "grid_vm" "vm1" {
+  name             = local.name
+  node             = grid_scheduler.sched.nodes["node1"]
+  network_name     = grid_network.net1.name
+  flist            = "https://hub.grid.tf/tf-official-apps/base:latest.flist"
+  cpu              = 2
+  memory           = 1024
+  entrypoint       = "/sbin/zinit init"
+  mycelium_ip_seed = random_bytes.mycelium_ip_seed.hex
+  env_vars = {
+    SSH_KEY = file("~/.ssh/id_rsa.pub")
+  }
+
+  disks {
+    name = "data"
+    size = 5
+  }
+  mounts {
+    name        = "data"
+    mount_point = "/data"
+  }
+}
+
+output "vm1_ip" {
+  value = grid_vm.vm1.ip
+}
+
+output "vm1_mycelium_ip" {
+  value = grid_vm.vm1.mycelium_ip
+}
+
+output "vm1_console_url" {
+  value = grid_vm.vm1.console_url
+}
@@ -0,0 +1,59 @@
+terraform {
+  required_providers {
+    grid = {
+      source = "threefoldtech/grid"
+    }
+  }
+}
+
+provider "grid" {
+}
+
+locals {
+  metas = ["meta1", "meta2", "meta3", "meta4"]
+  datas = ["data1", "data2", "data3", "data4"]
+}
+
+resource "grid_scheduler" "sched" {
+  requests {
+    name      = "node"
+    cru       = 0
+    sru       = 0
+    mru       = 0
+    hru       = 8 * 10 * 1024
+    yggdrasil = true
+    wireguard = false
+  }
+}
+
+resource "grid_zdb" "metas" {
+  for_each    = toset(local.metas)
+  node        = grid_scheduler.sched.nodes["node"]
+  name        = each.value
+  description = "qsfs metadata zdb"
+  password    = "password"
+  size        = 10
+  mode        = "user"
+}
+
+resource "grid_zdb" "datas" {
+  for_each    = toset(local.datas)
+  node        = grid_scheduler.sched.nodes["node"]
+  name        = each.value
+  description = "qsfs data zdb"
+  password    = "password"
+  size        = 10
+  mode        = "seq"
+}
+
+output "metas" {
+  value = {
+    for name, zdb in grid_zdb.metas : name => format("[%s]:%d/%s", zdb.ips[1], zdb.port, zdb.namespace)
+  }
+}
+
+output "datas" {
+  value = {
+    for name, zdb in grid_zdb.datas : name => format("[%s]:%d/%s", zdb.ips[1], zdb.port, zdb.namespace)
+  }
+}
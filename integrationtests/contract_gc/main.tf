@@ -0,0 +1,72 @@
+variable "with_gc" {
+  type = bool
+}
+
+terraform {
+  required_providers {
+    grid = {
+      source  = "threefoldtechdev.com/providers/grid"
+      version = "0.2"
+    }
+  }
+}
+
+provider "grid" {
+}
+
+resource "grid_scheduler" "scheduler" {
+  requests {
+    name      = "node"
+    hru       = 2 * 1024
+    yggdrasil = false
+    wireguard = false
+  }
+}
+
+resource "random_string" "prefix" {
+  length  = 8
+  special = false
+}
+
+resource "grid_zdb" "kept" {
+  node          = grid_scheduler.scheduler.nodes["node"]
+  name          = "${random_string.prefix.result}kept"
+  solution_type = random_string.prefix.result
+  size          = 1
+  password      = "password"
+  mode          = "user"
+}
+
+# this zdb isn't in keep_contracts, so it's reported as orphaned
+resource "grid_zdb" "orphaned" {
+  node          = grid_scheduler.scheduler.nodes["node"]
+  name          = "${random_string.prefix.result}orphaned"
+  solution_type = random_string.prefix.result
+  size          = 1
+  password      = "password"
+  mode          = "user"
+}
+
+resource "grid_contract_gc" "gc" {
+  count          = var.with_gc ? 1 : 0
+  prefix         = random_string.prefix.result
+  keep_contracts = [grid_zdb.kept.id]
+  grace_period   = "1m"
+  dry_run        = true
+}
+
+output "kept_contract_id" {
+  value = grid_zdb.kept.id
+}
+
+output "orphaned_contract_id" {
+  value = grid_zdb.orphaned.id
+}
+
+output "orphaned_contracts" {
+  value = var.with_gc ? grid_contract_gc.gc[0].orphaned_contracts : []
+}
+
+output "canceled_contracts" {
+  value = var.with_gc ? grid_contract_gc.gc[0].canceled_contracts : []
+}
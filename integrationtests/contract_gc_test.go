@@ -0,0 +1,63 @@
+//go:build integration
+// +build integration
+
+// Package integrationtests includes integration tests for deploying solutions on the tf grid, and some utilities to test these solutions.
+package integrationtests
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gruntwork-io/terratest/modules/terraform"
+	"github.com/stretchr/testify/require"
+	"github.com/threefoldtech/terraform-provider-grid/internal/provider/scheduler"
+)
+
+func TestContractGC(t *testing.T) {
+	t.Run("contract_gc_test", func(t *testing.T) {
+		/* Test case for reporting the orphaned node contracts.
+		   **Test Scenario**
+		   - Deploy two zdbs with the collector prefix as their solution type.
+		   - Wait for the grace period to pass.
+		   - Run the collector in dry run mode, keeping only the first zdb contract.
+		   - Assert that only the second zdb contract is reported as orphaned.
+		   - Assert that no contract is canceled.
+		   - Destroy the deployment
+		*/
+
+		terraformOptions := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
+			TerraformDir: "./contract_gc",
+			Vars: map[string]interface{}{
+				"with_gc": false,
+			},
+		})
+		defer terraform.Destroy(t, terraformOptions)
+
+		_, err := terraform.InitAndApplyE(t, terraformOptions)
+		if err != nil &&
+			(strings.Contains(err.Error(), scheduler.NoNodesFoundErr.Error()) ||
+				strings.Contains(err.Error(), "error creating threefold plugin client")) {
+			t.Skip("couldn't find any available nodes")
+			return
+		}
+
+		require.NoError(t, err)
+
+		keptContractID := terraform.Output(t, terraformOptions, "kept_contract_id")
+		require.NotEmpty(t, keptContractID)
+
+		orphanedContractID := terraform.Output(t, terraformOptions, "orphaned_contract_id")
+		require.NotEmpty(t, orphanedContractID)
+
+		// the contracts younger than the grace period are never collected
+		time.Sleep(90 * time.Second)
+
+		terraformOptions.Vars["with_gc"] = true
+		_, err = terraform.ApplyE(t, terraformOptions)
+		require.NoError(t, err)
+
+		require.Equal(t, []string{orphanedContractID}, terraform.OutputList(t, terraformOptions, "orphaned_contracts"))
+		require.Empty(t, terraform.OutputList(t, terraformOptions, "canceled_contracts"))
+	})
+}
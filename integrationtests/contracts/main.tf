@@ -0,0 +1,58 @@
+terraform {
+  required_providers {
+    grid = {
+      source  = "threefoldtechdev.com/providers/grid"
+      version = "0.2"
+    }
+  }
+}
+
+provider "grid" {
+}
+
+resource "grid_scheduler" "scheduler" {
+  requests {
+    name      = "node"
+    hru       = 1024
+    yggdrasil = false
+    wireguard = false
+  }
+}
+
+resource "random_string" "name" {
+  length  = 8
+  special = false
+}
+
+resource "grid_zdb" "zdb1" {
+  node     = grid_scheduler.scheduler.nodes["node"]
+  name     = random_string.name.result
+  size     = 1
+  password = "password"
+  mode     = "user"
+}
+
+data "grid_contracts" "contracts" {
+  type = "node"
+  node = grid_zdb.zdb1.node
+}
+
+output "zdb_contract_id" {
+  value = grid_zdb.zdb1.id
+}
+
+output "node_contract_ids" {
+  value = data.grid_contracts.contracts.node_contract_ids
+}
+
+output "name_contract_ids" {
+  value = data.grid_contracts.contracts.name_contract_ids
+}
+
+output "contract_node_ids" {
+  value = distinct([for contract in data.grid_contracts.contracts.contracts : contract.node_id])
+}
+
+output "node" {
+  value = grid_zdb.zdb1.node
+}
@@ -0,0 +1,64 @@
+//go:build integration
+// +build integration
+
+// Package integrationtests includes integration tests for deploying solutions on the tf grid, and some utilities to test these solutions.
+package integrationtests
+
+import (
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gruntwork-io/terratest/modules/terraform"
+	"github.com/stretchr/testify/require"
+	"github.com/threefoldtech/terraform-provider-grid/internal/provider/scheduler"
+)
+
+func TestContractsDataSource(t *testing.T) {
+	t.Run("contracts_test", func(t *testing.T) {
+		/* Test case for listing the contracts of the twin.
+		   **Test Scenario**
+		   - Deploy a zdb.
+		   - List the node contracts of the twin on the zdb node.
+		   - Assert that the zdb contract is listed.
+		   - Assert that only node contracts on the zdb node are listed.
+		   - Destroy the deployment
+		*/
+
+		terraformOptions := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
+			TerraformDir: "./contracts",
+		})
+		defer terraform.Destroy(t, terraformOptions)
+
+		_, err := terraform.InitAndApplyE(t, terraformOptions)
+		if err != nil &&
+			(strings.Contains(err.Error(), scheduler.NoNodesFoundErr.Error()) ||
+				strings.Contains(err.Error(), "error creating threefold plugin client")) {
+			t.Skip("couldn't find any available nodes")
+			return
+		}
+
+		require.NoError(t, err)
+
+		zdbContractID := terraform.Output(t, terraformOptions, "zdb_contract_id")
+		require.NotEmpty(t, zdbContractID)
+
+		// the grid proxy could take a while to index the new contract, so refresh the data source until it's listed
+		nodeContractIDs := terraform.OutputList(t, terraformOptions, "node_contract_ids")
+		for i := 0; i < 5 && !slices.Contains(nodeContractIDs, zdbContractID); i++ {
+			time.Sleep(10 * time.Second)
+
+			_, err = terraform.ApplyE(t, terraformOptions)
+			require.NoError(t, err)
+
+			nodeContractIDs = terraform.OutputList(t, terraformOptions, "node_contract_ids")
+		}
+		require.Contains(t, nodeContractIDs, zdbContractID)
+
+		require.Empty(t, terraform.OutputList(t, terraformOptions, "name_contract_ids"))
+
+		node := terraform.Output(t, terraformOptions, "node")
+		require.Equal(t, []string{node}, terraform.OutputList(t, terraformOptions, "contract_node_ids"))
+	})
+}
@@ -0,0 +1,60 @@
+terraform {
+  required_providers {
+    grid = {
+      source  = "threefoldtechdev.com/providers/grid"
+      version = "0.2"
+    }
+  }
+}
+
+provider "grid" {
+}
+
+data "grid_cost_estimate" "small" {
+  cru              = 1
+  mru              = 2048
+  sru              = 10240
+  public_ips       = 1
+  balance_discount = false
+}
+
+data "grid_cost_estimate" "large" {
+  cru              = 2
+  mru              = 4096
+  sru              = 20480
+  public_ips       = 2
+  balance_discount = false
+}
+
+data "grid_cost_estimate" "dedicated" {
+  cru              = 1
+  mru              = 2048
+  sru              = 10240
+  public_ips       = 1
+  dedicated        = true
+  balance_discount = false
+}
+
+output "small_monthly_cost_tft" {
+  value = data.grid_cost_estimate.small.monthly_cost_tft
+}
+
+output "small_monthly_cost_usd" {
+  value = data.grid_cost_estimate.small.monthly_cost_usd
+}
+
+output "small_discount_package" {
+  value = data.grid_cost_estimate.small.discount_package
+}
+
+output "tft_price" {
+  value = data.grid_cost_estimate.small.tft_price
+}
+
+output "large_monthly_cost_usd" {
+  value = data.grid_cost_estimate.large.monthly_cost_usd
+}
+
+output "dedicated_monthly_cost_usd" {
+  value = data.grid_cost_estimate.dedicated.monthly_cost_usd
+}
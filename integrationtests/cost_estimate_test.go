@@ -0,0 +1,63 @@
+//go:build integration
+// +build integration
+
+// Package integrationtests includes integration tests for deploying solutions on the tf grid, and some utilities to test these solutions.
+package integrationtests
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/gruntwork-io/terratest/modules/terraform"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCostEstimateDataSource(t *testing.T) {
+	outputFloat := func(t *testing.T, terraformOptions *terraform.Options, key string) float64 {
+		value, err := strconv.ParseFloat(terraform.Output(t, terraformOptions, key), 64)
+		require.NoError(t, err)
+		return value
+	}
+
+	t.Run("cost_estimate_test", func(t *testing.T) {
+		/* Test case for estimating the cost of some capacity.
+		   **Test Scenario**
+		   - Estimate the cost of some capacity, double of it, and the same capacity on a dedicated node.
+		   - Check that the costs are positive.
+		   - Assert that the usd cost matches the tft cost and price.
+		   - Assert that the cost of the doubled capacity is doubled.
+		   - Assert that the dedicated nodes discount is applied.
+		*/
+
+		terraformOptions := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
+			TerraformDir: "./cost_estimate",
+		})
+		defer terraform.Destroy(t, terraformOptions)
+
+		_, err := terraform.InitAndApplyE(t, terraformOptions)
+		if err != nil && strings.Contains(err.Error(), "error creating threefold plugin client") {
+			t.Skip("couldn't create the threefold plugin client")
+			return
+		}
+
+		require.NoError(t, err)
+
+		smallTFT := outputFloat(t, terraformOptions, "small_monthly_cost_tft")
+		require.Greater(t, smallTFT, 0.0)
+
+		smallUSD := outputFloat(t, terraformOptions, "small_monthly_cost_usd")
+		require.Greater(t, smallUSD, 0.0)
+
+		tftPrice := outputFloat(t, terraformOptions, "tft_price")
+		require.InEpsilon(t, smallUSD, smallTFT*tftPrice, 1e-6)
+
+		require.Equal(t, "none", terraform.Output(t, terraformOptions, "small_discount_package"))
+
+		largeUSD := outputFloat(t, terraformOptions, "large_monthly_cost_usd")
+		require.InEpsilon(t, 2*smallUSD, largeUSD, 1e-6)
+
+		dedicatedUSD := outputFloat(t, terraformOptions, "dedicated_monthly_cost_usd")
+		require.Less(t, dedicatedUSD, smallUSD)
+	})
+}
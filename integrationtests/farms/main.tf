@@ -0,0 +1,44 @@
+variable "free_ips" {
+  type = number
+}
+
+variable "limit" {
+  type = number
+}
+
+terraform {
+  required_providers {
+    grid = {
+      source  = "threefoldtechdev.com/providers/grid"
+      version = "0.2"
+    }
+  }
+}
+
+provider "grid" {
+}
+
+data "grid_farms" "farms" {
+  free_ips = var.free_ips
+  limit    = var.limit
+}
+
+data "grid_farms" "by_name" {
+  name = data.grid_farms.farms.farms[0].name
+}
+
+output "farm_ids" {
+  value = data.grid_farms.farms.farm_ids
+}
+
+output "free_ips" {
+  value = [for farm in data.grid_farms.farms.farms : length([for ip in farm.public_ips : ip if !ip.taken])]
+}
+
+output "first_farm_id" {
+  value = data.grid_farms.farms.farm_ids[0]
+}
+
+output "farm_ids_by_name" {
+  value = data.grid_farms.by_name.farm_ids
+}
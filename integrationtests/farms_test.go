@@ -0,0 +1,62 @@
+//go:build integration
+// +build integration
+
+// Package integrationtests includes integration tests for deploying solutions on the tf grid, and some utilities to test these solutions.
+package integrationtests
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/gruntwork-io/terratest/modules/terraform"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFarmsDataSource(t *testing.T) {
+	t.Run("farms_test", func(t *testing.T) {
+		/* Test case for searching the grid farms.
+		   **Test Scenario**
+		   - Search for farms with a minimum count of free public ips.
+		   - Check that the farm ids are not empty and within the limit.
+		   - Assert that all returned farms have enough untaken public ips.
+		   - Search for the first farm by its name.
+		   - Assert that the farm is found by its name.
+		*/
+
+		const (
+			freeIPs = 1
+			limit   = 5
+		)
+
+		terraformOptions := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
+			TerraformDir: "./farms",
+			Vars: map[string]interface{}{
+				"free_ips": freeIPs,
+				"limit":    limit,
+			},
+		})
+		defer terraform.Destroy(t, terraformOptions)
+
+		_, err := terraform.InitAndApplyE(t, terraformOptions)
+		if err != nil && strings.Contains(err.Error(), "error creating threefold plugin client") {
+			t.Skip("couldn't create the threefold plugin client")
+			return
+		}
+
+		require.NoError(t, err)
+
+		farmIDs := terraform.OutputList(t, terraformOptions, "farm_ids")
+		require.NotEmpty(t, farmIDs)
+		require.LessOrEqual(t, len(farmIDs), limit)
+
+		for _, free := range terraform.OutputList(t, terraformOptions, "free_ips") {
+			ips, err := strconv.Atoi(free)
+			require.NoError(t, err)
+			require.GreaterOrEqual(t, ips, freeIPs)
+		}
+
+		firstFarmID := terraform.Output(t, terraformOptions, "first_farm_id")
+		require.Contains(t, terraform.OutputList(t, terraformOptions, "farm_ids_by_name"), firstFarmID)
+	})
+}
@@ -0,0 +1,123 @@
+variable "public_key" {
+  type = string
+}
+
+variable "groups" {
+  type = list(string)
+}
+
+terraform {
+  required_providers {
+    grid = {
+      source  = "threefoldtechdev.com/providers/grid"
+      version = "0.2"
+    }
+  }
+}
+
+provider "grid" {
+}
+
+resource "random_string" "name" {
+  length  = 8
+  special = false
+}
+
+resource "grid_scheduler" "sched" {
+  requests {
+    name      = "node1"
+    cru       = 2
+    sru       = 2 * 1024
+    mru       = 2 * 1024
+    hru       = 8 * 1024
+    distinct  = true
+    yggdrasil = true
+    wireguard = false
+  }
+  requests {
+    name      = "node2"
+    hru       = 8 * 1024
+    distinct  = true
+    yggdrasil = true
+    wireguard = false
+  }
+  requests {
+    name      = "node3"
+    hru       = 8 * 1024
+    distinct  = true
+    yggdrasil = true
+    wireguard = false
+  }
+}
+
+locals {
+  nodes = [
+    grid_scheduler.sched.nodes["node1"],
+    grid_scheduler.sched.nodes["node2"],
+    grid_scheduler.sched.nodes["node3"],
+  ]
+}
+
+resource "grid_network" "net1" {
+  name        = random_string.name.result
+  nodes       = [grid_scheduler.sched.nodes["node1"]]
+  ip_range    = "10.1.0.0/16"
+  description = "qsfs network"
+}
+
+resource "grid_qsfs" "qsfs" {
+  name                  = random_string.name.result
+  node                  = grid_scheduler.sched.nodes["node1"]
+  network_name          = grid_network.net1.name
+  cache                 = 2048
+  minimal_shards        = 2
+  expected_shards       = 3
+  redundant_groups      = 0
+  redundant_nodes       = 0
+  max_zdb_data_dir_size = 512
+  encryption_key        = "4d778ba3216e4da4231540c92a55f06157cabba802f9b68fb0f78375d2e825af"
+  zdb_size              = 1
+  zdb_password          = "password"
+
+  metadata_nodes = local.nodes
+  dynamic "groups" {
+    for_each = var.groups
+    content {
+      name  = groups.value
+      nodes = local.nodes
+    }
+  }
+
+  vm {
+    name        = "vm"
+    flist       = "https://hub.grid.tf/tf-official-apps/base:latest.flist"
+    cpu         = 2
+    memory      = 1024
+    entrypoint  = "/sbin/zinit init"
+    planetary   = true
+    mount_point = "/qsfs"
+    env_vars = {
+      SSH_KEY = "${var.public_key}"
+    }
+  }
+}
+
+output "metrics" {
+  value = grid_qsfs.qsfs.metrics_endpoint
+}
+
+output "ygg_ip" {
+  value = grid_qsfs.qsfs.vm[0].planetary_ip
+}
+
+output "deployment_id" {
+  value = grid_qsfs.qsfs.deployment_id
+}
+
+output "zdb_deployment_ids" {
+  value = grid_qsfs.qsfs.zdb_deployment_ids
+}
+
+output "group_backends" {
+  value = length(grid_qsfs.qsfs.group_backends)
+}
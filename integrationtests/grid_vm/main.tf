@@ -0,0 +1,78 @@
+
+variable "public_key" {
+  type = string
+}
+
+variable "disk_size" {
+  type = number
+}
+
+terraform {
+  required_providers {
+    grid = {
+      source  = "threefoldtechdev.com/providers/grid"
+      version = "0.2"
+    }
+  }
+}
+
+provider "grid" {
+}
+
+resource "random_string" "name" {
+  length  = 8
+  special = false
+}
+
+resource "grid_scheduler" "scheduler" {
+  requests {
+    name      = "node"
+    cru       = 1
+    sru       = 5 * 1024 + 1024
+    mru       = 1024
+    yggdrasil = true
+    wireguard = false
+  }
+}
+
+resource "grid_network" "net1" {
+  nodes       = [grid_scheduler.scheduler.nodes["node"]]
+  ip_range    = "10.1.0.0/16"
+  name        = random_string.name.result
+  description = "vm network"
+}
+
+resource "grid_vm" "vm1" {
+  name         = "vm1"
+  node         = grid_scheduler.scheduler.nodes["node"]
+  network_name = grid_network.net1.name
+  flist        = "https://hub.grid.tf/tf-official-apps/threefoldtech-ubuntu-20.04.flist"
+  cpu          = 1
+  memory       = 1024
+  entrypoint   = "/init.sh"
+  planetary    = true
+  env_vars = {
+    SSH_KEY = "${var.public_key}"
+  }
+
+  disks {
+    name = "data"
+    size = var.disk_size
+  }
+  mounts {
+    name        = "data"
+    mount_point = "/app"
+  }
+}
+
+output "vm_id" {
+  value = grid_vm.vm1.id
+}
+
+output "vm_ip" {
+  value = grid_vm.vm1.ip
+}
+
+output "ygg_ip" {
+  value = grid_vm.vm1.planetary_ip
+}
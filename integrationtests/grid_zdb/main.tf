@@ -0,0 +1,54 @@
+variable "password" {
+  type = string
+}
+
+variable "size" {
+  type = number
+}
+
+terraform {
+  required_providers {
+    grid = {
+      source  = "threefoldtechdev.com/providers/grid"
+      version = "0.2"
+    }
+  }
+}
+
+provider "grid" {
+}
+
+resource "grid_scheduler" "scheduler" {
+  requests {
+    name      = "node"
+    hru       = 20 * 1024
+    yggdrasil = true
+    wireguard = false
+  }
+}
+
+resource "random_string" "name" {
+  length  = 8
+  special = false
+}
+
+resource "grid_zdb" "zdb1" {
+  node        = grid_scheduler.scheduler.nodes["node"]
+  name        = random_string.name.result
+  size        = var.size
+  description = "zdb description"
+  password    = var.password
+  mode        = "user"
+}
+
+output "zdb_id" {
+  value = grid_zdb.zdb1.id
+}
+
+output "zdb1_endpoint" {
+  value = format("[%s]:%d", grid_zdb.zdb1.ips[1], grid_zdb.zdb1.port)
+}
+
+output "zdb1_namespace" {
+  value = grid_zdb.zdb1.namespace
+}
@@ -0,0 +1,54 @@
+variable "with_proxy" {
+  type = bool
+}
+
+terraform {
+  required_providers {
+    grid = {
+      source  = "threefoldtechdev.com/providers/grid"
+      version = "0.2"
+    }
+  }
+}
+
+provider "grid" {
+}
+
+resource "random_string" "name" {
+  length  = 8
+  special = false
+  upper   = false
+}
+
+resource "grid_scheduler" "sched" {
+  requests {
+    name          = "gateway"
+    public_config = true
+    yggdrasil     = false
+    wireguard     = false
+  }
+}
+
+resource "grid_name_contract" "name" {
+  name = random_string.name.result
+}
+
+resource "grid_name_proxy" "p1" {
+  count         = var.with_proxy ? 1 : 0
+  node          = grid_scheduler.sched.nodes["gateway"]
+  name          = grid_name_contract.name.name
+  name_contract = grid_name_contract.name.contract_id
+  backends      = ["http://69.164.223.208"]
+}
+
+output "contract_id" {
+  value = grid_name_contract.name.contract_id
+}
+
+output "proxy_name_contract_id" {
+  value = var.with_proxy ? grid_name_proxy.p1[0].name_contract_id : 0
+}
+
+output "fqdn" {
+  value = var.with_proxy ? grid_name_proxy.p1[0].fqdn : ""
+}
@@ -0,0 +1,63 @@
+//go:build integration
+// +build integration
+
+// Package integrationtests includes integration tests for deploying solutions on the tf grid, and some utilities to test these solutions.
+package integrationtests
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/gruntwork-io/terratest/modules/terraform"
+	"github.com/stretchr/testify/require"
+	"github.com/threefoldtech/terraform-provider-grid/internal/provider/scheduler"
+)
+
+func TestNameContract(t *testing.T) {
+	t.Run("name_contract_test", func(t *testing.T) {
+		/* Test case for reserving a gateway name and using it by a gateway.
+		   **Test Scenario**
+		   - Reserve a gateway name.
+		   - Deploy a gateway name proxy using the reserved name.
+		   - Check that the outputs not empty.
+		   - Assert that the gateway uses the reserved name contract.
+		   - Destroy the gateway.
+		   - Assert that the name contract is kept.
+		   - Destroy the deployment
+		*/
+
+		terraformOptions := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
+			TerraformDir: "./name_contract",
+			Vars: map[string]interface{}{
+				"with_proxy": true,
+			},
+		})
+		defer terraform.Destroy(t, terraformOptions)
+
+		_, err := terraform.InitAndApplyE(t, terraformOptions)
+		if err != nil &&
+			(strings.Contains(err.Error(), scheduler.NoNodesFoundErr.Error()) ||
+				strings.Contains(err.Error(), "error creating threefold plugin client")) {
+			t.Skip("couldn't find any available nodes")
+			return
+		}
+
+		require.NoError(t, err)
+
+		// Check that the outputs not empty
+		contractID := terraform.Output(t, terraformOptions, "contract_id")
+		require.NotEqual(t, "0", contractID)
+
+		fqdn := terraform.Output(t, terraformOptions, "fqdn")
+		require.NotEmpty(t, fqdn)
+
+		require.Equal(t, contractID, terraform.Output(t, terraformOptions, "proxy_name_contract_id"))
+
+		// destroy the gateway and keep the name
+		terraformOptions.Vars["with_proxy"] = false
+		_, err = terraform.ApplyE(t, terraformOptions)
+		require.NoError(t, err)
+
+		require.Equal(t, contractID, terraform.Output(t, terraformOptions, "contract_id"))
+	})
+}
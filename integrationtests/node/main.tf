@@ -0,0 +1,48 @@
+terraform {
+  required_providers {
+    grid = {
+      source  = "threefoldtechdev.com/providers/grid"
+      version = "0.2"
+    }
+  }
+}
+
+provider "grid" {
+}
+
+data "grid_nodes" "nodes" {
+  status = ["up"]
+  limit  = 1
+}
+
+data "grid_node" "node" {
+  node = data.grid_nodes.nodes.node_ids[0]
+}
+
+output "proxy_farm_id" {
+  value = data.grid_nodes.nodes.nodes[0].farm_id
+}
+
+output "proxy_twin_id" {
+  value = data.grid_nodes.nodes.nodes[0].twin_id
+}
+
+output "farm_id" {
+  value = data.grid_node.node.farm_id
+}
+
+output "farm_name" {
+  value = data.grid_node.node.farm_name
+}
+
+output "twin_id" {
+  value = data.grid_node.node.twin_id
+}
+
+output "zos_version" {
+  value = data.grid_node.node.zos_version
+}
+
+output "total_mru" {
+  value = data.grid_node.node.total_resources[0].mru
+}
@@ -0,0 +1,51 @@
+//go:build integration
+// +build integration
+
+// Package integrationtests includes integration tests for deploying solutions on the tf grid, and some utilities to test these solutions.
+package integrationtests
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/gruntwork-io/terratest/modules/terraform"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNodeDataSource(t *testing.T) {
+	t.Run("node_test", func(t *testing.T) {
+		/* Test case for reading the live details of a node.
+		   **Test Scenario**
+		   - Pick an up node from the grid proxy.
+		   - Read the node details from the node itself.
+		   - Check that the outputs not empty.
+		   - Assert that the node farm and twin match the grid proxy ones.
+		*/
+
+		terraformOptions := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
+			TerraformDir: "./node",
+		})
+		defer terraform.Destroy(t, terraformOptions)
+
+		_, err := terraform.InitAndApplyE(t, terraformOptions)
+		if err != nil && strings.Contains(err.Error(), "error creating threefold plugin client") {
+			t.Skip("couldn't create the threefold plugin client")
+			return
+		}
+
+		require.NoError(t, err)
+
+		// Check that the outputs not empty
+		farmName := terraform.Output(t, terraformOptions, "farm_name")
+		require.NotEmpty(t, farmName)
+
+		zosVersion := terraform.Output(t, terraformOptions, "zos_version")
+		require.NotEmpty(t, zosVersion)
+
+		totalMRU := terraform.Output(t, terraformOptions, "total_mru")
+		require.NotEqual(t, "0", totalMRU)
+
+		require.Equal(t, terraform.Output(t, terraformOptions, "proxy_farm_id"), terraform.Output(t, terraformOptions, "farm_id"))
+		require.Equal(t, terraform.Output(t, terraformOptions, "proxy_twin_id"), terraform.Output(t, terraformOptions, "twin_id"))
+	})
+}
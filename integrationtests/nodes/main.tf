@@ -0,0 +1,37 @@
+variable "free_mru" {
+  type = number
+}
+
+variable "limit" {
+  type = number
+}
+
+terraform {
+  required_providers {
+    grid = {
+      source  = "threefoldtechdev.com/providers/grid"
+      version = "0.2"
+    }
+  }
+}
+
+provider "grid" {
+}
+
+data "grid_nodes" "nodes" {
+  free_mru = var.free_mru
+  status   = ["up"]
+  limit    = var.limit
+}
+
+output "node_ids" {
+  value = data.grid_nodes.nodes.node_ids
+}
+
+output "statuses" {
+  value = distinct([for node in data.grid_nodes.nodes.nodes : node.status])
+}
+
+output "free_mru" {
+  value = [for node in data.grid_nodes.nodes.nodes : node.total_resources[0].mru - node.used_resources[0].mru]
+}
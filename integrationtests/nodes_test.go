@@ -0,0 +1,60 @@
+//go:build integration
+// +build integration
+
+// Package integrationtests includes integration tests for deploying solutions on the tf grid, and some utilities to test these solutions.
+package integrationtests
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/gruntwork-io/terratest/modules/terraform"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNodesDataSource(t *testing.T) {
+	t.Run("nodes_test", func(t *testing.T) {
+		/* Test case for searching the grid nodes.
+		   **Test Scenario**
+		   - Search for up nodes with a minimum free memory.
+		   - Check that the node ids are not empty and within the limit.
+		   - Assert that all returned nodes are up and have enough free memory.
+		*/
+
+		const (
+			freeMRU = 1024
+			limit   = 3
+		)
+
+		terraformOptions := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
+			TerraformDir: "./nodes",
+			Vars: map[string]interface{}{
+				"free_mru": freeMRU,
+				"limit":    limit,
+			},
+		})
+		defer terraform.Destroy(t, terraformOptions)
+
+		_, err := terraform.InitAndApplyE(t, terraformOptions)
+		if err != nil && strings.Contains(err.Error(), "error creating threefold plugin client") {
+			t.Skip("couldn't create the threefold plugin client")
+			return
+		}
+
+		require.NoError(t, err)
+
+		nodeIDs := terraform.OutputList(t, terraformOptions, "node_ids")
+		require.NotEmpty(t, nodeIDs)
+		require.LessOrEqual(t, len(nodeIDs), limit)
+
+		statuses := terraform.OutputList(t, terraformOptions, "statuses")
+		require.Equal(t, []string{"up"}, statuses)
+
+		for _, free := range terraform.OutputList(t, terraformOptions, "free_mru") {
+			mru, err := strconv.ParseUint(free, 10, 64)
+			require.NoError(t, err)
+			require.GreaterOrEqual(t, mru, uint64(freeMRU))
+		}
+	})
+}
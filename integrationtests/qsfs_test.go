@@ -81,4 +81,65 @@ func TestQSFS(t *testing.T) {
 		require.NoError(t, err)
 		require.Contains(t, string(output), "fs_syscalls{syscall=\"create\"} 1")
 	})
+
+	t.Run("grid_qsfs_test", func(t *testing.T) {
+		/* Test case for deploying a grid_qsfs resource and adding a group in place.
+		   **Test Scenario**
+		   - Deploy a qsfs with one backend group and a vm mounting it.
+		   - Check that the outputs not empty.
+		   - Write a file on qsfs.
+		   - Add a second backend group.
+		   - Assert that the vm deployment is kept and the file is still there.
+		   - Destroy the deployment
+		*/
+
+		terraformOptions := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
+			TerraformDir: "./grid_qsfs",
+			Vars: map[string]interface{}{
+				"public_key": publicKey,
+				"groups":     []string{"group_a"},
+			},
+		})
+		defer terraform.Destroy(t, terraformOptions)
+
+		_, err = terraform.InitAndApplyE(t, terraformOptions)
+		if err != nil &&
+			(strings.Contains(err.Error(), scheduler.NoNodesFoundErr.Error()) ||
+				strings.Contains(err.Error(), "error creating threefold plugin client")) {
+			t.Skip("couldn't find any available nodes")
+			return
+		}
+
+		require.NoError(t, err)
+
+		// Check that the outputs not empty
+		metrics := terraform.Output(t, terraformOptions, "metrics")
+		require.NotEmpty(t, metrics)
+
+		yggIP := terraform.Output(t, terraformOptions, "ygg_ip")
+		require.NotEmpty(t, yggIP)
+
+		deploymentID := terraform.Output(t, terraformOptions, "deployment_id")
+		require.NotEmpty(t, deploymentID)
+
+		zdbDeploymentIDs := terraform.OutputMap(t, terraformOptions, "zdb_deployment_ids")
+		require.Len(t, zdbDeploymentIDs, 3)
+		require.Equal(t, "1", terraform.Output(t, terraformOptions, "group_backends"))
+
+		// try write to a file in mounted disk
+		_, err = RemoteRun("root", yggIP, "cd /qsfs && echo hamadatext >> hamadafile", privateKey)
+		require.NoError(t, err)
+
+		// add a group in place
+		terraformOptions.Vars["groups"] = []string{"group_a", "group_b"}
+		_, err = terraform.ApplyE(t, terraformOptions)
+		require.NoError(t, err)
+
+		require.Equal(t, "2", terraform.Output(t, terraformOptions, "group_backends"))
+		require.Equal(t, deploymentID, terraform.Output(t, terraformOptions, "deployment_id"))
+
+		output, err := RemoteRun("root", yggIP, "cat /qsfs/hamadafile", privateKey)
+		require.NoError(t, err)
+		require.Contains(t, output, "hamadatext")
+	})
 }
@@ -0,0 +1,69 @@
+variable "public_key" {
+  type = string
+}
+
+terraform {
+  required_providers {
+    grid = {
+      source  = "threefoldtechdev.com/providers/grid"
+      version = "0.2"
+    }
+  }
+}
+
+provider "grid" {
+}
+
+resource "random_string" "name" {
+  length  = 8
+  special = false
+}
+
+resource "grid_scheduler" "sched" {
+  requests {
+    name      = "node"
+    cru       = 1
+    sru       = 1024
+    mru       = 1024
+    dedicated = true
+    yggdrasil = true
+    wireguard = false
+  }
+}
+
+resource "grid_rent_contract" "rent" {
+  node = grid_scheduler.sched.nodes["node"]
+}
+
+resource "grid_network" "net1" {
+  nodes       = [grid_rent_contract.rent.node]
+  ip_range    = "10.1.0.0/16"
+  name        = random_string.name.result
+  description = "rented node network"
+}
+
+resource "grid_vm" "vm1" {
+  name         = "vm1"
+  node         = grid_rent_contract.rent.node
+  network_name = grid_network.net1.name
+  flist        = "https://hub.grid.tf/tf-official-apps/threefoldtech-ubuntu-20.04.flist"
+  cpu          = 1
+  memory       = 1024
+  entrypoint   = "/init.sh"
+  planetary    = true
+  env_vars = {
+    SSH_KEY = "${var.public_key}"
+  }
+}
+
+output "contract_id" {
+  value = grid_rent_contract.rent.contract_id
+}
+
+output "billing_rate" {
+  value = grid_rent_contract.rent.billing_rate
+}
+
+output "ygg_ip" {
+  value = grid_vm.vm1.planetary_ip
+}
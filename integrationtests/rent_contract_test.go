@@ -0,0 +1,66 @@
+//go:build integration
+// +build integration
+
+// Package integrationtests includes integration tests for deploying solutions on the tf grid, and some utilities to test these solutions.
+package integrationtests
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/gruntwork-io/terratest/modules/terraform"
+	"github.com/stretchr/testify/require"
+	"github.com/threefoldtech/terraform-provider-grid/internal/provider/scheduler"
+)
+
+func TestRentContract(t *testing.T) {
+	publicKey, _, err := GenerateSSHKeyPair()
+	if err != nil {
+		t.Fatalf("failed to generate ssh key pair: %s", err.Error())
+	}
+
+	t.Run("rent_contract_test", func(t *testing.T) {
+		/* Test case for renting a node and deploying a vm on it.
+		   **Test Scenario**
+		   - Rent a rentable node.
+		   - Deploy a vm on the rented node.
+		   - Check that the outputs not empty.
+		   - Check that vm is reachable.
+		   - Destroy the deployment
+		*/
+
+		terraformOptions := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
+			TerraformDir: "./rent_contract",
+			Vars: map[string]interface{}{
+				"public_key": publicKey,
+			},
+		})
+		defer terraform.Destroy(t, terraformOptions)
+
+		_, err = terraform.InitAndApplyE(t, terraformOptions)
+		if err != nil &&
+			(strings.Contains(err.Error(), scheduler.NoNodesFoundErr.Error()) ||
+				strings.Contains(err.Error(), "error creating threefold plugin client")) {
+			t.Skip("couldn't find any available nodes")
+			return
+		}
+
+		require.NoError(t, err)
+
+		// Check that the outputs not empty
+		contractID := terraform.Output(t, terraformOptions, "contract_id")
+		require.NotEqual(t, "0", contractID)
+
+		billingRate, err := strconv.ParseFloat(terraform.Output(t, terraformOptions, "billing_rate"), 64)
+		require.NoError(t, err)
+		require.Greater(t, billingRate, 0.0)
+
+		yggIP := terraform.Output(t, terraformOptions, "ygg_ip")
+		require.NotEmpty(t, yggIP)
+
+		// testing connection
+		ok := TestConnection(yggIP, "22")
+		require.True(t, ok)
+	})
+}
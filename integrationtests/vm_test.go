@@ -247,4 +247,62 @@ func TestVM(t *testing.T) {
 		ok = TestConnection(vm2YggIP, "22")
 		require.True(t, ok)
 	})
+
+	t.Run("grid_vm", func(t *testing.T) {
+		/* Test case for deploying a standalone grid_vm with a disk, and resizing the disk in place.
+
+		   **Test Scenario**
+
+		   - Deploy a grid_vm mounting a disk.
+		   - Check that the outputs are not empty.
+		   - Check that the vm is reachable and the disk is mounted.
+		   - Resize the disk.
+		   - Check that the vm kept its contract.
+		   - Destroy the deployment.
+		*/
+		diskSize := 2
+		terraformOptions := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
+			TerraformDir: "./grid_vm",
+			Vars: map[string]interface{}{
+				"public_key": publicKey,
+				"disk_size":  diskSize,
+			},
+		})
+		defer terraform.Destroy(t, terraformOptions)
+
+		_, err = terraform.InitAndApplyE(t, terraformOptions)
+		if err != nil &&
+			(strings.Contains(err.Error(), scheduler.NoNodesFoundErr.Error()) ||
+				strings.Contains(err.Error(), "error creating threefold plugin client")) {
+			t.Skip("couldn't find any available nodes")
+			return
+		}
+
+		require.NoError(t, err)
+
+		// Check that the outputs not empty
+		vmID := terraform.Output(t, terraformOptions, "vm_id")
+		require.NotEmpty(t, vmID)
+
+		vmIP := terraform.Output(t, terraformOptions, "vm_ip")
+		require.NotEmpty(t, vmIP)
+
+		yggIP := terraform.Output(t, terraformOptions, "ygg_ip")
+		require.NotEmpty(t, yggIP)
+
+		ok := TestConnection(yggIP, "22")
+		require.True(t, ok)
+
+		output, err := RemoteRun("root", yggIP, "df -h | grep -w /app", privateKey)
+		require.NoError(t, err)
+		require.Contains(t, string(output), fmt.Sprintf("%d.0G", diskSize))
+
+		// resize the disk in place
+		diskSize++
+		terraformOptions.Vars["disk_size"] = diskSize
+		_, err = terraform.ApplyE(t, terraformOptions)
+		require.NoError(t, err)
+
+		require.Equal(t, vmID, terraform.Output(t, terraformOptions, "vm_id"), "the vm shouldn't be recreated")
+	})
 }
@@ -76,4 +76,84 @@ func TestZdbs(t *testing.T) {
 		require.NoError(t, err)
 		require.Equal(t, res, "val1")
 	})
+
+	t.Run("grid_zdb_test", func(t *testing.T) {
+		/* Test case for deploying a standalone grid_zdb, and resizing it in place.
+
+		   **Test Scenario**
+
+		   - Deploy a grid_zdb.
+		   - Connect to the zdb.
+		   - Write and read from the zdb.
+		   - Assert that the written and read values match.
+		   - Resize the zdb.
+		   - Assert that the zdb kept its contract and data.
+		   - Destroy the deployment
+
+		*/
+		if network, _ := os.LookupEnv("NETWORK"); network == "test" || network == "main" {
+			t.Skip("https://github.com/threefoldtech/terraform-provider-grid/issues/770")
+			return
+		}
+
+		password := "password123"
+		terraformOptions := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
+			TerraformDir: "./grid_zdb",
+			Vars: map[string]interface{}{
+				"password": password,
+				"size":     10,
+			},
+		})
+		defer terraform.Destroy(t, terraformOptions)
+
+		_, err := terraform.InitAndApplyE(t, terraformOptions)
+		if err != nil &&
+			(strings.Contains(err.Error(), scheduler.NoNodesFoundErr.Error()) ||
+				strings.Contains(err.Error(), "error creating threefold plugin client")) {
+			t.Skip("couldn't find any available nodes")
+			return
+		}
+
+		require.NoError(t, err)
+
+		// Check that the outputs not empty
+		zdbID := terraform.Output(t, terraformOptions, "zdb_id")
+		require.NotEmpty(t, zdbID)
+
+		zdbEndpoint := terraform.Output(t, terraformOptions, "zdb1_endpoint")
+		require.NotEmpty(t, zdbEndpoint)
+
+		zdbNamespace := terraform.Output(t, terraformOptions, "zdb1_namespace")
+		require.NotEmpty(t, zdbNamespace)
+
+		rdb := redis.NewClient(&redis.Options{
+			Addr: zdbEndpoint,
+		})
+		_, err = rdb.Do("SELECT", zdbNamespace, password).Result()
+		require.NoError(t, err)
+
+		_, err = rdb.Set("key1", "val1", 0).Result()
+		require.NoError(t, err)
+
+		res, err := rdb.Get("key1").Result()
+		require.NoError(t, err)
+		require.Equal(t, res, "val1")
+
+		// resize the zdb in place
+		terraformOptions.Vars["size"] = 20
+		_, err = terraform.ApplyE(t, terraformOptions)
+		require.NoError(t, err)
+
+		require.Equal(t, zdbID, terraform.Output(t, terraformOptions, "zdb_id"), "the zdb shouldn't be recreated")
+
+		rdb = redis.NewClient(&redis.Options{
+			Addr: terraform.Output(t, terraformOptions, "zdb1_endpoint"),
+		})
+		_, err = rdb.Do("SELECT", zdbNamespace, password).Result()
+		require.NoError(t, err)
+
+		res, err = rdb.Get("key1").Result()
+		require.NoError(t, err)
+		require.Equal(t, res, "val1", "the zdb data should be kept")
+	})
 }
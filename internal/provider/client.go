@@ -0,0 +1,79 @@
+// Package provider is the terraform provider
+package provider
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/threefoldtech/tfgrid-sdk-go/grid-client/deployer"
+)
+
+// customNetwork is the network used for private grids, where all grid services urls have to be set explicitly
+const customNetwork = "custom"
+
+// gridURLs are the urls of the grid services, each service could have multiple fallback urls
+type gridURLs struct {
+	substrate []string
+	relay     []string
+	proxy     []string
+	graphql   []string
+}
+
+// splitURLs splits a comma separated list of urls
+func splitURLs(urls string) []string {
+	res := make([]string, 0)
+	for _, u := range strings.Split(urls, ",") {
+		if u = strings.TrimSpace(u); len(u) != 0 {
+			res = append(res, u)
+		}
+	}
+
+	return res
+}
+
+// validate makes sure the urls are usable with the given network: a custom network needs all services urls
+func (u gridURLs) validate(network string) error {
+	if network != customNetwork {
+		return nil
+	}
+
+	for service, urls := range map[string][]string{
+		"substrate_url": u.substrate,
+		"relay_url":     u.relay,
+		"proxy_url":     u.proxy,
+		"graphql_url":   u.graphql,
+	} {
+		if len(urls) == 0 {
+			return fmt.Errorf("%s is required for the %s network", service, customNetwork)
+		}
+	}
+
+	return nil
+}
+
+// pluginClients are the plugin clients configured by the instances of one provider, so their
+// substrate and relay connections could be closed once the provider is served
+type pluginClients struct {
+	mu      sync.Mutex
+	clients []*deployer.TFPluginClient
+}
+
+// add keeps a configured plugin client to be closed later
+func (c *pluginClients) add(tfPluginClient *deployer.TFPluginClient) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.clients = append(c.clients, tfPluginClient)
+}
+
+// close closes the connections of the configured plugin clients
+func (c *pluginClients) close() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, tfPluginClient := range c.clients {
+		tfPluginClient.Close()
+	}
+	c.clients = nil
+}
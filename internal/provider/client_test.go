@@ -0,0 +1,29 @@
+// Package provider is the terraform provider
+package provider
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const testMnemonic = "bottom drive obey lake curtain smoke basket hold race lonely fit walk"
+
+func TestSplitURLs(t *testing.T) {
+	assert.Equal(t, []string{"wss://a.grid.tf/ws", "wss://b.grid.tf/ws"}, splitURLs(" wss://a.grid.tf/ws, ,wss://b.grid.tf/ws,"))
+	assert.Empty(t, splitURLs(""))
+}
+
+func TestGridURLsValidate(t *testing.T) {
+	urls := gridURLs{
+		substrate: []string{"wss://tfchain.grid.example/ws"},
+		relay:     []string{"wss://relay.grid.example"},
+		proxy:     []string{"https://gridproxy.grid.example"},
+		graphql:   []string{"https://graphql.grid.example/graphql"},
+	}
+	assert.NoError(t, urls.validate(customNetwork))
+
+	urls.graphql = nil
+	assert.ErrorContains(t, urls.validate(customNetwork), "graphql_url is required")
+	assert.NoError(t, urls.validate("dev"), "public networks use their default urls")
+}
@@ -0,0 +1,71 @@
+// Package provider is the terraform provider
+package provider
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+
+	"github.com/pkg/errors"
+	"github.com/threefoldtech/tfgrid-sdk-go/grid-client/deployer"
+	"github.com/threefoldtech/tfgrid-sdk-go/grid-client/graphql"
+	"github.com/threefoldtech/tfgrid-sdk-go/grid-client/workloads"
+)
+
+const (
+	contractStateCreated     = "Created"
+	contractStateGracePeriod = "GracePeriod"
+)
+
+// nodeContractsByName returns a mapping from node id to contract id for the twin's active node contracts whose
+// deployment metadata has the given name and one of the given types, along with the metadata of these contracts.
+func nodeContractsByName(tfPluginClient *deployer.TFPluginClient, name string, deploymentTypes ...string) (map[uint32]uint64, workloads.DeploymentData, error) {
+	contracts, err := tfPluginClient.ContractsGetter.ListContractsByTwinID([]string{contractStateCreated, contractStateGracePeriod})
+	if err != nil {
+		return nil, workloads.DeploymentData{}, errors.Wrapf(err, "couldn't list contracts of twin %d", tfPluginClient.TwinID)
+	}
+
+	return filterNodeContractsByName(contracts.NodeContracts, name, deploymentTypes...)
+}
+
+// filterNodeContractsByName returns a mapping from node id to contract id for the node contracts whose deployment metadata
+// has the given name and one of the given types, along with the metadata of these contracts. It fails if a node has more
+// than one matching contract, since the contract to use couldn't be decided.
+func filterNodeContractsByName(contracts []graphql.Contract, name string, deploymentTypes ...string) (map[uint32]uint64, workloads.DeploymentData, error) {
+	var data workloads.DeploymentData
+	nodeContracts := make(map[uint32][]uint64)
+	for _, contract := range contracts {
+		deploymentData, err := workloads.ParseDeploymentData(contract.DeploymentData)
+		if err != nil {
+			// contracts created by other tools might not have valid metadata
+			continue
+		}
+
+		if deploymentData.Name != name || !slices.Contains(deploymentTypes, deploymentData.Type) {
+			continue
+		}
+
+		contractID, err := strconv.ParseUint(contract.ContractID, 10, 64)
+		if err != nil {
+			return nil, workloads.DeploymentData{}, errors.Wrapf(err, "couldn't parse contract id '%s'", contract.ContractID)
+		}
+
+		nodeContracts[contract.NodeID] = append(nodeContracts[contract.NodeID], contractID)
+		data = deploymentData
+	}
+
+	if len(nodeContracts) == 0 {
+		return nil, workloads.DeploymentData{}, fmt.Errorf("couldn't find any node contracts for %v with name '%s'", deploymentTypes, name)
+	}
+
+	nodeContract := make(map[uint32]uint64, len(nodeContracts))
+	for nodeID, contractIDs := range nodeContracts {
+		if len(contractIDs) > 1 {
+			slices.Sort(contractIDs)
+			return nil, workloads.DeploymentData{}, fmt.Errorf("found multiple node contracts %v for %v with name '%s' on node %d", contractIDs, deploymentTypes, name, nodeID)
+		}
+		nodeContract[nodeID] = contractIDs[0]
+	}
+
+	return nodeContract, data, nil
+}
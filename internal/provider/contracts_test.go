@@ -0,0 +1,63 @@
+// Package provider is the terraform provider
+package provider
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/threefoldtech/tfgrid-sdk-go/grid-client/graphql"
+	"github.com/threefoldtech/tfgrid-sdk-go/grid-client/workloads"
+	"github.com/threefoldtech/tfgrid-sdk-go/grid-client/zos"
+)
+
+func testGraphqlContract(id string, nodeID uint32, deploymentType, name string) graphql.Contract {
+	return graphql.Contract{
+		ContractID:     id,
+		NodeID:         nodeID,
+		DeploymentData: `{"version":0,"type":"` + deploymentType + `","name":"` + name + `","projectName":"` + deploymentType + `/` + name + `"}`,
+	}
+}
+
+func TestFilterNodeContractsByName(t *testing.T) {
+	t.Run("matching contracts", func(t *testing.T) {
+		contracts := []graphql.Contract{
+			testGraphqlContract("1", 11, workloads.NetworkType, "net"),
+			testGraphqlContract("2", 12, zos.NetworkLightType, "net"),
+			testGraphqlContract("3", 13, workloads.NetworkType, "other"),
+			testGraphqlContract("4", 14, workloads.K8sType, "net"),
+			{ContractID: "5", NodeID: 15, DeploymentData: "not json"},
+		}
+
+		nodeContracts, data, err := filterNodeContractsByName(contracts, "net", workloads.NetworkType, zos.NetworkLightType)
+		require.NoError(t, err)
+		assert.Equal(t, map[uint32]uint64{11: 1, 12: 2}, nodeContracts)
+		assert.Equal(t, "net", data.Name)
+	})
+
+	t.Run("no matching contracts", func(t *testing.T) {
+		contracts := []graphql.Contract{testGraphqlContract("1", 11, workloads.NetworkType, "other")}
+
+		_, _, err := filterNodeContractsByName(contracts, "net", workloads.NetworkType)
+		assert.ErrorContains(t, err, "couldn't find any node contracts")
+	})
+
+	t.Run("multiple contracts on the same node", func(t *testing.T) {
+		contracts := []graphql.Contract{
+			testGraphqlContract("7", 11, workloads.K8sType, "cluster"),
+			testGraphqlContract("2", 12, workloads.K8sType, "cluster"),
+			testGraphqlContract("3", 11, workloads.K8sType, "cluster"),
+		}
+
+		_, _, err := filterNodeContractsByName(contracts, "cluster", workloads.K8sType)
+		assert.ErrorContains(t, err, "found multiple node contracts [3 7]")
+		assert.ErrorContains(t, err, "on node 11")
+	})
+
+	t.Run("invalid contract id", func(t *testing.T) {
+		contracts := []graphql.Contract{testGraphqlContract("x", 11, workloads.NetworkType, "net")}
+
+		_, _, err := filterNodeContractsByName(contracts, "net", workloads.NetworkType)
+		assert.ErrorContains(t, err, "couldn't parse contract id 'x'")
+	})
+}
@@ -0,0 +1,327 @@
+// Package provider is the terraform provider
+package provider
+
+import (
+	"context"
+	"math"
+
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/pkg/errors"
+	substrate "github.com/threefoldtech/tfchain/clients/tfchain-client-go"
+	"github.com/threefoldtech/tfgrid-sdk-go/grid-client/deployer"
+	"github.com/threefoldtech/tfgrid-sdk-go/grid-client/subi"
+	"github.com/threefoldtech/tfgrid-sdk-go/grid-client/workloads"
+	"github.com/threefoldtech/tfgrid-sdk-go/grid-client/zos"
+	proxyTypes "github.com/threefoldtech/tfgrid-sdk-go/grid-proxy/pkg/types"
+	"github.com/threefoldtech/zos/pkg/gridtypes"
+)
+
+const (
+	// pricingPolicyUnits is the number of pricing policy units in one USD, the policy values are per hour
+	pricingPolicyUnits = 1e7
+	// hoursPerMonth is the number of hours used by the chain for a billing month
+	hoursPerMonth = 24 * 30
+	// certifiedNodeFactor is the price factor of capacity on certified nodes
+	certifiedNodeFactor = 1.25
+)
+
+// costOptions are the node properties affecting the price of capacity
+type costOptions struct {
+	pricingPolicyID uint32
+	certified       bool
+	dedicated       bool
+	// identity is the account whose balance decides the discount package, no discount package is applied if nil
+	identity substrate.Identity
+}
+
+// discountPackage is a discount given by the chain to twins whose balance covers the contract cost for some months
+type discountPackage struct {
+	name     string
+	months   float64
+	discount float64
+}
+
+// discountPackages are the chain discount packages, from the highest to the lowest
+var discountPackages = []discountPackage{
+	{name: "gold", months: 18, discount: 60},
+	{name: "silver", months: 6, discount: 40},
+	{name: "bronze", months: 3, discount: 30},
+	{name: "default", months: 1.5, discount: 20},
+}
+
+// noDiscountPackage is used if the balance doesn't cover any discount package
+var noDiscountPackage = discountPackage{name: "none"}
+
+// balanceDiscountPackage returns the discount package of a twin with the given balance in USD, for a contract with the given monthly cost in USD
+func balanceDiscountPackage(balanceUSD, monthlyUSD float64) discountPackage {
+	if monthlyUSD <= 0 {
+		return noDiscountPackage
+	}
+
+	for _, pkg := range discountPackages {
+		if balanceUSD >= monthlyUSD*pkg.months {
+			return pkg
+		}
+	}
+
+	return noDiscountPackage
+}
+
+// gridCost is the cost of some capacity on the grid
+type gridCost struct {
+	hourlyUSD  float64
+	monthlyUSD float64
+	hourlyTFT  float64
+	monthlyTFT float64
+	// tftPrice is the TFT price in USD used for the conversion
+	tftPrice float64
+	// discountPackage is the balance discount package applied
+	discountPackage discountPackage
+}
+
+// cloudUnits returns the compute and storage units of the given capacity
+func cloudUnits(c zos.Capacity) (cu, su float64) {
+	cru := float64(c.CRU)
+	mru := float64(c.MRU) / float64(gridtypes.Gigabyte)
+	sru := float64(c.SRU) / float64(gridtypes.Gigabyte)
+	hru := float64(c.HRU) / float64(gridtypes.Gigabyte)
+
+	cu = math.Min(
+		math.Max(mru/4, cru/2),
+		math.Min(math.Max(mru/8, cru), math.Max(mru/2, cru/4)),
+	)
+	su = hru/1200 + sru/200
+
+	return cu, su
+}
+
+// estimateCost computes the cost of the given capacity using the chain pricing policy, applying the dedicated nodes discount
+// and the discount package of the options identity balance the same way the chain does. Network usage isn't included as it's billed by usage
+func estimateCost(sub subi.SubstrateExt, c zos.Capacity, opts costOptions) (gridCost, error) {
+	pricingPolicy, err := sub.GetPricingPolicy(opts.pricingPolicyID)
+	if err != nil {
+		return gridCost{}, errors.Wrapf(err, "couldn't get pricing policy %d", opts.pricingPolicyID)
+	}
+
+	tftPriceMUSD, err := sub.GetTFTPrice()
+	if err != nil {
+		return gridCost{}, errors.Wrap(err, "couldn't get tft price")
+	}
+	if tftPriceMUSD == 0 {
+		return gridCost{}, errors.New("tft price is zero")
+	}
+
+	cu, su := cloudUnits(c)
+	units := cu*float64(pricingPolicy.CU.Value) + su*float64(pricingPolicy.SU.Value) + float64(c.IPV4U)*float64(pricingPolicy.IPU.Value)
+
+	if opts.certified {
+		units *= certifiedNodeFactor
+	}
+
+	if opts.dedicated {
+		units -= units * float64(pricingPolicy.DedicatedNodesDiscount) / 100
+	}
+
+	tftPrice := float64(tftPriceMUSD) / 1000
+
+	pkg := noDiscountPackage
+	if opts.identity != nil {
+		balance, err := sub.GetBalance(opts.identity)
+		if err != nil {
+			return gridCost{}, errors.Wrap(err, "couldn't get account balance")
+		}
+
+		balanceUSD := float64(balance.Free.Int64()) / tftUnit * tftPrice
+		pkg = balanceDiscountPackage(balanceUSD, units/pricingPolicyUnits*hoursPerMonth)
+		units -= units * pkg.discount / 100
+	}
+
+	hourlyUSD := units / pricingPolicyUnits
+
+	return gridCost{
+		hourlyUSD:       hourlyUSD,
+		monthlyUSD:      hourlyUSD * hoursPerMonth,
+		hourlyTFT:       hourlyUSD / tftPrice,
+		monthlyTFT:      hourlyUSD * hoursPerMonth / tftPrice,
+		tftPrice:        tftPrice,
+		discountPackage: pkg,
+	}, nil
+}
+
+// farmPricingPolicyID returns the pricing policy id of the given farm
+func farmPricingPolicyID(ctx context.Context, tfPluginClient *deployer.TFPluginClient, farmID uint32) (uint32, error) {
+	id := uint64(farmID)
+	farms, _, err := tfPluginClient.GridProxyClient.Farms(ctx, proxyTypes.FarmFilter{FarmID: &id}, proxyTypes.Limit{Size: 1, Page: 1})
+	if err != nil {
+		return 0, errors.Wrapf(err, "couldn't get farm %d from the grid proxy", farmID)
+	}
+	if len(farms) == 0 {
+		return 0, errors.Errorf("farm %d is not found", farmID)
+	}
+
+	return uint32(farms[0].PricingPolicyID), nil
+}
+
+// nodeCostOptions returns the cost options of the given node, and whether it's rented by the configured twin
+func nodeCostOptions(ctx context.Context, tfPluginClient *deployer.TFPluginClient, nodeID uint32) (opts costOptions, rentedByTwin bool, err error) {
+	node, err := tfPluginClient.GridProxyClient.Node(ctx, nodeID)
+	if err != nil {
+		return opts, false, errors.Wrapf(err, "couldn't get node %d from the grid proxy", nodeID)
+	}
+
+	opts.pricingPolicyID, err = farmPricingPolicyID(ctx, tfPluginClient, uint32(node.FarmID))
+	if err != nil {
+		return opts, false, err
+	}
+
+	opts.certified = node.CertificationType == certifiedNode
+	opts.identity = tfPluginClient.Identity
+	rentedByTwin = node.Rented && node.RentedByTwinID == uint(tfPluginClient.TwinID)
+
+	return opts, rentedByTwin, nil
+}
+
+// nodesMonthlyCost returns the monthly cost in TFT of the node contracts using the given capacity on each node.
+// Only the public ips are billed by the node contracts on nodes rented by the configured twin, the rest is billed by the rent contract
+func nodesMonthlyCost(ctx context.Context, tfPluginClient *deployer.TFPluginClient, capacities map[uint32]zos.Capacity) (float64, error) {
+	var total float64
+	for nodeID, c := range capacities {
+		opts, rentedByTwin, err := nodeCostOptions(ctx, tfPluginClient, nodeID)
+		if err != nil {
+			return 0, err
+		}
+
+		if rentedByTwin {
+			c = zos.Capacity{IPV4U: c.IPV4U}
+		}
+
+		cost, err := estimateCost(tfPluginClient.SubstrateConn, c, opts)
+		if err != nil {
+			return 0, errors.Wrapf(err, "couldn't estimate node %d cost", nodeID)
+		}
+
+		total += cost.monthlyTFT
+	}
+
+	return total, nil
+}
+
+// setEstimatedMonthlyCost sets the estimated monthly cost of the resource node contracts using the capacity of each node, failing to estimate the cost only produces a warning
+func setEstimatedMonthlyCost(ctx context.Context, d *schema.ResourceData, tfPluginClient *deployer.TFPluginClient, capacities func() (map[uint32]zos.Capacity, error)) diag.Diagnostics {
+	var cost float64
+	nodesCapacity, err := capacities()
+	if err == nil {
+		cost, err = nodesMonthlyCost(ctx, tfPluginClient, nodesCapacity)
+	}
+	if err != nil {
+		return diag.Diagnostics{{
+			Severity: diag.Warning,
+			Summary:  "failed to estimate the monthly cost",
+			Detail:   err.Error(),
+		}}
+	}
+
+	if err := d.Set("estimated_monthly_cost", cost); err != nil {
+		return diag.Errorf("couldn't set estimated monthly cost with error: %v", err)
+	}
+
+	return nil
+}
+
+// readEstimatedMonthlyCost sets the estimated monthly cost on read only if it wasn't estimated yet, e.g. for imported resources.
+// It's otherwise estimated on create and update, so a tft price change isn't reported as a change made outside of terraform
+func readEstimatedMonthlyCost(ctx context.Context, d *schema.ResourceData, tfPluginClient *deployer.TFPluginClient, capacities func() (map[uint32]zos.Capacity, error)) diag.Diagnostics {
+	if _, ok := d.GetOk("estimated_monthly_cost"); ok {
+		return nil
+	}
+
+	return setEstimatedMonthlyCost(ctx, d, tfPluginClient, capacities)
+}
+
+// planEstimatedMonthlyCost sets the estimated monthly cost of the planned node contracts so it's shown in the plan.
+// It's only planned for new resources or when the given capacity attributes change, so a tft price change doesn't produce a diff,
+// and it's left unknown if the capacity isn't known yet or the estimation fails
+func planEstimatedMonthlyCost(ctx context.Context, d *schema.ResourceDiff, tfPluginClient *deployer.TFPluginClient, capacityAttrs []string, capacities func() (map[uint32]zos.Capacity, error)) error {
+	if d.Id() != "" && !d.HasChanges(capacityAttrs...) {
+		return nil
+	}
+
+	for _, attr := range capacityAttrs {
+		if !d.NewValueKnown(attr) {
+			return d.SetNewComputed("estimated_monthly_cost")
+		}
+	}
+
+	var cost float64
+	nodesCapacity, err := capacities()
+	if err == nil {
+		cost, err = nodesMonthlyCost(ctx, tfPluginClient, nodesCapacity)
+	}
+	if err != nil {
+		tflog.Warn(ctx, "failed to estimate the monthly cost", map[string]interface{}{"error": err.Error()})
+		return d.SetNewComputed("estimated_monthly_cost")
+	}
+
+	return d.SetNew("estimated_monthly_cost", cost)
+}
+
+// deploymentCapacity returns the capacity used by the deployment workloads on its node
+func deploymentCapacity(dl *workloads.Deployment, twinID uint32) (map[uint32]zos.Capacity, error) {
+	zosDeployment, err := dl.ZosDeployment(twinID)
+	if err != nil {
+		return nil, err
+	}
+
+	var c zos.Capacity
+	for _, wl := range zosDeployment.Workloads {
+		wlCap, err := wl.Capacity()
+		if err != nil {
+			return nil, errors.Wrapf(err, "couldn't get workload %s capacity", wl.Name)
+		}
+
+		c.CRU += wlCap.CRU
+		c.MRU += wlCap.MRU
+		c.SRU += wlCap.SRU
+		c.HRU += wlCap.HRU
+		c.IPV4U += wlCap.IPV4U
+	}
+
+	return map[uint32]zos.Capacity{dl.NodeID: c}, nil
+}
+
+// k8sCapacity returns the capacity used by the cluster workloads on each of its nodes
+func k8sCapacity(k8s *workloads.K8sCluster) (map[uint32]zos.Capacity, error) {
+	capacities := make(map[uint32]zos.Capacity)
+
+	add := func(nodeID uint32, wls []gridtypes.Workload) error {
+		c := capacities[nodeID]
+		for _, wl := range wls {
+			wlCap, err := wl.Capacity()
+			if err != nil {
+				return errors.Wrapf(err, "couldn't get workload %s capacity", wl.Name)
+			}
+
+			c.CRU += wlCap.CRU
+			c.MRU += uint64(wlCap.MRU)
+			c.SRU += uint64(wlCap.SRU)
+			c.HRU += uint64(wlCap.HRU)
+			c.IPV4U += wlCap.IPV4U
+		}
+		capacities[nodeID] = c
+		return nil
+	}
+
+	if err := add(k8s.Master.NodeID, k8s.Master.MasterZosWorkload(k8s)); err != nil {
+		return nil, err
+	}
+
+	for _, worker := range k8s.Workers {
+		if err := add(worker.NodeID, worker.WorkerZosWorkload(k8s)); err != nil {
+			return nil, err
+		}
+	}
+
+	return capacities, nil
+}
@@ -0,0 +1,163 @@
+// Package provider is the terraform provider
+package provider
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	"github.com/centrifuge/go-substrate-rpc-client/v4/types"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	substrate "github.com/threefoldtech/tfchain/clients/tfchain-client-go"
+	"github.com/threefoldtech/tfgrid-sdk-go/grid-client/deployer"
+	"github.com/threefoldtech/tfgrid-sdk-go/grid-client/subi"
+	"github.com/threefoldtech/tfgrid-sdk-go/grid-client/zos"
+	"github.com/threefoldtech/zos/pkg/gridtypes"
+)
+
+// pricingSubstrate is a substrate client with a single pricing policy, a tft price and an account balance
+type pricingSubstrate struct {
+	subi.SubstrateExt
+	policy   substrate.PricingPolicy
+	tftPrice uint32
+	balance  int64
+}
+
+func (s *pricingSubstrate) GetPricingPolicy(policyID uint32) (substrate.PricingPolicy, error) {
+	if policyID != uint32(s.policy.ID) {
+		return substrate.PricingPolicy{}, substrate.ErrNotFound
+	}
+	return s.policy, nil
+}
+
+func (s *pricingSubstrate) GetTFTPrice() (types.U32, error) {
+	return types.U32(s.tftPrice), nil
+}
+
+func (s *pricingSubstrate) GetBalance(identity substrate.Identity) (substrate.Balance, error) {
+	return substrate.Balance{Free: types.NewU128(*big.NewInt(s.balance))}, nil
+}
+
+func testPricingSubstrate() *pricingSubstrate {
+	return &pricingSubstrate{
+		policy: substrate.PricingPolicy{
+			ID:                     1,
+			CU:                     substrate.Policy{Value: 1000},
+			SU:                     substrate.Policy{Value: 1000},
+			IPU:                    substrate.Policy{Value: 1000},
+			DedicatedNodesDiscount: 50,
+		},
+		// 0.1 USD
+		tftPrice: 100,
+	}
+}
+
+func TestCloudUnits(t *testing.T) {
+	gb := uint64(gridtypes.Gigabyte)
+
+	cases := []struct {
+		name string
+		c    zos.Capacity
+		cu   float64
+		su   float64
+	}{
+		{"empty", zos.Capacity{}, 0, 0},
+		{"balanced", zos.Capacity{CRU: 2, MRU: 4 * gb}, 1, 0},
+		{"cpu bound", zos.Capacity{CRU: 8, MRU: 4 * gb}, 2, 0},
+		{"memory bound", zos.Capacity{CRU: 1, MRU: 16 * gb}, 2, 0},
+		{"storage", zos.Capacity{SRU: 100 * gb, HRU: 600 * gb}, 0, 1},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			cu, su := cloudUnits(c.c)
+			assert.InDelta(t, c.cu, cu, 1e-9)
+			assert.InDelta(t, c.su, su, 1e-9)
+		})
+	}
+}
+
+func TestEstimateCost(t *testing.T) {
+	gb := uint64(gridtypes.Gigabyte)
+	// 1 CU, 1 SU and 1 public ip, 3000 units per hour
+	c := zos.Capacity{CRU: 2, MRU: 4 * gb, SRU: 200 * gb, IPV4U: 1}
+
+	identity, err := substrate.NewIdentityFromSr25519Phrase(testMnemonic)
+	require.NoError(t, err)
+
+	cases := []struct {
+		name       string
+		opts       costOptions
+		balance    int64
+		monthlyUSD float64
+		pkg        string
+	}{
+		{"shared", costOptions{pricingPolicyID: 1}, 0, 0.216, "none"},
+		{"certified", costOptions{pricingPolicyID: 1, certified: true}, 0, 0.27, "none"},
+		{"dedicated", costOptions{pricingPolicyID: 1, dedicated: true}, 0, 0.108, "none"},
+		{"no balance", costOptions{pricingPolicyID: 1, identity: identity}, 0, 0.216, "none"},
+		{"default package", costOptions{pricingPolicyID: 1, identity: identity}, 4 * tftUnit, 0.216 * 0.8, "default"},
+		{"silver package", costOptions{pricingPolicyID: 1, identity: identity}, 20 * tftUnit, 0.216 * 0.6, "silver"},
+		{"gold package", costOptions{pricingPolicyID: 1, identity: identity}, 100 * tftUnit, 0.216 * 0.4, "gold"},
+		{"balance ignored without identity", costOptions{pricingPolicyID: 1}, 100 * tftUnit, 0.216, "none"},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			sub := testPricingSubstrate()
+			sub.balance = tc.balance
+
+			cost, err := estimateCost(sub, c, tc.opts)
+			require.NoError(t, err)
+
+			assert.InDelta(t, tc.monthlyUSD, cost.monthlyUSD, 1e-9)
+			assert.InDelta(t, tc.monthlyUSD/hoursPerMonth, cost.hourlyUSD, 1e-9)
+			assert.InDelta(t, tc.monthlyUSD/0.1, cost.monthlyTFT, 1e-9)
+			assert.Equal(t, 0.1, cost.tftPrice)
+			assert.Equal(t, tc.pkg, cost.discountPackage.name)
+		})
+	}
+}
+
+func TestEstimateCostErrors(t *testing.T) {
+	sub := testPricingSubstrate()
+
+	_, err := estimateCost(sub, zos.Capacity{CRU: 1}, costOptions{pricingPolicyID: 2})
+	assert.True(t, errors.Is(err, substrate.ErrNotFound))
+
+	sub.tftPrice = 0
+	_, err = estimateCost(sub, zos.Capacity{CRU: 1}, costOptions{pricingPolicyID: 1})
+	assert.ErrorContains(t, err, "tft price is zero")
+}
+
+func TestBalanceDiscountPackage(t *testing.T) {
+	assert.Equal(t, "none", balanceDiscountPackage(100, 0).name, "free capacity has no discount")
+	assert.Equal(t, "none", balanceDiscountPackage(1, 1).name)
+	assert.Equal(t, "default", balanceDiscountPackage(1.5, 1).name)
+	assert.Equal(t, "bronze", balanceDiscountPackage(3, 1).name)
+	assert.Equal(t, "silver", balanceDiscountPackage(17, 1).name)
+	assert.Equal(t, "gold", balanceDiscountPackage(18, 1).name)
+}
+
+func TestReadEstimatedMonthlyCost(t *testing.T) {
+	calls := 0
+	capacities := func() (map[uint32]zos.Capacity, error) {
+		calls++
+		return nil, errors.New("no capacity")
+	}
+
+	d := schema.TestResourceDataRaw(t, resourceDeployment().Schema, map[string]interface{}{})
+	diags := readEstimatedMonthlyCost(context.Background(), d, &deployer.TFPluginClient{}, capacities)
+	require.Len(t, diags, 1)
+	assert.Equal(t, diag.Warning, diags[0].Severity)
+	assert.Equal(t, 1, calls, "the cost of imported resources is estimated on read")
+
+	require.NoError(t, d.Set("estimated_monthly_cost", 1.5))
+	assert.Empty(t, readEstimatedMonthlyCost(context.Background(), d, &deployer.TFPluginClient{}, capacities))
+	assert.Equal(t, 1, calls, "the cost is only estimated again on create and update")
+	assert.Equal(t, 1.5, d.Get("estimated_monthly_cost"))
+}
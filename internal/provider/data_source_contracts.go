@@ -0,0 +1,361 @@
+// Package provider is the terraform provider
+package provider
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
+	"github.com/pkg/errors"
+	"github.com/threefoldtech/tfgrid-sdk-go/grid-client/deployer"
+	"github.com/threefoldtech/tfgrid-sdk-go/grid-client/workloads"
+	proxyTypes "github.com/threefoldtech/tfgrid-sdk-go/grid-proxy/pkg/types"
+)
+
+const (
+	// contractsPageSize is the number of contracts fetched from the grid proxy per request
+	contractsPageSize = 50
+	// contractBillsPageSize is the number of contract bills fetched from the grid proxy per request
+	contractBillsPageSize = 100
+	// contractBillsWorkers is the maximum number of contracts whose bills are fetched concurrently
+	contractBillsWorkers = 10
+	// tftUnit is the number of billed units in one TFT
+	tftUnit = 1e7
+)
+
+func dataSourceContracts() *schema.Resource {
+	return &schema.Resource{
+		// This description is used by the documentation generator and the language server.
+		Description: "Data source for listing the node, name and rent contracts of the configured identity twin using the grid proxy, optionally with their billing.",
+
+		ReadContext: dataSourceContractsRead,
+
+		Schema: map[string]*schema.Schema{
+			"type": {
+				Type:             schema.TypeString,
+				Optional:         true,
+				Description:      "Type of the contracts, `node`, `name` or `rent`. All types are returned if not set.",
+				ValidateDiagFunc: validation.ToDiagFunc(validation.StringInSlice([]string{"node", "name", "rent"}, false)),
+			},
+			"state": {
+				Type:        schema.TypeList,
+				Optional:    true,
+				Description: "States of the contracts, `Created`, `GracePeriod` or `Deleted`. Defaults to `Created` and `GracePeriod`.",
+				Elem: &schema.Schema{
+					Type:             schema.TypeString,
+					ValidateDiagFunc: validation.ToDiagFunc(validation.StringInSlice([]string{"Created", "GracePeriod", "Deleted"}, false)),
+				},
+			},
+			"node": {
+				Type:        schema.TypeInt,
+				Optional:    true,
+				Description: "Node id of the node and rent contracts. Name contracts are not returned if set.",
+			},
+			"limit": {
+				Type:             schema.TypeInt,
+				Optional:         true,
+				Default:          0,
+				Description:      "Maximum number of contracts to return. 0 returns all matching contracts.",
+				ValidateDiagFunc: validation.ToDiagFunc(validation.IntAtLeast(0)),
+			},
+			"include_billing": {
+				Type:        schema.TypeBool,
+				Optional:    true,
+				Default:     false,
+				Description: "Fetch the bills of the contracts to set their `total_billed` and `last_billed_at`. All the bills of every matching contract are fetched on each read, so it's better used with a `limit` or a `node` filter.",
+			},
+			"node_contract_ids": {
+				Type:        schema.TypeList,
+				Computed:    true,
+				Elem:        &schema.Schema{Type: schema.TypeInt},
+				Description: "List of the matching node contract ids.",
+			},
+			"name_contract_ids": {
+				Type:        schema.TypeList,
+				Computed:    true,
+				Elem:        &schema.Schema{Type: schema.TypeInt},
+				Description: "List of the matching name contract ids.",
+			},
+			"rent_contract_ids": {
+				Type:        schema.TypeList,
+				Computed:    true,
+				Elem:        &schema.Schema{Type: schema.TypeInt},
+				Description: "List of the matching rent contract ids.",
+			},
+			"contracts": {
+				Type:        schema.TypeList,
+				Computed:    true,
+				Description: "List of the matching contracts.",
+				Elem: &schema.Resource{
+					Schema: map[string]*schema.Schema{
+						"contract_id": {
+							Type:        schema.TypeInt,
+							Computed:    true,
+							Description: "Contract id.",
+						},
+						"type": {
+							Type:        schema.TypeString,
+							Computed:    true,
+							Description: "Contract type, `node`, `name` or `rent`.",
+						},
+						"state": {
+							Type:        schema.TypeString,
+							Computed:    true,
+							Description: "Contract state.",
+						},
+						"node_id": {
+							Type:        schema.TypeInt,
+							Computed:    true,
+							Description: "Node id of the node and rent contracts, 0 for name contracts.",
+						},
+						"farm_id": {
+							Type:        schema.TypeInt,
+							Computed:    true,
+							Description: "Farm id of the node and rent contracts, 0 for name contracts.",
+						},
+						"name": {
+							Type:        schema.TypeString,
+							Computed:    true,
+							Description: "Reserved name of the name contracts.",
+						},
+						"public_ips": {
+							Type:        schema.TypeInt,
+							Computed:    true,
+							Description: "Number of public ips reserved by the node contracts.",
+						},
+						"solution_type": {
+							Type:        schema.TypeString,
+							Computed:    true,
+							Description: "Solution type parsed from the node contract deployment metadata, e.g. `vm` or `kubernetes`.",
+						},
+						"solution_name": {
+							Type:        schema.TypeString,
+							Computed:    true,
+							Description: "Solution name parsed from the node contract deployment metadata.",
+						},
+						"project_name": {
+							Type:        schema.TypeString,
+							Computed:    true,
+							Description: "Project name parsed from the node contract deployment metadata.",
+						},
+						"created_at": {
+							Type:        schema.TypeInt,
+							Computed:    true,
+							Description: "Creation time of the contract as a unix timestamp.",
+						},
+						"total_billed": {
+							Type:        schema.TypeFloat,
+							Computed:    true,
+							Description: "Total amount billed for the contract in TFT, 0 if `include_billing` isn't set.",
+						},
+						"last_billed_at": {
+							Type:        schema.TypeInt,
+							Computed:    true,
+							Description: "Time of the last bill of the contract as a unix timestamp, 0 if the contract wasn't billed yet or `include_billing` isn't set.",
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+// contractBilling is the billing summary of a contract
+type contractBilling struct {
+	total      uint64
+	lastBilled uint64
+}
+
+// newContractFilterFromSchema reads the contracts data source filters from the schema.ResourceData and converts them into a grid proxy contract filter
+func newContractFilterFromSchema(d *schema.ResourceData, twinID uint32) (f proxyTypes.ContractFilter) {
+	twin := uint64(twinID)
+	f.TwinID = &twin
+
+	if contractType := d.Get("type").(string); contractType != "" {
+		f.Type = &contractType
+	}
+	if nodeID := uint64(d.Get("node").(int)); nodeID != 0 {
+		f.NodeID = &nodeID
+	}
+
+	f.State = []string{"Created", "GracePeriod"}
+	if states := d.Get("state").([]interface{}); len(states) != 0 {
+		f.State = make([]string, 0, len(states))
+		for _, state := range states {
+			f.State = append(f.State, state.(string))
+		}
+	}
+
+	return f
+}
+
+func contractToMap(ctx context.Context, contract proxyTypes.Contract, billing contractBilling) map[string]interface{} {
+	c := map[string]interface{}{
+		"contract_id":    int(contract.ContractID),
+		"type":           contract.Type,
+		"state":          contract.State,
+		"created_at":     int(contract.CreatedAt),
+		"total_billed":   float64(billing.total) / tftUnit,
+		"last_billed_at": int(billing.lastBilled),
+	}
+
+	switch details := contract.Details.(type) {
+	case proxyTypes.NodeContractDetails:
+		c["node_id"] = int(details.NodeID)
+		c["farm_id"] = int(details.FarmId)
+		c["public_ips"] = int(details.NumberOfPublicIps)
+
+		deploymentData, err := workloads.ParseDeploymentData(details.DeploymentData)
+		if err != nil {
+			tflog.Debug(ctx, "couldn't parse contract deployment data", map[string]interface{}{
+				"contract_id": contract.ContractID,
+				"error":       err.Error(),
+			})
+			break
+		}
+		c["solution_type"] = deploymentData.Type
+		c["solution_name"] = deploymentData.Name
+		c["project_name"] = deploymentData.ProjectName
+	case proxyTypes.NameContractDetails:
+		c["name"] = details.Name
+	case proxyTypes.RentContractDetails:
+		c["node_id"] = int(details.NodeID)
+		c["farm_id"] = int(details.FarmId)
+	}
+
+	return c
+}
+
+// listContracts pages through the grid proxy contracts matching the given filter. A zero limit returns all of them
+func listContracts(ctx context.Context, tfPluginClient *deployer.TFPluginClient, filter proxyTypes.ContractFilter, limit int) ([]proxyTypes.Contract, error) {
+	var contracts []proxyTypes.Contract
+	for page := uint64(1); ; page++ {
+		var res []proxyTypes.Contract
+		err := traceCall(ctx, "GridProxyClient.Contracts", func() (err error) {
+			res, _, err = tfPluginClient.GridProxyClient.Contracts(ctx, filter, proxyTypes.Limit{
+				Size: contractsPageSize,
+				Page: page,
+			})
+			return err
+		})
+		if err != nil {
+			return nil, errors.Wrap(err, "couldn't list contracts from the grid proxy")
+		}
+
+		contracts = append(contracts, res...)
+		if len(res) < contractsPageSize || (limit != 0 && len(contracts) >= limit) {
+			break
+		}
+	}
+
+	if limit != 0 && len(contracts) > limit {
+		contracts = contracts[:limit]
+	}
+
+	return contracts, nil
+}
+
+// contractsBilling fetches the bills of the given contracts concurrently, and returns the billing summary of each of them
+func contractsBilling(ctx context.Context, tfPluginClient *deployer.TFPluginClient, contracts []proxyTypes.Contract) ([]contractBilling, error) {
+	billing := make([]contractBilling, len(contracts))
+	errs := make([]error, len(contracts))
+
+	sem := make(chan struct{}, contractBillsWorkers)
+	var wg sync.WaitGroup
+	for i, contract := range contracts {
+		wg.Add(1)
+		go func(i int, contractID uint32) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			for page := uint64(1); ; page++ {
+				bills, _, err := tfPluginClient.GridProxyClient.ContractBills(ctx, contractID, proxyTypes.Limit{
+					Size: contractBillsPageSize,
+					Page: page,
+				})
+				if err != nil {
+					errs[i] = errors.Wrapf(err, "couldn't get contract %d bills", contractID)
+					return
+				}
+
+				for _, bill := range bills {
+					billing[i].total += bill.AmountBilled
+					billing[i].lastBilled = max(billing[i].lastBilled, bill.Timestamp)
+				}
+
+				if len(bills) < contractBillsPageSize {
+					return
+				}
+			}
+		}(i, uint32(contract.ContractID))
+	}
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return billing, nil
+}
+
+func dataSourceContractsRead(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	tfPluginClient, ok := meta.(*deployer.TFPluginClient)
+	if !ok {
+		return diag.FromErr(fmt.Errorf("failed to cast meta into threefold plugin client"))
+	}
+
+	ctx = resourceLogContext(ctx, "grid_contracts", d)
+
+	filter := newContractFilterFromSchema(d, tfPluginClient.TwinID)
+	limit := d.Get("limit").(int)
+
+	contracts, err := listContracts(ctx, tfPluginClient, filter, limit)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	billing := make([]contractBilling, len(contracts))
+	if d.Get("include_billing").(bool) {
+		err = traceCall(ctx, "GridProxyClient.ContractBills", func() (err error) {
+			billing, err = contractsBilling(ctx, tfPluginClient, contracts)
+			return err
+		})
+		if err != nil {
+			return diag.FromErr(err)
+		}
+	}
+
+	contractIDs := map[string][]interface{}{
+		"node": make([]interface{}, 0),
+		"name": make([]interface{}, 0),
+		"rent": make([]interface{}, 0),
+	}
+	contractMaps := make([]interface{}, 0, len(contracts))
+	for i, contract := range contracts {
+		contractIDs[contract.Type] = append(contractIDs[contract.Type], int(contract.ContractID))
+		contractMaps = append(contractMaps, contractToMap(ctx, contract, billing[i]))
+	}
+
+	for contractType, ids := range contractIDs {
+		attr := fmt.Sprintf("%s_contract_ids", contractType)
+		if err := d.Set(attr, ids); err != nil {
+			return diag.FromErr(errors.Wrapf(err, "couldn't set %s", attr))
+		}
+	}
+
+	if err := d.Set("contracts", contractMaps); err != nil {
+		return diag.FromErr(errors.Wrap(err, "couldn't set contracts"))
+	}
+
+	d.SetId(strconv.FormatInt(time.Now().Unix(), 10))
+	return nil
+}
@@ -0,0 +1,158 @@
+// Package provider is the terraform provider
+package provider
+
+import (
+	"context"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/threefoldtech/tfgrid-sdk-go/grid-client/deployer"
+	proxy "github.com/threefoldtech/tfgrid-sdk-go/grid-proxy/pkg/client"
+	proxyTypes "github.com/threefoldtech/tfgrid-sdk-go/grid-proxy/pkg/types"
+)
+
+// billsProxy is a grid proxy client with the given contracts, each billed once, counting the bills requests
+type billsProxy struct {
+	proxy.Client
+	contracts     []proxyTypes.Contract
+	billsRequests int
+}
+
+func (p *billsProxy) Contracts(ctx context.Context, filter proxyTypes.ContractFilter, limit proxyTypes.Limit) ([]proxyTypes.Contract, int, error) {
+	return p.contracts, len(p.contracts), nil
+}
+
+func (p *billsProxy) ContractBills(ctx context.Context, contractID uint32, limit proxyTypes.Limit) ([]proxyTypes.ContractBilling, uint, error) {
+	p.billsRequests++
+	return []proxyTypes.ContractBilling{{AmountBilled: 1e7, Timestamp: 1700000000}}, 1, nil
+}
+
+func TestNewContractFilterFromSchema(t *testing.T) {
+	t.Run("defaults", func(t *testing.T) {
+		d := schema.TestResourceDataRaw(t, dataSourceContracts().Schema, map[string]interface{}{})
+
+		f := newContractFilterFromSchema(d, 7)
+		assert.Equal(t, uint64(7), *f.TwinID)
+		assert.Nil(t, f.Type)
+		assert.Nil(t, f.NodeID)
+		assert.Equal(t, []string{"Created", "GracePeriod"}, f.State)
+	})
+
+	t.Run("filters", func(t *testing.T) {
+		d := schema.TestResourceDataRaw(t, dataSourceContracts().Schema, map[string]interface{}{
+			"type":  "rent",
+			"node":  11,
+			"state": []interface{}{"Deleted"},
+		})
+
+		f := newContractFilterFromSchema(d, 7)
+		assert.Equal(t, uint64(7), *f.TwinID)
+		assert.Equal(t, "rent", *f.Type)
+		assert.Equal(t, uint64(11), *f.NodeID)
+		assert.Equal(t, []string{"Deleted"}, f.State)
+	})
+}
+
+func TestContractToMap(t *testing.T) {
+	ctx := context.Background()
+	billing := contractBilling{total: 25e6, lastBilled: 1700000000}
+
+	t.Run("node", func(t *testing.T) {
+		contract := proxyTypes.Contract{
+			ContractID: 1,
+			Type:       "node",
+			State:      "Created",
+			CreatedAt:  1600000000,
+			Details: proxyTypes.NodeContractDetails{
+				NodeID:            11,
+				FarmId:            1,
+				NumberOfPublicIps: 2,
+				DeploymentData:    `{"version":0,"type":"vm","name":"vm1","projectName":"vm/vm1"}`,
+			},
+		}
+
+		assert.Equal(t, map[string]interface{}{
+			"contract_id":    1,
+			"type":           "node",
+			"state":          "Created",
+			"created_at":     1600000000,
+			"total_billed":   2.5,
+			"last_billed_at": 1700000000,
+			"node_id":        11,
+			"farm_id":        1,
+			"public_ips":     2,
+			"solution_type":  "vm",
+			"solution_name":  "vm1",
+			"project_name":   "vm/vm1",
+		}, contractToMap(ctx, contract, billing))
+	})
+
+	t.Run("node with invalid deployment data", func(t *testing.T) {
+		contract := proxyTypes.Contract{
+			ContractID: 1,
+			Type:       "node",
+			Details:    proxyTypes.NodeContractDetails{NodeID: 11, DeploymentData: "not json"},
+		}
+
+		c := contractToMap(ctx, contract, contractBilling{})
+		assert.Equal(t, 11, c["node_id"])
+		assert.NotContains(t, c, "solution_type")
+		assert.NotContains(t, c, "project_name")
+	})
+
+	t.Run("name", func(t *testing.T) {
+		contract := proxyTypes.Contract{
+			ContractID: 2,
+			Type:       "name",
+			Details:    proxyTypes.NameContractDetails{Name: "example"},
+		}
+
+		c := contractToMap(ctx, contract, contractBilling{})
+		assert.Equal(t, "example", c["name"])
+		assert.NotContains(t, c, "node_id")
+		assert.Equal(t, 0.0, c["total_billed"])
+		assert.Equal(t, 0, c["last_billed_at"])
+	})
+
+	t.Run("rent", func(t *testing.T) {
+		contract := proxyTypes.Contract{
+			ContractID: 3,
+			Type:       "rent",
+			Details:    proxyTypes.RentContractDetails{NodeID: 11, FarmId: 1},
+		}
+
+		c := contractToMap(ctx, contract, billing)
+		assert.Equal(t, 11, c["node_id"])
+		assert.Equal(t, 1, c["farm_id"])
+		assert.NotContains(t, c, "name")
+	})
+}
+
+func TestDataSourceContractsReadBilling(t *testing.T) {
+	for _, includeBilling := range []bool{false, true} {
+		gridProxy := &billsProxy{contracts: []proxyTypes.Contract{{
+			ContractID: 1,
+			Type:       "name",
+			Details:    proxyTypes.NameContractDetails{Name: "example"},
+		}}}
+		tfPluginClient := &deployer.TFPluginClient{TwinID: 7, GridProxyClient: gridProxy}
+
+		d := schema.TestResourceDataRaw(t, dataSourceContracts().Schema, map[string]interface{}{
+			"include_billing": includeBilling,
+		})
+		require.False(t, dataSourceContractsRead(context.Background(), d, tfPluginClient).HasError())
+
+		if includeBilling {
+			assert.Equal(t, 1, gridProxy.billsRequests)
+			assert.Equal(t, 1.0, d.Get("contracts.0.total_billed"))
+			assert.Equal(t, 1700000000, d.Get("contracts.0.last_billed_at"))
+			continue
+		}
+
+		assert.Zero(t, gridProxy.billsRequests, "bills are only fetched with include_billing")
+		assert.Equal(t, 0.0, d.Get("contracts.0.total_billed"))
+		assert.Equal(t, 0, d.Get("contracts.0.last_billed_at"))
+	}
+}
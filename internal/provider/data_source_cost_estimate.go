@@ -0,0 +1,196 @@
+// Package provider is the terraform provider
+package provider
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
+	"github.com/pkg/errors"
+	"github.com/threefoldtech/tfgrid-sdk-go/grid-client/deployer"
+	"github.com/threefoldtech/tfgrid-sdk-go/grid-client/zos"
+	"github.com/threefoldtech/zos/pkg/gridtypes"
+)
+
+func dataSourceCostEstimate() *schema.Resource {
+	return &schema.Resource{
+		// This description is used by the documentation generator and the language server.
+		Description: "Data source for estimating the cost of some capacity on the grid using the chain pricing policy of the farm, and the discount package of the configured account balance. Network usage isn't included as it's billed by usage.",
+
+		ReadContext: dataSourceCostEstimateRead,
+
+		Schema: map[string]*schema.Schema{
+			"cru": {
+				Type:             schema.TypeInt,
+				Optional:         true,
+				Description:      "Number of virtual CPUs.",
+				ValidateDiagFunc: validation.ToDiagFunc(validation.IntAtLeast(0)),
+			},
+			"mru": {
+				Type:             schema.TypeInt,
+				Optional:         true,
+				Description:      "Memory size in MBs.",
+				ValidateDiagFunc: validation.ToDiagFunc(validation.IntAtLeast(0)),
+			},
+			"sru": {
+				Type:             schema.TypeInt,
+				Optional:         true,
+				Description:      "Disk SSD size in MBs.",
+				ValidateDiagFunc: validation.ToDiagFunc(validation.IntAtLeast(0)),
+			},
+			"hru": {
+				Type:             schema.TypeInt,
+				Optional:         true,
+				Description:      "Disk HDD size in MBs.",
+				ValidateDiagFunc: validation.ToDiagFunc(validation.IntAtLeast(0)),
+			},
+			"public_ips": {
+				Type:             schema.TypeInt,
+				Optional:         true,
+				Description:      "Number of public ipv4 addresses.",
+				ValidateDiagFunc: validation.ToDiagFunc(validation.IntAtLeast(0)),
+			},
+			"node": {
+				Type:          schema.TypeInt,
+				Optional:      true,
+				ConflictsWith: []string{"farm"},
+				Description:   "Node id to use the pricing policy of its farm.",
+			},
+			"farm": {
+				Type:          schema.TypeInt,
+				Optional:      true,
+				ConflictsWith: []string{"node"},
+				Description:   "Farm id to use its pricing policy. The default pricing policy is used if neither node nor farm is set.",
+			},
+			"dedicated": {
+				Type:        schema.TypeBool,
+				Optional:    true,
+				Default:     false,
+				Description: "Apply the dedicated nodes discount, as used for rent contracts.",
+			},
+			"certified": {
+				Type:        schema.TypeBool,
+				Optional:    true,
+				Default:     false,
+				Description: "Use the price of certified nodes.",
+			},
+			"balance_discount": {
+				Type:        schema.TypeBool,
+				Optional:    true,
+				Default:     true,
+				Description: "Apply the discount package given by the chain according to the balance of the configured account, as done when billing the contracts.",
+			},
+			"discount_package": {
+				Type:        schema.TypeString,
+				Computed:    true,
+				Description: "The balance discount package applied, `none`, `default`, `bronze`, `silver` or `gold`.",
+			},
+			"pricing_policy_id": {
+				Type:        schema.TypeInt,
+				Computed:    true,
+				Description: "Id of the pricing policy used for the estimation.",
+			},
+			"tft_price": {
+				Type:        schema.TypeFloat,
+				Computed:    true,
+				Description: "TFT price in USD used for the conversion.",
+			},
+			"hourly_cost_usd": {
+				Type:        schema.TypeFloat,
+				Computed:    true,
+				Description: "Hourly cost in USD.",
+			},
+			"monthly_cost_usd": {
+				Type:        schema.TypeFloat,
+				Computed:    true,
+				Description: "Monthly cost in USD.",
+			},
+			"hourly_cost_tft": {
+				Type:        schema.TypeFloat,
+				Computed:    true,
+				Description: "Hourly cost in TFT.",
+			},
+			"monthly_cost_tft": {
+				Type:        schema.TypeFloat,
+				Computed:    true,
+				Description: "Monthly cost in TFT.",
+			},
+		},
+	}
+}
+
+func dataSourceCostEstimateRead(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	tfPluginClient, ok := meta.(*deployer.TFPluginClient)
+	if !ok {
+		return diag.FromErr(fmt.Errorf("failed to cast meta into threefold plugin client"))
+	}
+
+	ctx = resourceLogContext(ctx, "grid_cost_estimate", d)
+
+	opts := costOptions{
+		pricingPolicyID: defaultPricingPolicyID,
+		certified:       d.Get("certified").(bool),
+		dedicated:       d.Get("dedicated").(bool),
+	}
+
+	if d.Get("balance_discount").(bool) {
+		opts.identity = tfPluginClient.Identity
+	}
+
+	farmID := uint32(d.Get("farm").(int))
+	if nodeID := uint32(d.Get("node").(int)); nodeID != 0 {
+		node, err := tfPluginClient.GridProxyClient.Node(ctx, nodeID)
+		if err != nil {
+			return diag.FromErr(errors.Wrapf(err, "couldn't get node %d from the grid proxy", nodeID))
+		}
+		farmID = uint32(node.FarmID)
+	}
+
+	if farmID != 0 {
+		var err error
+		opts.pricingPolicyID, err = farmPricingPolicyID(ctx, tfPluginClient, farmID)
+		if err != nil {
+			return diag.FromErr(err)
+		}
+	}
+
+	c := zos.Capacity{
+		CRU:   uint64(d.Get("cru").(int)),
+		MRU:   uint64(d.Get("mru").(int)) * uint64(gridtypes.Megabyte),
+		SRU:   uint64(d.Get("sru").(int)) * uint64(gridtypes.Megabyte),
+		HRU:   uint64(d.Get("hru").(int)) * uint64(gridtypes.Megabyte),
+		IPV4U: uint64(d.Get("public_ips").(int)),
+	}
+
+	var cost gridCost
+	err := traceCall(ctx, "Substrate.GetPricingPolicy", func() (err error) {
+		cost, err = estimateCost(tfPluginClient.SubstrateConn, c, opts)
+		return err
+	})
+	if err != nil {
+		return diag.FromErr(errors.Wrap(err, "couldn't estimate cost"))
+	}
+
+	values := map[string]interface{}{
+		"discount_package":  cost.discountPackage.name,
+		"pricing_policy_id": int(opts.pricingPolicyID),
+		"tft_price":         cost.tftPrice,
+		"hourly_cost_usd":   cost.hourlyUSD,
+		"monthly_cost_usd":  cost.monthlyUSD,
+		"hourly_cost_tft":   cost.hourlyTFT,
+		"monthly_cost_tft":  cost.monthlyTFT,
+	}
+
+	for attr, value := range values {
+		if err := d.Set(attr, value); err != nil {
+			return diag.FromErr(errors.Wrapf(err, "couldn't set %s", attr))
+		}
+	}
+
+	d.SetId(strconv.FormatInt(time.Now().Unix(), 10))
+	return nil
+}
@@ -0,0 +1,297 @@
+// Package provider is the terraform provider
+package provider
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
+	"github.com/pkg/errors"
+	"github.com/threefoldtech/terraform-provider-grid/internal/provider/scheduler"
+	"github.com/threefoldtech/tfgrid-sdk-go/grid-client/deployer"
+	proxyTypes "github.com/threefoldtech/tfgrid-sdk-go/grid-proxy/pkg/types"
+	"github.com/threefoldtech/tfgrid-sdk-go/rmb-sdk-go/peer"
+)
+
+const (
+	// farmsPageSize is the number of farms fetched from the grid proxy per request
+	farmsPageSize = 50
+	// farmerBotsWorkers is the maximum number of farmerbots pinged concurrently
+	farmerBotsWorkers = 10
+)
+
+func dataSourceFarms() *schema.Resource {
+	return &schema.Resource{
+		// This description is used by the documentation generator and the language server.
+		Description: "Data source for searching the grid farms using the grid proxy. Only farms matching all of the given filters are returned.",
+
+		ReadContext: dataSourceFarmsRead,
+
+		Schema: map[string]*schema.Schema{
+			"name": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				Description: "Farm name.",
+			},
+			"certification_type": {
+				Type:             schema.TypeString,
+				Optional:         true,
+				Description:      "Certification type of the farms, `NotCertified`, `Silver` or `Gold`.",
+				ValidateDiagFunc: validation.ToDiagFunc(validation.StringInSlice([]string{"NotCertified", "Silver", "Gold"}, false)),
+			},
+			"dedicated": {
+				Type:        schema.TypeBool,
+				Optional:    true,
+				Description: "Flag to pick only dedicated (or only non dedicated if false) farms.",
+			},
+			"free_ips": {
+				Type:        schema.TypeInt,
+				Optional:    true,
+				Description: "Minimum count of free public ips in the farm.",
+			},
+			"country": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				Description: "Country of the farm nodes.",
+			},
+			"limit": {
+				Type:             schema.TypeInt,
+				Optional:         true,
+				Default:          0,
+				Description:      "Maximum number of farms to return. 0 returns all matching farms.",
+				ValidateDiagFunc: validation.ToDiagFunc(validation.IntAtLeast(0)),
+			},
+			"farm_ids": {
+				Type:        schema.TypeList,
+				Computed:    true,
+				Elem:        &schema.Schema{Type: schema.TypeInt},
+				Description: "List of the matching farm ids.",
+			},
+			"farms": {
+				Type:        schema.TypeList,
+				Computed:    true,
+				Description: "List of the matching farms.",
+				Elem: &schema.Resource{
+					Schema: map[string]*schema.Schema{
+						"farm_id": {
+							Type:        schema.TypeInt,
+							Computed:    true,
+							Description: "Farm id.",
+						},
+						"name": {
+							Type:        schema.TypeString,
+							Computed:    true,
+							Description: "Farm name.",
+						},
+						"twin_id": {
+							Type:        schema.TypeInt,
+							Computed:    true,
+							Description: "Twin id of the farmer.",
+						},
+						"pricing_policy_id": {
+							Type:        schema.TypeInt,
+							Computed:    true,
+							Description: "Id of the pricing policy of the farm.",
+						},
+						"certification_type": {
+							Type:        schema.TypeString,
+							Computed:    true,
+							Description: "Certification type of the farm.",
+						},
+						"dedicated": {
+							Type:        schema.TypeBool,
+							Computed:    true,
+							Description: "True if the farm is dedicated.",
+						},
+						"free_ips": {
+							Type:        schema.TypeInt,
+							Computed:    true,
+							Description: "Count of free public ips in the farm.",
+						},
+						"has_farmerbot": {
+							Type:        schema.TypeBool,
+							Computed:    true,
+							Description: "True if the farm has a farmerbot answering rmb calls.",
+						},
+						"public_ips": {
+							Type:        schema.TypeList,
+							Computed:    true,
+							Description: "List of the public ips of the farm.",
+							Elem: &schema.Resource{
+								Schema: map[string]*schema.Schema{
+									"ip": {
+										Type:        schema.TypeString,
+										Computed:    true,
+										Description: "Public ip with its subnet mask.",
+									},
+									"gateway": {
+										Type:        schema.TypeString,
+										Computed:    true,
+										Description: "Gateway of the public ip.",
+									},
+									"contract_id": {
+										Type:        schema.TypeInt,
+										Computed:    true,
+										Description: "Id of the contract using the public ip, 0 if not used.",
+									},
+									"taken": {
+										Type:        schema.TypeBool,
+										Computed:    true,
+										Description: "True if the public ip is used by a contract.",
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+// newFarmFilterFromSchema reads the farms data source filters from the schema.ResourceData and converts them into a grid proxy farm filter
+func newFarmFilterFromSchema(d *schema.ResourceData) (f proxyTypes.FarmFilter) {
+	if name := d.Get("name").(string); name != "" {
+		f.Name = &name
+	}
+	if certification := d.Get("certification_type").(string); certification != "" {
+		f.CertificationType = &certification
+	}
+	if country := d.Get("country").(string); country != "" {
+		f.Country = &country
+	}
+	if freeIPs := uint64(d.Get("free_ips").(int)); freeIPs != 0 {
+		f.FreeIPs = &freeIPs
+	}
+
+	f.Dedicated = getOptionalBool(d, "dedicated")
+
+	return f
+}
+
+func farmToMap(farm proxyTypes.Farm, hasFarmerBot bool) map[string]interface{} {
+	freeIPs := 0
+	publicIPs := make([]interface{}, 0, len(farm.PublicIps))
+	for _, ip := range farm.PublicIps {
+		if ip.ContractID == 0 {
+			freeIPs++
+		}
+
+		publicIPs = append(publicIPs, map[string]interface{}{
+			"ip":          ip.IP,
+			"gateway":     ip.Gateway,
+			"contract_id": int(ip.ContractID),
+			"taken":       ip.ContractID != 0,
+		})
+	}
+
+	return map[string]interface{}{
+		"farm_id":            farm.FarmID,
+		"name":               farm.Name,
+		"twin_id":            farm.TwinID,
+		"pricing_policy_id":  farm.PricingPolicyID,
+		"certification_type": farm.CertificationType,
+		"dedicated":          farm.Dedicated,
+		"free_ips":           freeIPs,
+		"has_farmerbot":      hasFarmerBot,
+		"public_ips":         publicIPs,
+	}
+}
+
+// farmsFarmerBots pings the farmerbots of the given farms concurrently, with at most farmerBotsWorkers at a time, and returns whether each of them answered
+func farmsFarmerBots(ctx context.Context, rpcClient *peer.RpcClient, timeout time.Duration, farms []proxyTypes.Farm) []bool {
+	hasFarmerBot := make([]bool, len(farms))
+
+	sem := make(chan struct{}, farmerBotsWorkers)
+	var wg sync.WaitGroup
+	for i, farm := range farms {
+		wg.Add(1)
+		go func(i int, farm proxyTypes.Farm) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			ctx, cancel := context.WithTimeout(ctx, timeout)
+			defer cancel()
+
+			_, err := scheduler.PingFarmerBot(ctx, rpcClient, uint32(farm.FarmID), uint32(farm.TwinID))
+			if err != nil {
+				tflog.Debug(ctx, "farmerbot didn't answer", map[string]interface{}{
+					"farm_id": farm.FarmID,
+					"error":   err.Error(),
+				})
+			}
+			hasFarmerBot[i] = err == nil
+		}(i, farm)
+	}
+	wg.Wait()
+
+	return hasFarmerBot
+}
+
+func dataSourceFarmsRead(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	tfPluginClient, ok := meta.(*deployer.TFPluginClient)
+	if !ok {
+		return diag.FromErr(fmt.Errorf("failed to cast meta into threefold plugin client"))
+	}
+
+	ctx = resourceLogContext(ctx, "grid_farms", d)
+
+	rpcClient, ok := tfPluginClient.RMB.(*peer.RpcClient)
+	if !ok {
+		return diag.FromErr(fmt.Errorf("failed to cast rmb client into rpc client"))
+	}
+
+	filter := newFarmFilterFromSchema(d)
+	limit := d.Get("limit").(int)
+
+	var farms []proxyTypes.Farm
+	for page := uint64(1); ; page++ {
+		var res []proxyTypes.Farm
+		err := traceCall(ctx, "GridProxyClient.Farms", func() (err error) {
+			res, _, err = tfPluginClient.GridProxyClient.Farms(ctx, filter, proxyTypes.Limit{
+				Size: farmsPageSize,
+				Page: page,
+			})
+			return err
+		})
+		if err != nil {
+			return diag.FromErr(errors.Wrap(err, "couldn't list farms from the grid proxy"))
+		}
+
+		farms = append(farms, res...)
+		if len(res) < farmsPageSize || (limit != 0 && len(farms) >= limit) {
+			break
+		}
+	}
+
+	if limit != 0 && len(farms) > limit {
+		farms = farms[:limit]
+	}
+
+	hasFarmerBot := farmsFarmerBots(ctx, rpcClient, tfPluginClient.RMBTimeout, farms)
+
+	farmIDs := make([]interface{}, 0, len(farms))
+	farmMaps := make([]interface{}, 0, len(farms))
+	for i, farm := range farms {
+		farmIDs = append(farmIDs, farm.FarmID)
+		farmMaps = append(farmMaps, farmToMap(farm, hasFarmerBot[i]))
+	}
+
+	if err := d.Set("farm_ids", farmIDs); err != nil {
+		return diag.FromErr(errors.Wrap(err, "couldn't set farm ids"))
+	}
+
+	if err := d.Set("farms", farmMaps); err != nil {
+		return diag.FromErr(errors.Wrap(err, "couldn't set farms"))
+	}
+
+	d.SetId(strconv.FormatInt(time.Now().Unix(), 10))
+	return nil
+}
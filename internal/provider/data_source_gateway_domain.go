@@ -48,6 +48,8 @@ func dataSourceGatewayRead(ctx context.Context, d *schema.ResourceData, meta int
 		return diag.FromErr(fmt.Errorf("failed to cast meta into threefold plugin client"))
 	}
 
+	ctx = resourceLogContext(ctx, "grid_gateway_domain", d)
+
 	nodeID := uint32(d.Get("node").(int))
 	name := d.Get("name").(string)
 
@@ -0,0 +1,245 @@
+// Package provider is the terraform provider
+package provider
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"slices"
+
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/pkg/errors"
+	"github.com/threefoldtech/tfgrid-sdk-go/grid-client/deployer"
+	client "github.com/threefoldtech/tfgrid-sdk-go/grid-client/node"
+	"github.com/threefoldtech/tfgrid-sdk-go/grid-client/zos"
+	"github.com/threefoldtech/zos/pkg/gridtypes"
+)
+
+func dataSourceNode() *schema.Resource {
+	return &schema.Resource{
+		// This description is used by the documentation generator and the language server.
+		Description: "Data source for reading the live details of a node. The details are fetched from the node itself, except for the farm which is fetched from the grid proxy.",
+
+		ReadContext: dataSourceNodeRead,
+
+		Schema: map[string]*schema.Schema{
+			"node": {
+				Type:        schema.TypeInt,
+				Required:    true,
+				Description: "Node id.",
+			},
+			"farm_id": {
+				Type:        schema.TypeInt,
+				Computed:    true,
+				Description: "Farm id of the node.",
+			},
+			"farm_name": {
+				Type:        schema.TypeString,
+				Computed:    true,
+				Description: "Farm name of the node.",
+			},
+			"twin_id": {
+				Type:        schema.TypeInt,
+				Computed:    true,
+				Description: "Twin id of the node.",
+			},
+			"features": {
+				Type:        schema.TypeList,
+				Computed:    true,
+				Elem:        &schema.Schema{Type: schema.TypeString},
+				Description: "Zos features supported by the node.",
+			},
+			"light": {
+				Type:        schema.TypeBool,
+				Computed:    true,
+				Description: "True if the node is a zos light node.",
+			},
+			"zos_version": {
+				Type:        schema.TypeString,
+				Computed:    true,
+				Description: "Zos version running on the node.",
+			},
+			"public_config": {
+				Type:        schema.TypeList,
+				Computed:    true,
+				Description: "Public config of the node, empty if the node has no public config.",
+				Elem:        nodePublicConfigSchema(),
+			},
+			"total_resources": {
+				Type:        schema.TypeList,
+				Computed:    true,
+				Description: "Total capacity of the node.",
+				Elem:        nodeCapacitySchema(),
+			},
+			"used_resources": {
+				Type:        schema.TypeList,
+				Computed:    true,
+				Description: "Used capacity of the node.",
+				Elem:        nodeCapacitySchema(),
+			},
+			"gpus": {
+				Type:        schema.TypeList,
+				Computed:    true,
+				Description: "List of the gpus on the node.",
+				Elem: &schema.Resource{
+					Schema: map[string]*schema.Schema{
+						"id": {
+							Type:        schema.TypeString,
+							Computed:    true,
+							Description: "Gpu id, could be used in the vm gpus list.",
+						},
+						"vendor": {
+							Type:        schema.TypeString,
+							Computed:    true,
+							Description: "Gpu vendor.",
+						},
+						"device": {
+							Type:        schema.TypeString,
+							Computed:    true,
+							Description: "Gpu device.",
+						},
+						"contract": {
+							Type:        schema.TypeInt,
+							Computed:    true,
+							Description: "Id of the contract using the gpu, 0 if not used.",
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+func gridCapacityToMap(c gridtypes.Capacity) []interface{} {
+	return []interface{}{map[string]interface{}{
+		"cru": int(c.CRU),
+		"mru": int(uint64(c.MRU) / uint64(gridtypes.Megabyte)),
+		"sru": int(uint64(c.SRU) / uint64(gridtypes.Megabyte)),
+		"hru": int(uint64(c.HRU) / uint64(gridtypes.Megabyte)),
+	}}
+}
+
+func dataSourceNodeRead(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	var diags diag.Diagnostics
+	tfPluginClient, ok := meta.(*deployer.TFPluginClient)
+	if !ok {
+		return diag.FromErr(fmt.Errorf("failed to cast meta into threefold plugin client"))
+	}
+
+	ctx = resourceLogContext(ctx, "grid_node", d)
+
+	nodeID := uint32(d.Get("node").(int))
+
+	var nodeClient *client.NodeClient
+	err := traceCall(ctx, "NcPool.GetNodeClient", func() (err error) {
+		nodeClient, err = tfPluginClient.NcPool.GetNodeClient(tfPluginClient.SubstrateConn, nodeID)
+		return err
+	})
+	if err != nil {
+		return diag.FromErr(errors.Wrapf(err, "failed to get node client with ID %d", nodeID))
+	}
+
+	var features []string
+	err = traceCall(ctx, "NodeClient.SystemGetNodeFeatures", func() (err error) {
+		features, err = nodeClient.SystemGetNodeFeatures(ctx)
+		return err
+	})
+	if err != nil {
+		return diag.FromErr(errors.Wrapf(err, "couldn't get node %d features", nodeID))
+	}
+
+	var version client.Version
+	err = traceCall(ctx, "NodeClient.SystemVersion", func() (err error) {
+		version, err = nodeClient.SystemVersion(ctx)
+		return err
+	})
+	if err != nil {
+		return diag.FromErr(errors.Wrapf(err, "couldn't get node %d version", nodeID))
+	}
+
+	var total, used gridtypes.Capacity
+	err = traceCall(ctx, "NodeClient.Statistics", func() (err error) {
+		total, used, err = nodeClient.Statistics(ctx)
+		return err
+	})
+	if err != nil {
+		return diag.FromErr(errors.Wrapf(err, "couldn't get node %d statistics", nodeID))
+	}
+
+	var nodeGPUs []client.GPU
+	err = traceCall(ctx, "NodeClient.GPUs", func() (err error) {
+		nodeGPUs, err = nodeClient.GPUs(ctx)
+		return err
+	})
+	if err != nil {
+		return diag.FromErr(errors.Wrapf(err, "couldn't get node %d gpus", nodeID))
+	}
+
+	// the node returns an error if it has no public config
+	publicConfig := make([]interface{}, 0)
+	var cfg client.PublicConfig
+	err = traceCall(ctx, "NodeClient.NetworkGetPublicConfig", func() (err error) {
+		cfg, err = nodeClient.NetworkGetPublicConfig(ctx)
+		return err
+	})
+	if err != nil {
+		tflog.Debug(ctx, "node has no public config", map[string]interface{}{"node_id": nodeID, "error": err.Error()})
+	} else {
+		publicConfig = append(publicConfig, map[string]interface{}{
+			"domain": cfg.Domain,
+			"ipv4":   cfg.IPv4.String(),
+			"ipv6":   cfg.IPv6.String(),
+			"gw4":    cfg.GW4.String(),
+			"gw6":    cfg.GW6.String(),
+		})
+	}
+
+	node, err := tfPluginClient.GridProxyClient.Node(ctx, nodeID)
+	if err != nil {
+		return diag.FromErr(errors.Wrapf(err, "couldn't get node %d from the grid proxy", nodeID))
+	}
+
+	gpuIDRegex := regexp.MustCompile(gpuValidationRegex)
+	gpus := make([]interface{}, 0, len(nodeGPUs))
+	for _, gpu := range nodeGPUs {
+		if !gpuIDRegex.MatchString(gpu.ID) {
+			diags = append(diags, diag.Diagnostic{
+				Severity: diag.Warning,
+				Summary:  fmt.Sprintf("skipping gpu %q on node %d", gpu.ID, nodeID),
+				Detail:   gpuValidationErrMsg,
+			})
+			continue
+		}
+
+		gpus = append(gpus, map[string]interface{}{
+			"id":       gpu.ID,
+			"vendor":   gpu.Vendor,
+			"device":   gpu.Device,
+			"contract": int(gpu.Contract),
+		})
+	}
+
+	values := map[string]interface{}{
+		"farm_id":         node.FarmID,
+		"farm_name":       node.FarmName,
+		"twin_id":         node.TwinID,
+		"features":        features,
+		"light":           slices.Contains(features, zos.NetworkLightType),
+		"zos_version":     version.ZOS,
+		"public_config":   publicConfig,
+		"total_resources": gridCapacityToMap(total),
+		"used_resources":  gridCapacityToMap(used),
+		"gpus":            gpus,
+	}
+
+	for attr, value := range values {
+		if err := d.Set(attr, value); err != nil {
+			return append(diags, diag.FromErr(errors.Wrapf(err, "couldn't set %s", attr))...)
+		}
+	}
+
+	d.SetId(fmt.Sprint(nodeID))
+	return diags
+}
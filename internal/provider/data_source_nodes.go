@@ -0,0 +1,456 @@
+// Package provider is the terraform provider
+package provider
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
+	"github.com/pkg/errors"
+	"github.com/threefoldtech/tfgrid-sdk-go/grid-client/deployer"
+	proxyTypes "github.com/threefoldtech/tfgrid-sdk-go/grid-proxy/pkg/types"
+	"github.com/threefoldtech/zos/pkg/gridtypes"
+)
+
+// nodesPageSize is the number of nodes fetched from the grid proxy per request
+const nodesPageSize = 50
+
+func dataSourceNodes() *schema.Resource {
+	return &schema.Resource{
+		// This description is used by the documentation generator and the language server.
+		Description: "Data source for searching the grid nodes using the grid proxy. Only nodes matching all of the given filters are returned.",
+
+		ReadContext: dataSourceNodesRead,
+
+		Schema: map[string]*schema.Schema{
+			"farm_ids": {
+				Type:        schema.TypeList,
+				Optional:    true,
+				Elem:        &schema.Schema{Type: schema.TypeInt},
+				Description: "List of farm ids to search for nodes in.",
+			},
+			"country": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				Description: "Country of the nodes.",
+			},
+			"city": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				Description: "City of the nodes.",
+			},
+			"free_mru": {
+				Type:        schema.TypeInt,
+				Optional:    true,
+				Description: "Minimum free memory size in MBs.",
+			},
+			"free_sru": {
+				Type:        schema.TypeInt,
+				Optional:    true,
+				Description: "Minimum free disk SSD size in MBs.",
+			},
+			"free_hru": {
+				Type:        schema.TypeInt,
+				Optional:    true,
+				Description: "Minimum free disk HDD size in MBs.",
+			},
+			"free_ips": {
+				Type:        schema.TypeInt,
+				Optional:    true,
+				Description: "Minimum count of free public ips in the node's farm.",
+			},
+			"gpu_vendor_name": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				Description: "Vendor name of a gpu on the nodes (e.g. NVIDIA).",
+			},
+			"gpu_device_name": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				Description: "Device name of a gpu on the nodes.",
+			},
+			"features": {
+				Type:        schema.TypeList,
+				Optional:    true,
+				Elem:        &schema.Schema{Type: schema.TypeString},
+				Description: "List of zos features the nodes must support (e.g. zmachine, network, zmachine-light, network-light).",
+			},
+			"rentable": {
+				Type:        schema.TypeBool,
+				Optional:    true,
+				Description: "Flag to pick only rentable (or only non rentable if false) nodes.",
+			},
+			"rented": {
+				Type:        schema.TypeBool,
+				Optional:    true,
+				Description: "Flag to pick only rented (or only non rented if false) nodes.",
+			},
+			"certification_type": {
+				Type:             schema.TypeString,
+				Optional:         true,
+				Description:      "Certification type of the nodes, `Diy` or `Certified`.",
+				ValidateDiagFunc: validation.ToDiagFunc(validation.StringInSlice([]string{"Diy", "Certified"}, false)),
+			},
+			"status": {
+				Type:        schema.TypeList,
+				Optional:    true,
+				Elem:        &schema.Schema{Type: schema.TypeString, ValidateDiagFunc: validation.ToDiagFunc(validation.StringInSlice([]string{"up", "down", "standby"}, false))},
+				Description: "List of accepted node statuses (`up`, `down` or `standby`). Defaults to `up`.",
+			},
+			"limit": {
+				Type:             schema.TypeInt,
+				Optional:         true,
+				Default:          0,
+				Description:      "Maximum number of nodes to return. 0 returns all matching nodes.",
+				ValidateDiagFunc: validation.ToDiagFunc(validation.IntAtLeast(0)),
+			},
+			"node_ids": {
+				Type:        schema.TypeList,
+				Computed:    true,
+				Elem:        &schema.Schema{Type: schema.TypeInt},
+				Description: "List of the matching node ids.",
+			},
+			"nodes": {
+				Type:        schema.TypeList,
+				Computed:    true,
+				Description: "List of the matching nodes.",
+				Elem: &schema.Resource{
+					Schema: map[string]*schema.Schema{
+						"node_id": {
+							Type:        schema.TypeInt,
+							Computed:    true,
+							Description: "Node id.",
+						},
+						"farm_id": {
+							Type:        schema.TypeInt,
+							Computed:    true,
+							Description: "Farm id of the node.",
+						},
+						"twin_id": {
+							Type:        schema.TypeInt,
+							Computed:    true,
+							Description: "Twin id of the node.",
+						},
+						"country": {
+							Type:        schema.TypeString,
+							Computed:    true,
+							Description: "Country of the node.",
+						},
+						"city": {
+							Type:        schema.TypeString,
+							Computed:    true,
+							Description: "City of the node.",
+						},
+						"status": {
+							Type:        schema.TypeString,
+							Computed:    true,
+							Description: "Status of the node.",
+						},
+						"certification_type": {
+							Type:        schema.TypeString,
+							Computed:    true,
+							Description: "Certification type of the node.",
+						},
+						"rentable": {
+							Type:        schema.TypeBool,
+							Computed:    true,
+							Description: "True if the node could be rented.",
+						},
+						"rented": {
+							Type:        schema.TypeBool,
+							Computed:    true,
+							Description: "True if the node is rented.",
+						},
+						"features": {
+							Type:        schema.TypeList,
+							Computed:    true,
+							Elem:        &schema.Schema{Type: schema.TypeString},
+							Description: "Zos features supported by the node.",
+						},
+						"public_config": {
+							Type:        schema.TypeList,
+							Computed:    true,
+							Description: "Public config of the node.",
+							Elem:        nodePublicConfigSchema(),
+						},
+						"total_resources": {
+							Type:        schema.TypeList,
+							Computed:    true,
+							Description: "Total capacity of the node.",
+							Elem:        nodeCapacitySchema(),
+						},
+						"used_resources": {
+							Type:        schema.TypeList,
+							Computed:    true,
+							Description: "Used capacity of the node.",
+							Elem:        nodeCapacitySchema(),
+						},
+						"gpus": {
+							Type:        schema.TypeList,
+							Computed:    true,
+							Description: "List of the gpus on the node.",
+							Elem: &schema.Resource{
+								Schema: map[string]*schema.Schema{
+									"id": {
+										Type:        schema.TypeString,
+										Computed:    true,
+										Description: "Gpu id.",
+									},
+									"vendor": {
+										Type:        schema.TypeString,
+										Computed:    true,
+										Description: "Gpu vendor.",
+									},
+									"device": {
+										Type:        schema.TypeString,
+										Computed:    true,
+										Description: "Gpu device.",
+									},
+									"contract": {
+										Type:        schema.TypeInt,
+										Computed:    true,
+										Description: "Id of the contract using the gpu, 0 if not used.",
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+func nodePublicConfigSchema() *schema.Resource {
+	return &schema.Resource{
+		Schema: map[string]*schema.Schema{
+			"domain": {
+				Type:        schema.TypeString,
+				Computed:    true,
+				Description: "Domain of the node.",
+			},
+			"ipv4": {
+				Type:        schema.TypeString,
+				Computed:    true,
+				Description: "Public ipv4 of the node.",
+			},
+			"ipv6": {
+				Type:        schema.TypeString,
+				Computed:    true,
+				Description: "Public ipv6 of the node.",
+			},
+			"gw4": {
+				Type:        schema.TypeString,
+				Computed:    true,
+				Description: "Ipv4 gateway of the node.",
+			},
+			"gw6": {
+				Type:        schema.TypeString,
+				Computed:    true,
+				Description: "Ipv6 gateway of the node.",
+			},
+		},
+	}
+}
+
+func nodeCapacitySchema() *schema.Resource {
+	return &schema.Resource{
+		Schema: map[string]*schema.Schema{
+			"cru": {
+				Type:        schema.TypeInt,
+				Computed:    true,
+				Description: "Number of virtual CPUs.",
+			},
+			"mru": {
+				Type:        schema.TypeInt,
+				Computed:    true,
+				Description: "Memory size in MBs.",
+			},
+			"sru": {
+				Type:        schema.TypeInt,
+				Computed:    true,
+				Description: "Disk SSD size in MBs.",
+			},
+			"hru": {
+				Type:        schema.TypeInt,
+				Computed:    true,
+				Description: "Disk HDD size in MBs.",
+			},
+		},
+	}
+}
+
+// getOptionalBool returns the configured value of an optional bool attribute, or nil if it is not set
+func getOptionalBool(d *schema.ResourceData, key string) *bool {
+	value := d.GetRawConfig().GetAttr(key)
+	if value.IsNull() || !value.IsKnown() {
+		return nil
+	}
+
+	b := value.True()
+	return &b
+}
+
+// newNodeFilterFromSchema reads the nodes data source filters from the schema.ResourceData and converts them into a grid proxy node filter
+func newNodeFilterFromSchema(d *schema.ResourceData) (f proxyTypes.NodeFilter) {
+	f.Status = []string{"up"}
+	if statuses := d.Get("status").([]interface{}); len(statuses) != 0 {
+		f.Status = nil
+		for _, status := range statuses {
+			f.Status = append(f.Status, status.(string))
+		}
+	}
+
+	for _, farmID := range d.Get("farm_ids").([]interface{}) {
+		f.FarmIDs = append(f.FarmIDs, uint64(farmID.(int)))
+	}
+
+	for _, feature := range d.Get("features").([]interface{}) {
+		f.Features = append(f.Features, feature.(string))
+	}
+
+	if country := d.Get("country").(string); country != "" {
+		f.Country = &country
+	}
+	if city := d.Get("city").(string); city != "" {
+		f.City = &city
+	}
+
+	if mru := uint64(d.Get("free_mru").(int)); mru != 0 {
+		mru *= uint64(gridtypes.Megabyte)
+		f.FreeMRU = &mru
+	}
+	if sru := uint64(d.Get("free_sru").(int)); sru != 0 {
+		sru *= uint64(gridtypes.Megabyte)
+		f.FreeSRU = &sru
+	}
+	if hru := uint64(d.Get("free_hru").(int)); hru != 0 {
+		hru *= uint64(gridtypes.Megabyte)
+		f.FreeHRU = &hru
+	}
+	if freeIPs := uint64(d.Get("free_ips").(int)); freeIPs != 0 {
+		f.FreeIPs = &freeIPs
+	}
+
+	if vendor := d.Get("gpu_vendor_name").(string); vendor != "" {
+		f.GpuVendorName = &vendor
+	}
+	if device := d.Get("gpu_device_name").(string); device != "" {
+		f.GpuDeviceName = &device
+	}
+
+	if certification := d.Get("certification_type").(string); certification != "" {
+		f.CertificationType = &certification
+	}
+
+	f.Rentable = getOptionalBool(d, "rentable")
+	f.Rented = getOptionalBool(d, "rented")
+
+	return f
+}
+
+func nodeCapacityToMap(c proxyTypes.Capacity) []interface{} {
+	return []interface{}{map[string]interface{}{
+		"cru": int(c.CRU),
+		"mru": int(uint64(c.MRU) / uint64(gridtypes.Megabyte)),
+		"sru": int(uint64(c.SRU) / uint64(gridtypes.Megabyte)),
+		"hru": int(uint64(c.HRU) / uint64(gridtypes.Megabyte)),
+	}}
+}
+
+func nodePublicConfigToMap(c proxyTypes.PublicConfig) []interface{} {
+	return []interface{}{map[string]interface{}{
+		"domain": c.Domain,
+		"ipv4":   c.Ipv4,
+		"ipv6":   c.Ipv6,
+		"gw4":    c.Gw4,
+		"gw6":    c.Gw6,
+	}}
+}
+
+func nodeToMap(node proxyTypes.Node) map[string]interface{} {
+	gpus := make([]interface{}, 0, len(node.GPUs))
+	for _, gpu := range node.GPUs {
+		gpus = append(gpus, map[string]interface{}{
+			"id":       gpu.ID,
+			"vendor":   gpu.Vendor,
+			"device":   gpu.Device,
+			"contract": gpu.Contract,
+		})
+	}
+
+	return map[string]interface{}{
+		"node_id":            node.NodeID,
+		"farm_id":            node.FarmID,
+		"twin_id":            node.TwinID,
+		"country":            node.Country,
+		"city":               node.City,
+		"status":             node.Status,
+		"certification_type": node.CertificationType,
+		"rentable":           node.Rentable,
+		"rented":             node.Rented,
+		"features":           node.Features,
+		"public_config":      nodePublicConfigToMap(node.PublicConfig),
+		"total_resources":    nodeCapacityToMap(node.TotalResources),
+		"used_resources":     nodeCapacityToMap(node.UsedResources),
+		"gpus":               gpus,
+	}
+}
+
+func dataSourceNodesRead(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	tfPluginClient, ok := meta.(*deployer.TFPluginClient)
+	if !ok {
+		return diag.FromErr(fmt.Errorf("failed to cast meta into threefold plugin client"))
+	}
+
+	ctx = resourceLogContext(ctx, "grid_nodes", d)
+
+	filter := newNodeFilterFromSchema(d)
+	limit := d.Get("limit").(int)
+
+	var nodes []proxyTypes.Node
+	for page := uint64(1); ; page++ {
+		var res []proxyTypes.Node
+		err := traceCall(ctx, "GridProxyClient.Nodes", func() (err error) {
+			res, _, err = tfPluginClient.GridProxyClient.Nodes(ctx, filter, proxyTypes.Limit{
+				Size: nodesPageSize,
+				Page: page,
+			})
+			return err
+		})
+		if err != nil {
+			return diag.FromErr(errors.Wrap(err, "couldn't list nodes from the grid proxy"))
+		}
+
+		nodes = append(nodes, res...)
+		if len(res) < nodesPageSize || (limit != 0 && len(nodes) >= limit) {
+			break
+		}
+	}
+
+	if limit != 0 && len(nodes) > limit {
+		nodes = nodes[:limit]
+	}
+
+	nodeIDs := make([]interface{}, 0, len(nodes))
+	nodeMaps := make([]interface{}, 0, len(nodes))
+	for _, node := range nodes {
+		nodeIDs = append(nodeIDs, node.NodeID)
+		nodeMaps = append(nodeMaps, nodeToMap(node))
+	}
+
+	if err := d.Set("node_ids", nodeIDs); err != nil {
+		return diag.FromErr(errors.Wrap(err, "couldn't set node ids"))
+	}
+
+	if err := d.Set("nodes", nodeMaps); err != nil {
+		return diag.FromErr(errors.Wrap(err, "couldn't set nodes"))
+	}
+
+	d.SetId(strconv.FormatInt(time.Now().Unix(), 10))
+	return nil
+}
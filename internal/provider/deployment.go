@@ -6,16 +6,25 @@ import (
 	"encoding/hex"
 	"fmt"
 	"strconv"
+	"strings"
 
 	"github.com/hashicorp/go-multierror"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
 	"github.com/pkg/errors"
+	"github.com/threefoldtech/tfgrid-sdk-go/grid-client/deployer"
 	client "github.com/threefoldtech/tfgrid-sdk-go/grid-client/node"
 	"github.com/threefoldtech/tfgrid-sdk-go/grid-client/subi"
 	"github.com/threefoldtech/tfgrid-sdk-go/grid-client/workloads"
+	"github.com/threefoldtech/tfgrid-sdk-go/grid-client/zos"
 )
 
-func newDeploymentFromSchema(ctx context.Context, d *schema.ResourceData, ncPool client.NodeClientGetter, sub subi.SubstrateExt) (*workloads.Deployment, error) {
+// schemaReader reads the resource configuration from either the resource data or the planned resource diff
+type schemaReader interface {
+	Get(key string) interface{}
+	Id() string
+}
+
+func newDeploymentFromSchema(ctx context.Context, d schemaReader, ncPool client.NodeClientGetter, sub subi.SubstrateExt) (*workloads.Deployment, error) {
 	networkName := d.Get("network_name").(string)
 	nodeID := uint32(d.Get("node").(int))
 
@@ -30,13 +39,9 @@ func newDeploymentFromSchema(ctx context.Context, d *schema.ResourceData, ncPool
 		solutionType = fmt.Sprintf("vm/%s", name)
 	}
 
-	disks := make([]workloads.Disk, 0)
-	for _, disk := range d.Get("disks").([]interface{}) {
-		d, err := workloads.NewWorkloadFromMap(disk.(map[string]interface{}), &workloads.Disk{})
-		if err != nil {
-			return nil, errors.Wrap(err, "failed to create workload from disk map")
-		}
-		disks = append(disks, *(d.(*workloads.Disk)))
+	disks, err := newDisksFromMaps(d.Get("disks").([]interface{}))
+	if err != nil {
+		return nil, err
 	}
 
 	zdbs := make([]workloads.ZDB, 0)
@@ -48,47 +53,11 @@ func newDeploymentFromSchema(ctx context.Context, d *schema.ResourceData, ncPool
 		zdbs = append(zdbs, *(z.(*workloads.ZDB)))
 	}
 
-	vms := make([]workloads.VM, 0)
-	vmsLight := make([]workloads.VMLight, 0)
-	for _, vm := range d.Get("vms").([]interface{}) {
-		vmMap := vm.(map[string]interface{})
-		vmMap["network_name"] = networkName
-
-		myceliumIPSeed := vmMap["mycelium_ip_seed"].(string)
-		myceliumIPSeedBytes, err := hex.DecodeString(myceliumIPSeed)
-		if err != nil {
-			return nil, errors.Wrapf(err, "failed to decode mycelium ip seed '%s'", myceliumIPSeed)
-		}
-		vmMap["mycelium_ip_seed"] = myceliumIPSeedBytes
-
-		if light {
-			v, err := workloads.NewWorkloadFromMap(vmMap, &workloads.VMLight{})
-			if err != nil {
-				return nil, errors.Wrap(err, "failed to create workload from vm map")
-			}
-
-			vmWorkload := *v.(*workloads.VMLight)
-			vmWorkload.NodeID = nodeID
-			vmsLight = append(vmsLight, vmWorkload)
-			continue
-		}
-
-		v, err := workloads.NewWorkloadFromMap(vmMap, &workloads.VM{})
-		if err != nil {
-			return nil, errors.Wrap(err, "failed to create workload from vm map")
-		}
-
-		vmWorkload := *v.(*workloads.VM)
-		vmWorkload.NodeID = nodeID
-		vms = append(vms, vmWorkload)
+	vms, vmsLight, err := newVMsFromMaps(d.Get("vms").([]interface{}), networkName, nodeID, light)
+	if err != nil {
+		return nil, err
 	}
 
-	// TODO: ip_range
-	// err = r.Set("ip_range", d.IPRange)
-	// if err != nil {
-	// 	errors = multierror.Append(errors, err)
-	// }
-
 	qsfs := make([]workloads.QSFS, 0)
 	for _, qsfsdata := range d.Get("qsfs").([]interface{}) {
 		qsfsI := qsfsdata.(map[string]interface{})
@@ -129,6 +98,7 @@ func newDeploymentFromSchema(ctx context.Context, d *schema.ResourceData, ncPool
 		QSFS:             qsfs,
 		Zdbs:             zdbs,
 		NetworkName:      networkName,
+		IPrange:          d.Get("ip_range").(string),
 		ContractID:       contractID,
 		NodeDeploymentID: nodeDeploymentID,
 	}
@@ -136,33 +106,132 @@ func newDeploymentFromSchema(ctx context.Context, d *schema.ResourceData, ncPool
 	return &dl, nil
 }
 
-// syncContractsDeployments updates the terraform local state with the latest changes to workloads
-func syncContractsDeployments(r *schema.ResourceData, d *workloads.Deployment) (errors error) {
-	vms := make([]interface{}, 0)
-	disks := make([]interface{}, 0)
-	zdbs := make([]interface{}, 0)
-	qsfs := make([]interface{}, 0)
+// newDisksFromMaps converts the disks configurations into disk workloads.
+func newDisksFromMaps(diskMaps []interface{}) ([]workloads.Disk, error) {
+	disks := make([]workloads.Disk, 0)
+	for _, disk := range diskMaps {
+		d, err := workloads.NewWorkloadFromMap(disk.(map[string]interface{}), &workloads.Disk{})
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to create workload from disk map")
+		}
+		disks = append(disks, *(d.(*workloads.Disk)))
+	}
 
-	for _, vm := range d.Vms {
-		vmMap, err := workloads.ToMap(vm)
+	return disks, nil
+}
+
+// newVMsFromMaps converts the vms configurations into vm workloads, or vm light workloads if the node is a zos light node.
+func newVMsFromMaps(vmMaps []interface{}, networkName string, nodeID uint32, light bool) ([]workloads.VM, []workloads.VMLight, error) {
+	vms := make([]workloads.VM, 0)
+	vmsLight := make([]workloads.VMLight, 0)
+	for _, vm := range vmMaps {
+		vmMap := vm.(map[string]interface{})
+		vmMap["network_name"] = networkName
+
+		myceliumIPSeed := vmMap["mycelium_ip_seed"].(string)
+		myceliumIPSeedBytes, err := hex.DecodeString(myceliumIPSeed)
 		if err != nil {
-			return err
+			return nil, nil, errors.Wrapf(err, "failed to decode mycelium ip seed '%s'", myceliumIPSeed)
 		}
+		vmMap["mycelium_ip_seed"] = myceliumIPSeedBytes
 
-		vmMap["mycelium_ip_seed"] = hex.EncodeToString(vm.MyceliumIPSeed)
-		delete(vmMap, "network_name")
-		vms = append(vms, vmMap)
-	}
+		if light {
+			v, err := workloads.NewWorkloadFromMap(vmMap, &workloads.VMLight{})
+			if err != nil {
+				return nil, nil, errors.Wrap(err, "failed to create workload from vm map")
+			}
 
-	for _, vm := range d.VmsLight {
-		vmMap, err := workloads.ToMap(vm)
+			vmWorkload := *v.(*workloads.VMLight)
+			vmWorkload.NodeID = nodeID
+			vmsLight = append(vmsLight, vmWorkload)
+			continue
+		}
+
+		v, err := workloads.NewWorkloadFromMap(vmMap, &workloads.VM{})
 		if err != nil {
-			return err
+			return nil, nil, errors.Wrap(err, "failed to create workload from vm map")
 		}
 
-		vmMap["mycelium_ip_seed"] = hex.EncodeToString(vm.MyceliumIPSeed)
-		delete(vmMap, "network_name")
-		vms = append(vms, vmMap)
+		vmWorkload := *v.(*workloads.VM)
+		vmWorkload.NodeID = nodeID
+		vms = append(vms, vmWorkload)
+	}
+
+	return vms, vmsLight, nil
+}
+
+// loadDeploymentFromContract fetches the deployment of the given node contract from its node, and converts it into a deployment instance.
+func loadDeploymentFromContract(ctx context.Context, tfPluginClient *deployer.TFPluginClient, contractID uint64) (*workloads.Deployment, error) {
+	contract, err := tfPluginClient.SubstrateConn.GetContract(contractID)
+	if err != nil {
+		return nil, errors.Wrapf(err, "couldn't get contract %d", contractID)
+	}
+
+	if err := checkImportedContract(contract, contractID, tfPluginClient.TwinID); err != nil {
+		return nil, err
+	}
+
+	nodeID := uint32(contract.ContractType.NodeContract.Node)
+	nodeClient, err := tfPluginClient.NcPool.GetNodeClient(tfPluginClient.SubstrateConn, nodeID)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to get node client '%d'", nodeID)
+	}
+
+	zosDeployment, err := nodeClient.DeploymentGet(ctx, contractID)
+	if err != nil {
+		return nil, errors.Wrapf(err, "couldn't get deployment %d from node %d", contractID, nodeID)
+	}
+
+	return newDeploymentFromContract(contract, zosDeployment)
+}
+
+// checkImportedContract checks that the given contract is an active node contract owned by the given twin.
+func checkImportedContract(contract subi.Contract, contractID uint64, twinID uint32) error {
+	if !contract.ContractType.IsNodeContract {
+		return fmt.Errorf("contract %d is not a node contract", contractID)
+	}
+
+	if contract.IsDeleted() {
+		return fmt.Errorf("contract %d is deleted", contractID)
+	}
+
+	if contract.TwinID() != twinID {
+		return fmt.Errorf("contract %d is not owned by twin %d", contractID, twinID)
+	}
+
+	return nil
+}
+
+// newDeploymentFromContract converts the zos deployment of a node contract into a deployment instance. The contract deployment
+// data is used as the deployment metadata if the node deployment has none.
+func newDeploymentFromContract(contract subi.Contract, zosDeployment zos.Deployment) (*workloads.Deployment, error) {
+	if len(strings.TrimSpace(zosDeployment.Metadata)) == 0 {
+		zosDeployment.Metadata = contract.ContractType.NodeContract.DeploymentData
+	}
+
+	nodeID := uint32(contract.ContractType.NodeContract.Node)
+	dl, err := workloads.NewDeploymentFromZosDeployment(zosDeployment, nodeID)
+	if err != nil {
+		return nil, errors.Wrapf(err, "couldn't load deployment %d", zosDeployment.ContractID)
+	}
+
+	if ok, providerID := contract.SolutionProviderID.Unwrap(); ok {
+		solutionProvider := uint64(providerID)
+		dl.SolutionProvider = &solutionProvider
+	}
+
+	return &dl, nil
+}
+
+// syncContractsDeployments updates the terraform local state with the latest changes to workloads
+func syncContractsDeployments(r *schema.ResourceData, d *workloads.Deployment) (errors error) {
+	disks := make([]interface{}, 0)
+	zdbs := make([]interface{}, 0)
+	qsfs := make([]interface{}, 0)
+
+	vms, err := vmsToMaps(d)
+	if err != nil {
+		return err
 	}
 
 	for _, d := range d.Disks {
@@ -191,7 +260,7 @@ func syncContractsDeployments(r *schema.ResourceData, d *workloads.Deployment) (
 		qsfs = append(qsfs, qs)
 	}
 
-	err := r.Set("vms", vms)
+	err = r.Set("vms", vms)
 	if err != nil {
 		errors = multierror.Append(errors, fmt.Errorf("failed to set vms with error: %w", err))
 	}
@@ -235,13 +304,39 @@ func syncContractsDeployments(r *schema.ResourceData, d *workloads.Deployment) (
 		errors = multierror.Append(errors, fmt.Errorf("failed to set solution provider with error: %w", err))
 	}
 
-	/* TODO: iprange
-	err = r.Set("ip_range", d.IPRange)
+	err = r.Set("ip_range", d.IPrange)
 	if err != nil {
 		errors = multierror.Append(errors, fmt.Errorf("failed to set ip range with error: %w", err))
 	}
-	*/
 
 	r.SetId(fmt.Sprint(d.ContractID))
 	return
 }
+
+// vmsToMaps converts the vms and vm light workloads of a deployment into maps to be stored in the terraform state.
+func vmsToMaps(dl *workloads.Deployment) ([]interface{}, error) {
+	vms := make([]interface{}, 0)
+	for _, vm := range dl.Vms {
+		vmMap, err := workloads.ToMap(vm)
+		if err != nil {
+			return nil, err
+		}
+
+		vmMap["mycelium_ip_seed"] = hex.EncodeToString(vm.MyceliumIPSeed)
+		delete(vmMap, "network_name")
+		vms = append(vms, vmMap)
+	}
+
+	for _, vm := range dl.VmsLight {
+		vmMap, err := workloads.ToMap(vm)
+		if err != nil {
+			return nil, err
+		}
+
+		vmMap["mycelium_ip_seed"] = hex.EncodeToString(vm.MyceliumIPSeed)
+		delete(vmMap, "network_name")
+		vms = append(vms, vmMap)
+	}
+
+	return vms, nil
+}
@@ -0,0 +1,91 @@
+// Package provider is the terraform provider
+package provider
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/centrifuge/go-substrate-rpc-client/v4/types"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	substrate "github.com/threefoldtech/tfchain/clients/tfchain-client-go"
+	"github.com/threefoldtech/tfgrid-sdk-go/grid-client/subi"
+	"github.com/threefoldtech/tfgrid-sdk-go/grid-client/workloads"
+	"github.com/threefoldtech/tfgrid-sdk-go/grid-client/zos"
+)
+
+func testSubstrateNodeContract(twinID, nodeID uint32, deploymentData string) subi.Contract {
+	return subi.Contract{Contract: &substrate.Contract{
+		State:  substrate.ContractState{IsCreated: true},
+		TwinID: types.U32(twinID),
+		ContractType: substrate.ContractType{
+			IsNodeContract: true,
+			NodeContract: substrate.NodeContract{
+				Node:           types.U32(nodeID),
+				DeploymentData: deploymentData,
+			},
+		},
+	}}
+}
+
+func TestCheckImportedContract(t *testing.T) {
+	contract := testSubstrateNodeContract(7, 11, "")
+	assert.NoError(t, checkImportedContract(contract, 1, 7))
+	assert.ErrorContains(t, checkImportedContract(contract, 1, 8), "contract 1 is not owned by twin 8")
+
+	contract.State = substrate.ContractState{IsDeleted: true}
+	assert.ErrorContains(t, checkImportedContract(contract, 1, 7), "contract 1 is deleted")
+
+	name := subi.Contract{Contract: &substrate.Contract{
+		TwinID:       7,
+		ContractType: substrate.ContractType{IsNameContract: true},
+	}}
+	assert.ErrorContains(t, checkImportedContract(name, 1, 7), "contract 1 is not a node contract")
+}
+
+func TestImportDeploymentFromContract(t *testing.T) {
+	dl := workloads.Deployment{
+		Name:         "dl",
+		NodeID:       11,
+		SolutionType: "myproject",
+		Disks:        []workloads.Disk{{Name: "disk", SizeGB: 5, Description: "data"}},
+		Zdbs:         []workloads.ZDB{{Name: "zdb", Password: "password", SizeGB: 2, Mode: workloads.ZDBModeUser}},
+	}
+
+	zosDeployment, err := dl.ZosDeployment(7)
+	require.NoError(t, err)
+	zosDeployment.ContractID = 42
+
+	result, err := json.Marshal(zos.ZDBResult{Namespace: "ns", IPs: []string{"::1"}, Port: 9900})
+	require.NoError(t, err)
+	for i := range zosDeployment.Workloads {
+		if zosDeployment.Workloads[i].Type == zos.ZDBType {
+			zosDeployment.Workloads[i].Result.Data = result
+		}
+	}
+
+	// the contract deployment data is used if the node deployment has no metadata
+	zosDeployment.Metadata = ""
+	contract := testSubstrateNodeContract(7, 11, `{"version":0,"type":"vm","name":"dl","projectName":"myproject"}`)
+	contract.SolutionProviderID = types.NewOptionU64(types.U64(3))
+
+	imported, err := newDeploymentFromContract(contract, zosDeployment)
+	require.NoError(t, err)
+
+	d := schema.TestResourceDataRaw(t, resourceDeployment().Schema, map[string]interface{}{})
+	require.NoError(t, syncContractsDeployments(d, imported))
+
+	assert.Equal(t, "42", d.Id())
+	assert.Equal(t, 11, d.Get("node"))
+	assert.Equal(t, "myproject", d.Get("solution_type"))
+	assert.Equal(t, 3, d.Get("solution_provider"))
+	assert.Equal(t, "disk", d.Get("disks.0.name"))
+	assert.Equal(t, 5, d.Get("disks.0.size"))
+	assert.Equal(t, "zdb", d.Get("zdbs.0.name"))
+	assert.Equal(t, 2, d.Get("zdbs.0.size"))
+	assert.Equal(t, workloads.ZDBModeUser, d.Get("zdbs.0.mode"))
+	assert.Equal(t, "ns", d.Get("zdbs.0.namespace"))
+	assert.Equal(t, 9900, d.Get("zdbs.0.port"))
+	assert.Empty(t, d.Get("vms"))
+}
@@ -8,6 +8,9 @@ import (
 	"github.com/hashicorp/go-multierror"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
 	"github.com/pkg/errors"
+	substrate "github.com/threefoldtech/tfchain/clients/tfchain-client-go"
+	"github.com/threefoldtech/tfgrid-sdk-go/grid-client/deployer"
+	"github.com/threefoldtech/tfgrid-sdk-go/grid-client/subi"
 	"github.com/threefoldtech/tfgrid-sdk-go/grid-client/workloads"
 	"github.com/threefoldtech/zos/pkg/gridtypes/zos"
 )
@@ -62,6 +65,12 @@ func newNameGatewayFromSchema(d *schema.ResourceData) (*workloads.GatewayNamePro
 		NameContractID:   uint64(d.Get("name_contract_id").(int)),
 		ContractID:       contractID,
 	}
+
+	// a name contract reserved by a grid_name_contract resource takes over the one created by the gateway
+	if reserved := uint64(d.Get("name_contract").(int)); reserved != 0 {
+		gw.NameContractID = reserved
+	}
+
 	return &gw, nil
 }
 
@@ -130,3 +139,55 @@ func validateBackends(backnends []zos.Backend, tlsPassthrough bool) error {
 
 	return nil
 }
+
+// reservedNameSubstrate is a substrate client that never cancels a name contract reserved by a grid_name_contract resource
+type reservedNameSubstrate struct {
+	subi.SubstrateExt
+	nameContractID uint64
+}
+
+// CancelContract cancels a contract unless it's the reserved name contract
+func (s *reservedNameSubstrate) CancelContract(identity substrate.Identity, contractID uint64) error {
+	if contractID == s.nameContractID {
+		return nil
+	}
+	return s.SubstrateExt.CancelContract(identity, contractID)
+}
+
+// EnsureContractCanceled ensures a contract is canceled unless it's the reserved name contract
+func (s *reservedNameSubstrate) EnsureContractCanceled(identity substrate.Identity, contractID uint64) error {
+	if contractID == s.nameContractID {
+		return nil
+	}
+	return s.SubstrateExt.EnsureContractCanceled(identity, contractID)
+}
+
+// gatewayNameDeployer returns the gateway name deployer to use for a gateway using the given reserved name contract.
+// The returned deployer leaves the reserved name contract alone when the gateway is canceled or fails to deploy.
+func gatewayNameDeployer(tfPluginClient *deployer.TFPluginClient, reservedNameContractID uint64) deployer.GatewayNameDeployer {
+	if reservedNameContractID == 0 {
+		return tfPluginClient.GatewayNameDeployer
+	}
+
+	client := *tfPluginClient
+	client.SubstrateConn = &reservedNameSubstrate{SubstrateExt: tfPluginClient.SubstrateConn, nameContractID: reservedNameContractID}
+	return deployer.NewGatewayNameDeployer(&client)
+}
+
+// validateReservedNameContract ensures the given reserved name contract is active and reserves the given name
+func validateReservedNameContract(sub subi.SubstrateExt, contractID uint64, name string) error {
+	contract, err := sub.GetContract(contractID)
+	if err != nil {
+		return errors.Wrapf(err, "couldn't get name contract %d", contractID)
+	}
+
+	if !contract.IsCreated() || !contract.ContractType.IsNameContract {
+		return fmt.Errorf("contract %d is not an active name contract", contractID)
+	}
+
+	if reserved := contract.ContractType.NameContract.Name; reserved != name {
+		return fmt.Errorf("name contract %d reserves the name %q, not %q", contractID, reserved, name)
+	}
+
+	return nil
+}
@@ -2,6 +2,7 @@
 package provider
 
 import (
+	"context"
 	"encoding/hex"
 	"fmt"
 	"strconv"
@@ -9,12 +10,13 @@ import (
 	"github.com/hashicorp/go-multierror"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
 	"github.com/pkg/errors"
+	"github.com/threefoldtech/tfgrid-sdk-go/grid-client/deployer"
 	"github.com/threefoldtech/tfgrid-sdk-go/grid-client/workloads"
 	"github.com/threefoldtech/zos/pkg/gridtypes"
 )
 
-// newK8sFromSchema reads the k8s resource configuration data from the schema.ResourceData, converts them into a new K8s instance, and returns this instance.
-func newK8sFromSchema(d *schema.ResourceData) (*workloads.K8sCluster, error) {
+// newK8sFromSchema reads the k8s resource configuration data from the schema.ResourceData or the planned schema.ResourceDiff, converts them into a new K8s instance, and returns this instance.
+func newK8sFromSchema(d schemaReader) (*workloads.K8sCluster, error) {
 	networkName := d.Get("network_name").(string)
 	nodesIPRange := make(map[uint32]gridtypes.IPNet)
 
@@ -88,6 +90,72 @@ func newK8sFromSchema(d *schema.ResourceData) (*workloads.K8sCluster, error) {
 	return &k8s, nil
 }
 
+// loadK8sFromGrid rebuilds a k8s cluster from the twin's node contracts that were deployed with the given cluster name (the master name).
+func loadK8sFromGrid(ctx context.Context, tfPluginClient *deployer.TFPluginClient, name string) (*workloads.K8sCluster, error) {
+	nodeDeploymentID, deploymentData, err := nodeContractsByName(tfPluginClient, name, workloads.K8sType)
+	if err != nil {
+		return nil, err
+	}
+
+	k8s := workloads.K8sCluster{
+		Master:           &workloads.K8sNode{VM: &workloads.VM{Name: name}},
+		Workers:          make([]workloads.K8sNode, 0),
+		SolutionType:     deploymentData.ProjectName,
+		NodeDeploymentID: nodeDeploymentID,
+		NodesIPRange:     make(map[uint32]gridtypes.IPNet),
+	}
+
+	if err := tfPluginClient.K8sDeployer.UpdateFromRemote(ctx, &k8s); err != nil {
+		return nil, errors.Wrap(err, "couldn't update k8s cluster from remote")
+	}
+
+	if k8s.Master == nil {
+		return nil, fmt.Errorf("couldn't find master node '%s' in the cluster deployments", name)
+	}
+
+	k8s.Flist = k8s.Master.Flist
+	k8s.FlistChecksum = k8s.Master.FlistChecksum
+	k8s.Entrypoint = k8s.Master.Entrypoint
+
+	net, err := loadNetworkFromGrid(ctx, tfPluginClient, k8s.NetworkName)
+	if err != nil {
+		return nil, errors.Wrapf(err, "couldn't load network '%s' of the cluster", k8s.NetworkName)
+	}
+
+	nodesIPRange := net.GetNodesIPRange()
+	for _, node := range append([]workloads.K8sNode{*k8s.Master}, k8s.Workers...) {
+		if ipRange, ok := nodesIPRange[node.NodeID]; ok {
+			k8s.NodesIPRange[node.NodeID] = gridtypes.IPNet{IPNet: ipRange.IPNet}
+		}
+	}
+
+	return &k8s, nil
+}
+
+// k8sNodeIDs returns the node ids of the master and workers of a k8s cluster
+func k8sNodeIDs(k8s *workloads.K8sCluster) []uint32 {
+	nodeIDs := make([]uint32, 0, len(k8s.Workers)+1)
+	if k8s.Master != nil {
+		nodeIDs = append(nodeIDs, k8s.Master.NodeID)
+	}
+
+	for _, worker := range k8s.Workers {
+		nodeIDs = append(nodeIDs, worker.NodeID)
+	}
+
+	return nodeIDs
+}
+
+// k8sNodesSubnets returns the reserved network subnets of the k8s cluster nodes
+func k8sNodesSubnets(k8s *workloads.K8sCluster) map[uint32]string {
+	nodesSubnets := make(map[uint32]string)
+	for nodeID, ipRange := range k8s.NodesIPRange {
+		nodesSubnets[nodeID] = ipRange.String()
+	}
+
+	return nodesSubnets
+}
+
 func retainChecksums(workers []interface{}, master interface{}, k8s *workloads.K8sCluster) {
 	checksumMap := make(map[string]string)
 	checksumMap[k8s.Master.Name] = k8s.Master.FlistChecksum
@@ -183,6 +251,27 @@ func storeK8sState(d *schema.ResourceData, k8s *workloads.K8sCluster) (errors er
 	return
 }
 
+// storeImportedK8s sets the attributes of an imported k8s cluster, including the ones only known from the configuration of a managed cluster
+func storeImportedK8s(d *schema.ResourceData, name string, k8sCluster *workloads.K8sCluster) error {
+	if err := d.Set("name", name); err != nil {
+		return errors.Wrap(err, "couldn't set k8s cluster name")
+	}
+
+	if err := d.Set("flist", k8sCluster.Flist); err != nil {
+		return errors.Wrap(err, "couldn't set k8s cluster flist")
+	}
+
+	if err := d.Set("entrypoint", k8sCluster.Entrypoint); err != nil {
+		return errors.Wrap(err, "couldn't set k8s cluster entrypoint")
+	}
+
+	if err := storeK8sState(d, k8sCluster); err != nil {
+		return errors.Wrap(err, "couldn't set k8s cluster data to the resource")
+	}
+
+	return nil
+}
+
 func removeExtraFieldsFromK8sNode(k8sNode map[string]interface{}) {
 	delete(k8sNode, "zlogs")
 	delete(k8sNode, "gpus")
@@ -0,0 +1,56 @@
+// Package provider is the terraform provider
+package provider
+
+import (
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/threefoldtech/tfgrid-sdk-go/grid-client/workloads"
+	"github.com/threefoldtech/zos/pkg/gridtypes"
+)
+
+func TestStoreImportedK8s(t *testing.T) {
+	flist := "https://hub.grid.tf/tf-official-apps/threefoldtech-k3s-latest.flist"
+	k8s := &workloads.K8sCluster{
+		Master: &workloads.K8sNode{VM: &workloads.VM{
+			Name:       "master",
+			NodeID:     11,
+			Flist:      flist,
+			Entrypoint: "/sbin/zinit init",
+			CPU:        2,
+			MemoryMB:   2048,
+		}},
+		Workers: []workloads.K8sNode{{VM: &workloads.VM{
+			Name:       "worker",
+			NodeID:     12,
+			Flist:      flist,
+			Entrypoint: "/sbin/zinit init",
+			CPU:        1,
+			MemoryMB:   1024,
+		}}},
+		Token:            "token",
+		NetworkName:      "net",
+		SolutionType:     "kubernetes/master",
+		Flist:            flist,
+		Entrypoint:       "/sbin/zinit init",
+		NodeDeploymentID: map[uint32]uint64{11: 1, 12: 2},
+		NodesIPRange:     map[uint32]gridtypes.IPNet{},
+	}
+
+	d := schema.TestResourceDataRaw(t, resourceKubernetes().Schema, map[string]interface{}{})
+	require.NoError(t, storeImportedK8s(d, "master", k8s))
+
+	assert.Equal(t, "master", d.Get("name"))
+	assert.Equal(t, flist, d.Get("flist"))
+	assert.Equal(t, "/sbin/zinit init", d.Get("entrypoint"))
+	assert.Equal(t, "net", d.Get("network_name"))
+	assert.Equal(t, "token", d.Get("token"))
+	assert.Equal(t, "kubernetes/master", d.Get("solution_type"))
+	assert.Equal(t, map[string]interface{}{"11": 1, "12": 2}, d.Get("node_deployment_id"))
+	assert.Equal(t, "master", d.Get("master.0.name"))
+	assert.Equal(t, 11, d.Get("master.0.node"))
+	assert.Equal(t, "worker", d.Get("workers.0.name"))
+	assert.Equal(t, 1024, d.Get("workers.0.memory"))
+}
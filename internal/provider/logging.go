@@ -0,0 +1,51 @@
+// Package provider is the terraform provider
+package provider
+
+import (
+	"context"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+)
+
+// resourceLogContext returns a context whose log entries carry the resource type and id of a CRUD operation
+func resourceLogContext(ctx context.Context, resource string, d *schema.ResourceData) context.Context {
+	ctx = tflog.SetField(ctx, "resource", resource)
+	return tflog.SetField(ctx, "id", d.Id())
+}
+
+// traceCall runs a grid call (rmb or chain) and logs its duration, along with its error if any
+func traceCall(ctx context.Context, call string, fn func() error) error {
+	start := time.Now()
+	err := fn()
+
+	fields := map[string]interface{}{
+		"call":     call,
+		"duration": time.Since(start).String(),
+	}
+
+	if err != nil {
+		fields["error"] = err.Error()
+		tflog.Warn(ctx, "grid call failed", fields)
+		return err
+	}
+
+	tflog.Debug(ctx, "grid call succeeded", fields)
+	return nil
+}
+
+// logNodeContracts logs the outcome of a CRUD operation with the node id and contract id of each of the resource deployments
+func logNodeContracts(ctx context.Context, msg string, nodeDeploymentID map[uint32]uint64) {
+	if len(nodeDeploymentID) == 0 {
+		tflog.Debug(ctx, msg)
+		return
+	}
+
+	for nodeID, contractID := range nodeDeploymentID {
+		tflog.Debug(ctx, msg, map[string]interface{}{
+			"node_id":     nodeID,
+			"contract_id": contractID,
+		})
+	}
+}
@@ -3,15 +3,17 @@ package provider
 
 import (
 	"context"
+	"fmt"
 	"strings"
+	"time"
 
+	"github.com/hashicorp/terraform-plugin-log/tflog"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
 	"github.com/pkg/errors"
 	"github.com/threefoldtech/terraform-provider-grid/internal/state"
 	"github.com/threefoldtech/tfgrid-sdk-go/grid-client/deployer"
-	"github.com/threefoldtech/tfgrid-sdk-go/grid-client/subi"
 )
 
 const errTerraformOutSync = "Error reading data from remote, terraform state might be out of sync with the remote state"
@@ -20,9 +22,9 @@ const nameValidationErrorMessage = "must only include alphanumeric and underscor
 const gpuValidationRegex = "^[A-Za-z0-9:.]+/[A-Za-z0-9]+/[A-Za-z0-9]+$"
 const gpuValidationErrMsg = "not a valid gpu id"
 
-// New returns a new schema.Provider instance, and an open substrate connection
-func New(version string, st state.Getter) (func() *schema.Provider, subi.SubstrateExt) {
-	var substrateConnection subi.SubstrateExt
+// New returns a new schema.Provider instance, and a function closing the grid connections of the configured providers
+func New(version string, st *state.Manager) (func() *schema.Provider, func()) {
+	clients := &pluginClients{}
 	return func() *schema.Provider {
 		p := &schema.Provider{
 			Schema: map[string]*schema.Schema{
@@ -45,33 +47,40 @@ func New(version string, st state.Getter) (func() *schema.Provider, subi.Substra
 				"network": {
 					Type:        schema.TypeString,
 					Required:    true,
-					Description: "grid network, one of: dev test qa main",
+					Description: "grid network, one of: dev test qa main custom. The custom network requires substrate_url, relay_url, proxy_url and graphql_url to be set, using wss and https urls like the public networks",
 					DefaultFunc: schema.EnvDefaultFunc("NETWORK", "dev"),
 					ValidateDiagFunc: validation.ToDiagFunc(validation.StringInSlice(
-						[]string{"dev", "qa", "test", "main"},
+						[]string{"dev", "qa", "test", "main", customNetwork},
 						false,
 					)),
 				},
 				"substrate_url": {
 					Type:             schema.TypeString,
 					Optional:         true,
-					Description:      "substrate url, example: wss://tfchain.dev.grid.tf/ws",
+					Description:      "substrate url, example: wss://tfchain.dev.grid.tf/ws. Multiple fallback urls could be separated by commas",
 					DefaultFunc:      schema.EnvDefaultFunc("SUBSTRATE_URL", nil),
-					ValidateDiagFunc: validation.ToDiagFunc(validation.IsURLWithScheme([]string{"wss"})),
+					ValidateDiagFunc: validation.ToDiagFunc(validateURLs("wss")),
 				},
 				"relay_url": {
 					Type:             schema.TypeString,
 					Optional:         true,
-					Description:      "relay url, example: wss://relay.dev.grid.tf",
+					Description:      "relay url, example: wss://relay.dev.grid.tf. Multiple fallback urls could be separated by commas",
 					DefaultFunc:      schema.EnvDefaultFunc("RELAY_URL", nil),
-					ValidateDiagFunc: validation.ToDiagFunc(validation.IsURLWithScheme([]string{"wss"})),
+					ValidateDiagFunc: validation.ToDiagFunc(validateURLs("wss")),
 				},
 				"proxy_url": {
 					Type:             schema.TypeString,
 					Optional:         true,
-					Description:      "proxy url, example: https://gridproxy.dev.grid.tf",
+					Description:      "proxy url, example: https://gridproxy.dev.grid.tf. Multiple fallback urls could be separated by commas",
 					DefaultFunc:      schema.EnvDefaultFunc("PROXY_URL", nil),
-					ValidateDiagFunc: validation.ToDiagFunc(validation.IsURLWithScheme([]string{"https"})),
+					ValidateDiagFunc: validation.ToDiagFunc(validateURLs("https")),
+				},
+				"graphql_url": {
+					Type:             schema.TypeString,
+					Optional:         true,
+					Description:      "graphql url, example: https://graphql.dev.grid.tf/graphql. Multiple fallback urls could be separated by commas",
+					DefaultFunc:      schema.EnvDefaultFunc("GRAPHQL_URL", nil),
+					ValidateDiagFunc: validation.ToDiagFunc(validateURLs("https")),
 				},
 				"rmb_timeout": {
 					Type:        schema.TypeInt,
@@ -79,76 +88,286 @@ func New(version string, st state.Getter) (func() *schema.Provider, subi.Substra
 					Description: "timeout duration in seconds for rmb calls",
 					DefaultFunc: schema.EnvDefaultFunc("RMB_TIMEOUT", 10),
 				},
+				"max_retries": {
+					Type:             schema.TypeInt,
+					Optional:         true,
+					Description:      "maximum number of retries of failed rmb and chain calls, retries are disabled by default. Calls creating, updating or canceling contracts and deployments are only retried once the chain or the node shows the failed call didn't take effect",
+					DefaultFunc:      schema.EnvDefaultFunc("MAX_RETRIES", 0),
+					ValidateDiagFunc: validation.ToDiagFunc(validation.IntAtLeast(0)),
+				},
+				"initial_backoff": {
+					Type:             schema.TypeInt,
+					Optional:         true,
+					Description:      "duration in seconds to wait before the first retry, it's doubled on each following retry",
+					DefaultFunc:      schema.EnvDefaultFunc("INITIAL_BACKOFF", 1),
+					ValidateDiagFunc: validation.ToDiagFunc(validation.IntAtLeast(1)),
+				},
+				"max_backoff": {
+					Type:             schema.TypeInt,
+					Optional:         true,
+					Description:      "maximum duration in seconds to wait between retries",
+					DefaultFunc:      schema.EnvDefaultFunc("MAX_BACKOFF", 30),
+					ValidateDiagFunc: validation.ToDiagFunc(validation.IntAtLeast(1)),
+				},
+				"retryable_errors": {
+					Type:        schema.TypeList,
+					Optional:    true,
+					Description: "classes of errors to retry, any of: timeout connection rmb substrate. Defaults to timeout and connection errors",
+					Elem: &schema.Schema{
+						Type: schema.TypeString,
+						ValidateDiagFunc: validation.ToDiagFunc(validation.StringInSlice(
+							retryableErrorClasses,
+							false,
+						)),
+					},
+				},
+				"debug": {
+					Type:        schema.TypeBool,
+					Optional:    true,
+					Description: "enable debug logs of the grid client, logs are shown with TF_LOG=DEBUG",
+					DefaultFunc: schema.EnvDefaultFunc("GRID_DEBUG", false),
+				},
+				"state_backend": {
+					Type:        schema.TypeString,
+					Optional:    true,
+					Description: "backend used to store the networks subnets reservations, one of: none local http. The subnets of the twin's networks are always rebuilt from the grid when the provider is configured. With none, nothing is stored, and an existing state.json is only read as a migration source. The default is local to keep the state.json of existing configurations, switching to none is safe since the subnets of deployed networks are rebuilt from the grid",
+					DefaultFunc: schema.EnvDefaultFunc("STATE_BACKEND", state.LocalBackend),
+					ValidateDiagFunc: validation.ToDiagFunc(validation.StringInSlice(
+						[]string{state.NoneBackend, state.LocalBackend, state.HTTPBackend},
+						false,
+					)),
+				},
+				"state_path": {
+					Type:        schema.TypeString,
+					Optional:    true,
+					Description: "state file path or directory for the local state backend (or the migration source for the none backend), defaults to state.json in the current directory. Aliased providers using the same path share one state",
+					DefaultFunc: schema.EnvDefaultFunc("STATE_PATH", nil),
+				},
+				"state_http_url": {
+					Type:             schema.TypeString,
+					Optional:         true,
+					Description:      "state url for the http state backend, the state is fetched with GET and stored with POST requests",
+					DefaultFunc:      schema.EnvDefaultFunc("STATE_HTTP_URL", nil),
+					ValidateDiagFunc: validation.ToDiagFunc(validation.IsURLWithScheme([]string{"http", "https"})),
+				},
+				"state_http_lock_url": {
+					Type:             schema.TypeString,
+					Optional:         true,
+					Description:      "lock url for the http state backend, the state is locked with a LOCK request and unlocked with an UNLOCK request like the terraform http backend, and the lock id is sent in the ID query parameter of the state updates. Without it, the http state isn't locked and concurrent runs could overwrite each other's subnets reservations",
+					DefaultFunc:      schema.EnvDefaultFunc("STATE_HTTP_LOCK_URL", nil),
+					ValidateDiagFunc: validation.ToDiagFunc(validation.IsURLWithScheme([]string{"http", "https"})),
+				},
+				"state_http_unlock_url": {
+					Type:             schema.TypeString,
+					Optional:         true,
+					Description:      "unlock url for the http state backend, defaults to state_http_lock_url",
+					DefaultFunc:      schema.EnvDefaultFunc("STATE_HTTP_UNLOCK_URL", nil),
+					ValidateDiagFunc: validation.ToDiagFunc(validation.IsURLWithScheme([]string{"http", "https"})),
+				},
+				"state_http_username": {
+					Type:        schema.TypeString,
+					Optional:    true,
+					Description: "basic auth username for the http state backend",
+					DefaultFunc: schema.EnvDefaultFunc("STATE_HTTP_USERNAME", nil),
+				},
+				"state_http_password": {
+					Type:        schema.TypeString,
+					Optional:    true,
+					Sensitive:   true,
+					Description: "basic auth password for the http state backend",
+					DefaultFunc: schema.EnvDefaultFunc("STATE_HTTP_PASSWORD", nil),
+				},
 			},
 			DataSourcesMap: map[string]*schema.Resource{
 				"grid_gateway_domain": dataSourceGatewayDomain(),
+				"grid_nodes":          dataSourceNodes(),
+				"grid_farms":          dataSourceFarms(),
+				"grid_node":           dataSourceNode(),
+				"grid_contracts":      dataSourceContracts(),
+				"grid_cost_estimate":  dataSourceCostEstimate(),
 			},
 			ResourcesMap: map[string]*schema.Resource{
-				"grid_scheduler":  resourceScheduler(),
-				"grid_deployment": resourceDeployment(),
-				"grid_network":    resourceNetwork(),
-				"grid_kubernetes": resourceKubernetes(),
-				"grid_name_proxy": resourceGatewayNameProxy(),
-				"grid_fqdn_proxy": resourceGatewayFQDNProxy(),
+				"grid_scheduler":     resourceScheduler(),
+				"grid_deployment":    resourceDeployment(),
+				"grid_network":       resourceNetwork(),
+				"grid_kubernetes":    resourceKubernetes(),
+				"grid_name_proxy":    resourceGatewayNameProxy(),
+				"grid_fqdn_proxy":    resourceGatewayFQDNProxy(),
+				"grid_vm":            resourceVM(),
+				"grid_zdb":           resourceZDB(),
+				"grid_qsfs":          resourceQSFS(),
+				"grid_rent_contract": resourceRentContract(),
+				"grid_name_contract": resourceNameContract(),
+				"grid_contract_gc":   resourceContractGC(),
 			},
 		}
-		configFunc, sub := providerConfigure(st)
-		substrateConnection = sub
-		p.ConfigureContextFunc = configFunc
+		for _, r := range p.ResourcesMap {
+			bindRetryContext(r)
+		}
+		for _, r := range p.DataSourcesMap {
+			bindRetryContext(r)
+		}
+
+		p.ConfigureContextFunc = providerConfigure(st, clients)
 
 		return p
-	}, substrateConnection
+	}, clients.close
 }
 
-func providerConfigure(st state.Getter) (func(ctx context.Context, d *schema.ResourceData) (interface{}, diag.Diagnostics), subi.SubstrateExt) {
-	var substrateConn subi.SubstrateExt
+func providerConfigure(st *state.Manager, clients *pluginClients) func(ctx context.Context, d *schema.ResourceData) (interface{}, diag.Diagnostics) {
 	return func(ctx context.Context, d *schema.ResourceData) (interface{}, diag.Diagnostics) {
 		mnemonic := d.Get("mnemonic").(string)
 		keyType := d.Get("key_type").(string)
 		network := d.Get("network").(string)
-		substrateURL := d.Get("substrate_url").(string)
-		relayURL := d.Get("relay_url").(string)
-		proxyURL := d.Get("proxy_url").(string)
+		urls := gridURLs{
+			substrate: splitURLs(d.Get("substrate_url").(string)),
+			relay:     splitURLs(d.Get("relay_url").(string)),
+			proxy:     splitURLs(d.Get("proxy_url").(string)),
+			graphql:   splitURLs(d.Get("graphql_url").(string)),
+		}
 		timeout := d.Get("rmb_timeout").(int)
-		debug := false
+		retry := retryPolicy{
+			maxRetries:     d.Get("max_retries").(int),
+			initialBackoff: time.Duration(d.Get("initial_backoff").(int)) * time.Second,
+			maxBackoff:     time.Duration(d.Get("max_backoff").(int)) * time.Second,
+			classes:        []string{retryTimeout, retryConnection},
+		}
+		if classes := d.Get("retryable_errors").([]interface{}); len(classes) != 0 {
+			retry.classes = make([]string, 0, len(classes))
+			for _, class := range classes {
+				retry.classes = append(retry.classes, class.(string))
+			}
+		}
+		debug := d.Get("debug").(bool)
 
-		opts := []deployer.PluginOpt{
-			deployer.WithNetwork(network),
-			deployer.WithTwinCache(),
+		stateCfg := state.Config{
+			Backend:   d.Get("state_backend").(string),
+			Path:      d.Get("state_path").(string),
+			URL:       d.Get("state_http_url").(string),
+			Username:  d.Get("state_http_username").(string),
+			Password:  d.Get("state_http_password").(string),
+			LockURL:   d.Get("state_http_lock_url").(string),
+			UnlockURL: d.Get("state_http_unlock_url").(string),
 		}
 
-		if timeout > 0 {
-			opts = append(opts, deployer.WithRMBTimeout(timeout))
+		if err := urls.validate(network); err != nil {
+			return nil, diag.FromErr(err)
 		}
 
-		if len(strings.TrimSpace(keyType)) != 0 {
-			opts = append(opts, deployer.WithKeyType(keyType))
+		tflog.Debug(ctx, "creating threefold plugin client", map[string]interface{}{
+			"network":       network,
+			"substrate_url": urls.substrate,
+			"relay_url":     urls.relay,
+			"proxy_url":     urls.proxy,
+			"graphql_url":   urls.graphql,
+			"debug":         debug,
+		})
+
+		tfPluginClient, err := newTFPluginClient(mnemonic, keyType, network, timeout, urls, debug)
+		if err != nil {
+			return nil, diag.FromErr(errors.Wrap(err, "error creating threefold plugin client"))
 		}
+		clients.add(tfPluginClient)
 
-		if len(strings.TrimSpace(substrateURL)) > 0 {
-			opts = append(opts, deployer.WithSubstrateURL(substrateURL))
+		if err := applyRetryPolicy(tfPluginClient, retry); err != nil {
+			return nil, diag.FromErr(err)
 		}
 
-		if len(strings.TrimSpace(proxyURL)) > 0 {
-			opts = append(opts, deployer.WithProxyURL(proxyURL))
+		backend, err := st.Open(stateCfg)
+		if err != nil {
+			return nil, diag.FromErr(errors.Wrapf(err, "couldn't load state from %s backend", stateCfg.Backend))
 		}
 
-		if len(strings.TrimSpace(relayURL)) > 0 {
-			opts = append(opts, deployer.WithRelayURL(relayURL))
+		// set state
+		tfPluginClient.State.Networks = *backend.GetState()
+
+		var diags diag.Diagnostics
+		err = traceCall(ctx, "rebuildNetworksSubnets", func() error {
+			return rebuildNetworksSubnets(ctx, tfPluginClient)
+		})
+		if err != nil {
+			// the missing subnets are loaded again from the grid when a resource uses their network
+			diags = append(diags, diag.Diagnostic{
+				Severity: diag.Warning,
+				Summary:  "failed to rebuild some networks subnets from the grid",
+				Detail:   err.Error(),
+			})
 		}
 
-		if debug {
-			opts = append(opts, deployer.WithLogs())
+		return tfPluginClient, diags
+	}
+}
+
+// newTFPluginClient creates a threefold plugin client for the given network.
+// The grid client only knows the public networks, so a custom network client is created
+// as a dev network client whose services urls are all overridden by the given urls.
+func newTFPluginClient(mnemonic, keyType, network string, rmbTimeout int, urls gridURLs, debug bool) (*deployer.TFPluginClient, error) {
+	clientNetwork := network
+	if network == customNetwork {
+		clientNetwork = deployer.DevNetwork
+	}
+
+	opts := []deployer.PluginOpt{
+		deployer.WithNetwork(clientNetwork),
+		deployer.WithTwinCache(),
+	}
+
+	if rmbTimeout > 0 {
+		opts = append(opts, deployer.WithRMBTimeout(rmbTimeout))
+	}
+
+	if len(strings.TrimSpace(keyType)) != 0 {
+		opts = append(opts, deployer.WithKeyType(keyType))
+	}
+
+	if len(urls.substrate) > 0 {
+		opts = append(opts, deployer.WithSubstrateURL(urls.substrate...))
+	}
+
+	if len(urls.proxy) > 0 {
+		opts = append(opts, deployer.WithProxyURL(urls.proxy...))
+	}
+
+	if len(urls.relay) > 0 {
+		opts = append(opts, deployer.WithRelayURL(urls.relay...))
+	}
+
+	if len(urls.graphql) > 0 {
+		opts = append(opts, deployer.WithGraphQlURL(urls.graphql...))
+	}
+
+	if debug {
+		opts = append(opts, deployer.WithLogs())
+	}
+
+	tfPluginClient, err := deployer.NewTFPluginClient(mnemonic, opts...)
+	if err != nil {
+		return nil, err
+	}
+	tfPluginClient.Network = network
+
+	return &tfPluginClient, nil
+}
+
+// validateURLs validates a comma separated list of urls with the given schemes
+func validateURLs(schemes ...string) schema.SchemaValidateFunc {
+	return func(i interface{}, k string) (warnings []string, errs []error) {
+		v, ok := i.(string)
+		if !ok {
+			return nil, []error{fmt.Errorf("expected type of %q to be string", k)}
 		}
 
-		tfPluginClient, err := deployer.NewTFPluginClient(mnemonic, opts...)
-		if err != nil {
-			return nil, diag.FromErr(errors.Wrap(err, "error creating threefold plugin client"))
+		urls := splitURLs(v)
+		if len(urls) == 0 {
+			return nil, []error{fmt.Errorf("expected %q to contain at least one url", k)}
 		}
 
-		// set state
-		tfPluginClient.State.Networks = *st.GetState()
+		for _, u := range urls {
+			w, e := validation.IsURLWithScheme(schemes)(u, k)
+			warnings = append(warnings, w...)
+			errs = append(errs, e...)
+		}
 
-		return &tfPluginClient, nil
-	}, substrateConn
+		return warnings, errs
+	}
 }
@@ -2,18 +2,66 @@
 package provider
 
 import (
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/threefoldtech/terraform-provider-grid/internal/state"
 )
 
 func TestProvider(t *testing.T) {
-	stateDB := state.NewLocalFileState()
-	f, sub := New("dev", &stateDB)
-	if sub != nil {
-		defer sub.Close()
-	}
+	f, closeClients := New("dev", state.NewManager())
+	defer closeClients()
 	if err := f().InternalValidate(); err != nil {
 		t.Fatalf("err: %s", err)
 	}
 }
+
+// documentedNames returns the names of the resources or data sources with a generated page in the given docs directory
+func documentedNames(t *testing.T, dir string) []string {
+	t.Helper()
+
+	files, err := filepath.Glob(filepath.Join("..", "..", "docs", dir, "*.md"))
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	names := make([]string, 0, len(files))
+	for _, file := range files {
+		content, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatalf("err: %s", err)
+		}
+
+		// skip the guides that are not generated from a schema
+		name := "grid_" + strings.TrimSuffix(filepath.Base(file), ".md")
+		if strings.Contains(string(content), "page_title: \""+name+" ") {
+			names = append(names, name)
+		}
+	}
+
+	return names
+}
+
+func TestProviderRegistersDocumented(t *testing.T) {
+	if _, err := os.Stat(filepath.Join("..", "..", "docs")); err != nil {
+		t.Skip("docs directory is not available")
+	}
+
+	f, closeClients := New("dev", state.NewManager())
+	defer closeClients()
+	p := f()
+
+	for _, name := range documentedNames(t, "data-sources") {
+		if _, ok := p.DataSourcesMap[name]; !ok {
+			t.Errorf("data source %s is documented but not registered", name)
+		}
+	}
+
+	for _, name := range documentedNames(t, "resources") {
+		if _, ok := p.ResourcesMap[name]; !ok {
+			t.Errorf("resource %s is documented but not registered", name)
+		}
+	}
+}
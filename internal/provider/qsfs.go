@@ -0,0 +1,480 @@
+// Package provider is the terraform provider
+package provider
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"slices"
+	"strconv"
+
+	"github.com/hashicorp/go-multierror"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/pkg/errors"
+	"github.com/threefoldtech/tfgrid-sdk-go/grid-client/deployer"
+	"github.com/threefoldtech/tfgrid-sdk-go/grid-client/workloads"
+)
+
+const qsfsMetadataType = "zdb"
+
+// qsfsZDBDeploymentName returns the name of the deployments holding the zdb backends of a qsfs
+func qsfsZDBDeploymentName(name string) string {
+	return fmt.Sprintf("%s_zdbs", name)
+}
+
+// qsfsMetadataZDBName returns the name of the metadata zdb of a qsfs on the given node
+func qsfsMetadataZDBName(nodeID uint32) string {
+	return fmt.Sprintf("meta_%d", nodeID)
+}
+
+// qsfsGroupZDBName returns the name of the data zdb of the given group on the given node.
+// The zdbs are named after their group and node rather than their positions, so adding, removing or replacing a group,
+// or replacing a node of a group, keeps the names, and so the data, of the other zdbs.
+func qsfsGroupZDBName(group string, nodeID uint32) string {
+	return fmt.Sprintf("group_%s_%d", group, nodeID)
+}
+
+// qsfsGroup is a backend group of the qsfs, with a seq mode zdb on each of its nodes
+type qsfsGroup struct {
+	name  string
+	nodes []uint32
+}
+
+// qsfsMetadataNodes returns the node ids configured for the metadata zdbs of the qsfs
+func qsfsMetadataNodes(metadataNodes []interface{}) []uint32 {
+	nodes := make([]uint32, 0, len(metadataNodes))
+	for _, node := range metadataNodes {
+		nodes = append(nodes, uint32(node.(int)))
+	}
+
+	return nodes
+}
+
+// qsfsGroups returns the groups configured for the qsfs
+func qsfsGroups(groups []interface{}) []qsfsGroup {
+	res := make([]qsfsGroup, 0, len(groups))
+	for _, group := range groups {
+		groupMap := group.(map[string]interface{})
+		res = append(res, qsfsGroup{
+			name:  groupMap["name"].(string),
+			nodes: qsfsMetadataNodes(groupMap["nodes"].([]interface{})),
+		})
+	}
+
+	return res
+}
+
+// validateQSFSBackends makes sure each zdb of the qsfs has a distinct name, the metadata nodes and the nodes of each group
+// must be distinct, and so must the groups names
+func validateQSFSBackends(metadataNodes []uint32, groups []qsfsGroup) error {
+	for i, node := range metadataNodes {
+		if slices.Contains(metadataNodes[:i], node) {
+			return fmt.Errorf("metadata node %d is duplicated", node)
+		}
+	}
+
+	names := make(map[string]bool)
+	for _, group := range groups {
+		if names[group.name] {
+			return fmt.Errorf("group name '%s' is duplicated", group.name)
+		}
+		names[group.name] = true
+
+		for i, node := range group.nodes {
+			if slices.Contains(group.nodes[:i], node) {
+				return fmt.Errorf("node %d is duplicated in group '%s'", node, group.name)
+			}
+		}
+	}
+
+	return nil
+}
+
+// qsfsBackendNodes returns the distinct node ids hosting the zdb backends of the qsfs
+func qsfsBackendNodes(metadataNodes []uint32, groups []qsfsGroup) []uint32 {
+	nodes := make([]uint32, 0)
+	for _, node := range metadataNodes {
+		if !slices.Contains(nodes, node) {
+			nodes = append(nodes, node)
+		}
+	}
+
+	for _, group := range groups {
+		for _, node := range group.nodes {
+			if !slices.Contains(nodes, node) {
+				nodes = append(nodes, node)
+			}
+		}
+	}
+
+	return nodes
+}
+
+// qsfsHasVM checks if the qsfs is mounted into a vm deployed by the resource
+func qsfsHasVM(d interface{ Get(string) interface{} }) bool {
+	return len(d.Get("vm").([]interface{})) != 0
+}
+
+// parseNodeContracts parses a mapping from node id to contract id stored in the terraform state
+func parseNodeContracts(nodeContractsIf map[string]interface{}) (map[uint32]uint64, error) {
+	nodeContracts := make(map[uint32]uint64)
+	for node, id := range nodeContractsIf {
+		nodeID, err := strconv.ParseUint(node, 10, 32)
+		if err != nil {
+			return nil, errors.Wrapf(err, "couldn't parse node id '%s'", node)
+		}
+		nodeContracts[uint32(nodeID)] = uint64(id.(int))
+	}
+
+	return nodeContracts, nil
+}
+
+// newQSFSZDBDeploymentsFromSchema builds a deployment for each node hosting zdb backends of the qsfs, holding
+// the user mode metadata zdbs and seq mode data zdbs configured on this node. It also returns the contracts of the zdbs
+// deployments on nodes that are no longer configured to host backends.
+func newQSFSZDBDeploymentsFromSchema(d *schema.ResourceData) (map[uint32]*workloads.Deployment, map[uint32]uint64, error) {
+	name := d.Get("name").(string)
+	password := d.Get("zdb_password").(string)
+
+	zdbContracts, err := parseNodeContracts(d.Get("zdb_deployment_ids").(map[string]interface{}))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	solutionType := d.Get("solution_type").(string)
+	if solutionType == "" {
+		solutionType = fmt.Sprintf("qsfs/%s", name)
+	}
+
+	dls := make(map[uint32]*workloads.Deployment)
+	nodeDeployment := func(nodeID uint32) *workloads.Deployment {
+		if dl, ok := dls[nodeID]; ok {
+			return dl
+		}
+
+		dl := &workloads.Deployment{
+			Name:             qsfsZDBDeploymentName(name),
+			NodeID:           nodeID,
+			SolutionType:     solutionType,
+			NodeDeploymentID: map[uint32]uint64{},
+		}
+		if contractID, ok := zdbContracts[nodeID]; ok && contractID != 0 {
+			dl.ContractID = contractID
+			dl.NodeDeploymentID[nodeID] = contractID
+		}
+		dls[nodeID] = dl
+		return dl
+	}
+
+	metadataNodes := qsfsMetadataNodes(d.Get("metadata_nodes").([]interface{}))
+	groups := qsfsGroups(d.Get("groups").([]interface{}))
+	if err := validateQSFSBackends(metadataNodes, groups); err != nil {
+		return nil, nil, err
+	}
+
+	for _, node := range metadataNodes {
+		dl := nodeDeployment(node)
+		dl.Zdbs = append(dl.Zdbs, workloads.ZDB{
+			Name:     qsfsMetadataZDBName(node),
+			Password: password,
+			SizeGB:   uint64(d.Get("metadata_zdb_size").(int)),
+			Mode:     workloads.ZDBModeUser,
+		})
+	}
+
+	for _, group := range groups {
+		for _, node := range group.nodes {
+			dl := nodeDeployment(node)
+			dl.Zdbs = append(dl.Zdbs, workloads.ZDB{
+				Name:     qsfsGroupZDBName(group.name, node),
+				Password: password,
+				SizeGB:   uint64(d.Get("zdb_size").(int)),
+				Mode:     workloads.ZDBModeSeq,
+			})
+		}
+	}
+
+	stale := make(map[uint32]uint64)
+	for nodeID, contractID := range zdbContracts {
+		if _, ok := dls[nodeID]; !ok {
+			stale[nodeID] = contractID
+		}
+	}
+
+	return dls, stale, nil
+}
+
+// qsfsZDBContracts returns the contracts of the zdbs deployments of a qsfs, including the stale ones that are not canceled yet
+func qsfsZDBContracts(zdbDls map[uint32]*workloads.Deployment, stale map[uint32]uint64) map[uint32]uint64 {
+	contracts := make(map[uint32]uint64)
+	for nodeID, contractID := range stale {
+		contracts[nodeID] = contractID
+	}
+
+	for nodeID, dl := range zdbDls {
+		if dl.ContractID != 0 {
+			contracts[nodeID] = dl.ContractID
+		}
+	}
+
+	return contracts
+}
+
+// zdbBackendAddress returns the address the qsfs uses to reach a zdb, preferring the zdb's yggdrasil ip
+func zdbBackendAddress(zdb workloads.ZDB) (string, error) {
+	if len(zdb.IPs) == 0 {
+		return "", fmt.Errorf("zdb '%s' has no ips", zdb.Name)
+	}
+
+	ip := zdb.IPs[0]
+	for _, zdbIP := range zdb.IPs {
+		// yggdrasil ips are in the 200::/7 range
+		if parsed := net.ParseIP(zdbIP); parsed != nil && parsed.To4() == nil && parsed[0]&0xfe == 0x02 {
+			ip = zdbIP
+			break
+		}
+	}
+
+	return fmt.Sprintf("[%s]:%d", ip, zdb.Port), nil
+}
+
+// qsfsBackends returns the backends of the deployed zdbs of the qsfs metadata, and of each of its groups
+func qsfsBackends(d *schema.ResourceData, zdbDls map[uint32]*workloads.Deployment) (workloads.Backends, workloads.Groups, error) {
+	password := d.Get("zdb_password").(string)
+
+	backend := func(nodeID uint32, zdbName string) (workloads.Backend, error) {
+		dl, ok := zdbDls[nodeID]
+		if !ok {
+			return workloads.Backend{}, fmt.Errorf("couldn't find zdbs deployment on node %d", nodeID)
+		}
+
+		for _, zdb := range dl.Zdbs {
+			if zdb.Name != zdbName {
+				continue
+			}
+
+			address, err := zdbBackendAddress(zdb)
+			if err != nil {
+				return workloads.Backend{}, errors.Wrapf(err, "backend node %d", nodeID)
+			}
+
+			return workloads.Backend{Address: address, Namespace: zdb.Namespace, Password: password}, nil
+		}
+
+		return workloads.Backend{}, fmt.Errorf("couldn't find zdb '%s' on node %d, the node might be down and should be replaced", zdbName, nodeID)
+	}
+
+	metadataBackends := make(workloads.Backends, 0)
+	for _, node := range qsfsMetadataNodes(d.Get("metadata_nodes").([]interface{})) {
+		b, err := backend(node, qsfsMetadataZDBName(node))
+		if err != nil {
+			return nil, nil, err
+		}
+		metadataBackends = append(metadataBackends, b)
+	}
+
+	groups := make(workloads.Groups, 0)
+	for _, qsfsGroup := range qsfsGroups(d.Get("groups").([]interface{})) {
+		group := workloads.Group{Backends: make(workloads.Backends, 0)}
+		for _, node := range qsfsGroup.nodes {
+			b, err := backend(node, qsfsGroupZDBName(qsfsGroup.name, node))
+			if err != nil {
+				return nil, nil, err
+			}
+			group.Backends = append(group.Backends, b)
+		}
+		groups = append(groups, group)
+	}
+
+	return metadataBackends, groups, nil
+}
+
+// newQSFSFromZDBDeployments builds the qsfs workload configuration, wiring the deployed zdbs into its metadata and groups
+func newQSFSFromZDBDeployments(d *schema.ResourceData, zdbDls map[uint32]*workloads.Deployment) (workloads.QSFS, error) {
+	name := d.Get("name").(string)
+
+	metadataBackends, groups, err := qsfsBackends(d, zdbDls)
+	if err != nil {
+		return workloads.QSFS{}, err
+	}
+
+	metadataPrefix := d.Get("metadata_prefix").(string)
+	if metadataPrefix == "" {
+		metadataPrefix = name
+	}
+
+	encryptionKey := d.Get("encryption_key").(string)
+	encryptionAlgorithm := d.Get("encryption_algorithm").(string)
+
+	return workloads.QSFS{
+		Name:                 name,
+		Description:          d.Get("description").(string),
+		Cache:                d.Get("cache").(int),
+		MinimalShards:        uint32(d.Get("minimal_shards").(int)),
+		ExpectedShards:       uint32(d.Get("expected_shards").(int)),
+		RedundantGroups:      uint32(d.Get("redundant_groups").(int)),
+		RedundantNodes:       uint32(d.Get("redundant_nodes").(int)),
+		MaxZDBDataDirSize:    uint32(d.Get("max_zdb_data_dir_size").(int)),
+		EncryptionAlgorithm:  encryptionAlgorithm,
+		EncryptionKey:        encryptionKey,
+		CompressionAlgorithm: d.Get("compression_algorithm").(string),
+		Metadata: workloads.Metadata{
+			Type:                qsfsMetadataType,
+			Prefix:              metadataPrefix,
+			EncryptionAlgorithm: encryptionAlgorithm,
+			EncryptionKey:       encryptionKey,
+			Backends:            metadataBackends,
+		},
+		Groups: groups,
+	}, nil
+}
+
+// newQSFSDeploymentFromSchema builds the deployment holding the qsfs workload and the vm mounting it.
+// The qsfs workload is only included if the zdb backends deployments are given.
+func newQSFSDeploymentFromSchema(ctx context.Context, d *schema.ResourceData, tfPluginClient *deployer.TFPluginClient, zdbDls map[uint32]*workloads.Deployment) (*workloads.Deployment, error) {
+	name := d.Get("name").(string)
+	nodeID := uint32(d.Get("node").(int))
+	networkName := d.Get("network_name").(string)
+
+	light, err := isZosLight(ctx, nodeID, tfPluginClient.NcPool, tfPluginClient.SubstrateConn)
+	if err != nil {
+		return nil, err
+	}
+
+	vmMap := d.Get("vm").([]interface{})[0].(map[string]interface{})
+	vmMap["mounts"] = []interface{}{map[string]interface{}{"name": name, "mount_point": vmMap["mount_point"]}}
+	delete(vmMap, "mount_point")
+
+	vms, vmsLight, err := newVMsFromMaps([]interface{}{vmMap}, networkName, nodeID, light)
+	if err != nil {
+		return nil, err
+	}
+
+	solutionType := d.Get("solution_type").(string)
+	if solutionType == "" {
+		solutionType = fmt.Sprintf("qsfs/%s", name)
+	}
+
+	dl := workloads.Deployment{
+		Name:             name,
+		NodeID:           nodeID,
+		SolutionType:     solutionType,
+		Vms:              vms,
+		VmsLight:         vmsLight,
+		NetworkName:      networkName,
+		IPrange:          d.Get("ip_range").(string),
+		NodeDeploymentID: map[uint32]uint64{},
+	}
+
+	if contractID := uint64(d.Get("deployment_id").(int)); contractID != 0 {
+		dl.ContractID = contractID
+		dl.NodeDeploymentID[nodeID] = contractID
+	}
+
+	if zdbDls != nil {
+		qsfs, err := newQSFSFromZDBDeployments(d, zdbDls)
+		if err != nil {
+			return nil, err
+		}
+		dl.QSFS = []workloads.QSFS{qsfs}
+	}
+
+	return &dl, nil
+}
+
+// backendsToList converts zdb backends into a list of maps to be stored in the terraform state
+func backendsToList(backends workloads.Backends) []interface{} {
+	res := make([]interface{}, 0, len(backends))
+	for _, b := range backends {
+		res = append(res, map[string]interface{}{
+			"address":   b.Address,
+			"namespace": b.Namespace,
+			"password":  b.Password,
+		})
+	}
+
+	return res
+}
+
+// syncQSFSBackends updates the terraform local state with the backends of the deployed zdbs, so they could be
+// used by a qsfs mounted into a vm of another deployment
+func syncQSFSBackends(r *schema.ResourceData, zdbDls map[uint32]*workloads.Deployment) error {
+	metadataBackends, groups, err := qsfsBackends(r, zdbDls)
+	if err != nil {
+		return err
+	}
+
+	groupBackends := make([]interface{}, 0, len(groups))
+	for i, qsfsGroup := range qsfsGroups(r.Get("groups").([]interface{})) {
+		groupBackends = append(groupBackends, map[string]interface{}{
+			"name":     qsfsGroup.name,
+			"backends": backendsToList(groups[i].Backends),
+		})
+	}
+
+	if err := r.Set("metadata_backends", backendsToList(metadataBackends)); err != nil {
+		return fmt.Errorf("failed to set metadata backends with error: %w", err)
+	}
+
+	if err := r.Set("group_backends", groupBackends); err != nil {
+		return fmt.Errorf("failed to set group backends with error: %w", err)
+	}
+
+	return nil
+}
+
+// syncContractsQSFS updates the terraform local state with the latest changes to the qsfs, its vm and its zdb backends contracts
+func syncContractsQSFS(r *schema.ResourceData, dl *workloads.Deployment, zdbContracts map[uint32]uint64) (errors error) {
+	zdbDeploymentIDs := make(map[string]interface{})
+	for nodeID, contractID := range zdbContracts {
+		zdbDeploymentIDs[fmt.Sprint(nodeID)] = int(contractID)
+	}
+
+	err := r.Set("zdb_deployment_ids", zdbDeploymentIDs)
+	if err != nil {
+		errors = multierror.Append(errors, fmt.Errorf("failed to set zdb deployment ids with error: %w", err))
+	}
+
+	if dl == nil {
+		return
+	}
+
+	err = r.Set("deployment_id", int(dl.ContractID))
+	if err != nil {
+		errors = multierror.Append(errors, fmt.Errorf("failed to set deployment id with error: %w", err))
+	}
+
+	err = r.Set("ip_range", dl.IPrange)
+	if err != nil {
+		errors = multierror.Append(errors, fmt.Errorf("failed to set ip range with error: %w", err))
+	}
+
+	if len(dl.QSFS) != 0 {
+		err = r.Set("metrics_endpoint", dl.QSFS[0].MetricsEndpoint)
+		if err != nil {
+			errors = multierror.Append(errors, fmt.Errorf("failed to set metrics endpoint with error: %w", err))
+		}
+	}
+
+	vms, err := vmsToMaps(dl)
+	if err != nil {
+		return multierror.Append(errors, err)
+	}
+
+	if len(vms) != 0 {
+		vmMap := vms[0].(map[string]interface{})
+		vm := r.Get("vm").([]interface{})[0].(map[string]interface{})
+		for attr := range vm {
+			if value, ok := vmMap[attr]; ok {
+				vm[attr] = value
+			}
+		}
+
+		err = r.Set("vm", []interface{}{vm})
+		if err != nil {
+			errors = multierror.Append(errors, fmt.Errorf("failed to set vm with error: %w", err))
+		}
+	}
+
+	return
+}
@@ -0,0 +1,100 @@
+// Package provider is the terraform provider
+package provider
+
+import (
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func qsfsResourceData(t *testing.T, groups []interface{}, zdbDeploymentIDs map[string]interface{}) *schema.ResourceData {
+	t.Helper()
+
+	return schema.TestResourceDataRaw(t, resourceQSFS().Schema, map[string]interface{}{
+		"name":               "qsfs",
+		"zdb_password":       "pass",
+		"metadata_nodes":     []interface{}{11, 12},
+		"groups":             groups,
+		"zdb_deployment_ids": zdbDeploymentIDs,
+	})
+}
+
+// qsfsZDBNames returns the names of the zdbs to be deployed on each node
+func qsfsZDBNames(t *testing.T, d *schema.ResourceData) (map[uint32][]string, map[uint32]uint64) {
+	t.Helper()
+
+	dls, stale, err := newQSFSZDBDeploymentsFromSchema(d)
+	require.NoError(t, err)
+
+	names := make(map[uint32][]string)
+	for nodeID, dl := range dls {
+		for _, zdb := range dl.Zdbs {
+			names[nodeID] = append(names[nodeID], zdb.Name)
+		}
+	}
+
+	return names, stale
+}
+
+func qsfsGroupRaw(name string, nodes ...int) map[string]interface{} {
+	nodesIf := make([]interface{}, 0, len(nodes))
+	for _, node := range nodes {
+		nodesIf = append(nodesIf, node)
+	}
+	return map[string]interface{}{"name": name, "nodes": nodesIf}
+}
+
+func TestQSFSZDBsAddGroup(t *testing.T) {
+	before, _ := qsfsZDBNames(t, qsfsResourceData(t, []interface{}{qsfsGroupRaw("a", 11, 13)}, nil))
+	after, _ := qsfsZDBNames(t, qsfsResourceData(t, []interface{}{qsfsGroupRaw("b", 13, 14), qsfsGroupRaw("a", 11, 13)}, nil))
+
+	assert.Equal(t, map[uint32][]string{
+		11: {"meta_11", "group_a_11"},
+		12: {"meta_12"},
+		13: {"group_a_13"},
+	}, before)
+	assert.Equal(t, map[uint32][]string{
+		11: {"meta_11", "group_a_11"},
+		12: {"meta_12"},
+		13: {"group_b_13", "group_a_13"},
+		14: {"group_b_14"},
+	}, after, "adding a group before another one shouldn't rename the zdbs of the existing groups")
+}
+
+func TestQSFSZDBsRemoveGroup(t *testing.T) {
+	contracts := map[string]interface{}{"11": 1, "12": 2, "13": 3, "14": 4}
+	names, stale := qsfsZDBNames(t, qsfsResourceData(t, []interface{}{qsfsGroupRaw("b", 13, 14)}, contracts))
+
+	assert.Equal(t, map[uint32][]string{
+		11: {"meta_11"},
+		12: {"meta_12"},
+		13: {"group_b_13"},
+		14: {"group_b_14"},
+	}, names, "removing the first group shouldn't rename the zdbs of the other groups")
+	assert.Empty(t, stale)
+
+	names, stale = qsfsZDBNames(t, qsfsResourceData(t, []interface{}{qsfsGroupRaw("a", 11, 13)}, contracts))
+	assert.Equal(t, []string{"group_a_13"}, names[13])
+	assert.Equal(t, map[uint32]uint64{14: 4}, stale, "the zdbs deployment of a node that no longer hosts backends should be canceled")
+}
+
+func TestQSFSZDBsReplaceNode(t *testing.T) {
+	contracts := map[string]interface{}{"11": 1, "12": 2, "13": 3}
+	names, stale := qsfsZDBNames(t, qsfsResourceData(t, []interface{}{qsfsGroupRaw("a", 11, 15)}, contracts))
+
+	assert.Equal(t, map[uint32][]string{
+		11: {"meta_11", "group_a_11"},
+		12: {"meta_12"},
+		15: {"group_a_15"},
+	}, names, "replacing a node should only change the zdb on this node")
+	assert.Equal(t, map[uint32]uint64{13: 3}, stale)
+}
+
+func TestValidateQSFSBackends(t *testing.T) {
+	assert.NoError(t, validateQSFSBackends([]uint32{1, 2}, []qsfsGroup{{name: "a", nodes: []uint32{1, 2}}, {name: "b", nodes: []uint32{1}}}))
+	assert.ErrorContains(t, validateQSFSBackends([]uint32{1, 1}, nil), "metadata node 1 is duplicated")
+	assert.ErrorContains(t, validateQSFSBackends(nil, []qsfsGroup{{name: "a"}, {name: "a"}}), "group name 'a' is duplicated")
+	assert.ErrorContains(t, validateQSFSBackends(nil, []qsfsGroup{{name: "a", nodes: []uint32{3, 3}}}), "node 3 is duplicated in group 'a'")
+}
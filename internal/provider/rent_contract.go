@@ -0,0 +1,86 @@
+// Package provider is the terraform provider
+package provider
+
+import (
+	"context"
+
+	"github.com/centrifuge/go-substrate-rpc-client/v4/types"
+	"github.com/pkg/errors"
+	"github.com/threefoldtech/tfgrid-sdk-go/grid-client/deployer"
+	"github.com/threefoldtech/tfgrid-sdk-go/grid-client/subi"
+	"github.com/threefoldtech/tfgrid-sdk-go/grid-client/zos"
+)
+
+// defaultPricingPolicyID is the pricing policy used by the grid calculator
+const defaultPricingPolicyID = 1
+
+// certifiedNode is the certification type of certified nodes
+const certifiedNode = "Certified"
+
+// nodeRentBillingRate returns the monthly cost in TFT of renting the whole node, with the dedicated nodes discount and the twin balance discount package applied
+func nodeRentBillingRate(ctx context.Context, tfPluginClient *deployer.TFPluginClient, nodeID uint32) (float64, error) {
+	node, err := tfPluginClient.GridProxyClient.Node(ctx, nodeID)
+	if err != nil {
+		return 0, errors.Wrapf(err, "couldn't get node %d from the grid proxy", nodeID)
+	}
+
+	pricingPolicyID, err := farmPricingPolicyID(ctx, tfPluginClient, uint32(node.FarmID))
+	if err != nil {
+		return 0, err
+	}
+
+	total := node.Capacity.Total
+	cost, err := estimateCost(tfPluginClient.SubstrateConn, zos.Capacity{
+		CRU: total.CRU,
+		MRU: uint64(total.MRU),
+		SRU: uint64(total.SRU),
+		HRU: uint64(total.HRU),
+	}, costOptions{
+		pricingPolicyID: pricingPolicyID,
+		certified:       node.CertificationType == certifiedNode,
+		dedicated:       true,
+		identity:        tfPluginClient.Identity,
+	})
+	if err != nil {
+		return 0, errors.Wrap(err, "couldn't calculate node cost")
+	}
+
+	return cost.monthlyTFT, nil
+}
+
+// nodeActiveContracts returns the active node contracts on the given node, split into the contracts owned by the
+// given twin, and the contracts of other twins
+func nodeActiveContracts(tfPluginClient *deployer.TFPluginClient, nodeID uint32, twinID uint32) (own []uint64, others []uint64, err error) {
+	sub, retrying, err := substrateImpl(tfPluginClient.SubstrateConn)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var ids []types.U64
+	err = retrying.do(func() (err error) {
+		ids, err = sub.GetNodeContracts(nodeID)
+		return err
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	for _, id := range ids {
+		var contract subi.Contract
+		err = retrying.do(func() (err error) {
+			contract, err = tfPluginClient.SubstrateConn.GetContract(uint64(id))
+			return err
+		})
+		if err != nil {
+			return nil, nil, errors.Wrapf(err, "couldn't get contract %d", id)
+		}
+
+		if contract.TwinID() == twinID {
+			own = append(own, uint64(id))
+		} else {
+			others = append(others, uint64(id))
+		}
+	}
+
+	return own, others, nil
+}
@@ -0,0 +1,291 @@
+// Package provider is the terraform provider
+package provider
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
+	"github.com/pkg/errors"
+	"github.com/threefoldtech/tfgrid-sdk-go/grid-client/deployer"
+	"github.com/threefoldtech/tfgrid-sdk-go/grid-client/workloads"
+	proxyTypes "github.com/threefoldtech/tfgrid-sdk-go/grid-proxy/pkg/types"
+)
+
+// defaultContractGCGracePeriod is the default minimum age of the node contracts collected by the contracts collector
+const defaultContractGCGracePeriod = 24 * time.Hour
+
+// validateMinDuration validates a duration string that is at least the given minimum
+func validateMinDuration(min time.Duration) schema.SchemaValidateFunc {
+	return func(i interface{}, k string) ([]string, []error) {
+		v, ok := i.(string)
+		if !ok {
+			return nil, []error{fmt.Errorf("expected type of %s to be string", k)}
+		}
+
+		duration, err := time.ParseDuration(v)
+		if err != nil {
+			return nil, []error{fmt.Errorf("%s must be a duration (e.g. 30m, 24h): %w", k, err)}
+		}
+
+		if duration < min {
+			return nil, []error{fmt.Errorf("%s must be at least %s, got %s", k, min, duration)}
+		}
+
+		return nil, nil
+	}
+}
+
+func resourceContractGC() *schema.Resource {
+	return &schema.Resource{
+		// This description is used by the documentation generator and the language server.
+		Description:   "Resource for collecting the orphaned node contracts of the configured identity twin, e.g. contracts left on chain by failed creates. A node contract is orphaned if its solution type (project name) or solution name is `prefix` or starts with `prefix/`, it's not in `keep_contracts`, and it's older than `grace_period`. Contracts in `keep_contracts` are the ones referenced by the terraform state, since a provider couldn't read the state of other resources; the grace period protects the contracts being created by the same apply that aren't referenced yet. The orphaned contracts are only reported unless `dry_run` is false, and they are never canceled while `keep_contracts` is empty. Destroying the resource doesn't cancel any contract.",
+		CreateContext: resourceContractGCCreate,
+		ReadContext:   resourceContractGCRead,
+		UpdateContext: resourceContractGCUpdate,
+		DeleteContext: resourceContractGCDelete,
+
+		Timeouts: &schema.ResourceTimeout{
+			Create: schema.DefaultTimeout(10 * time.Minute),
+			Read:   schema.DefaultTimeout(10 * time.Minute),
+			Update: schema.DefaultTimeout(10 * time.Minute),
+			Delete: schema.DefaultTimeout(10 * time.Minute),
+		},
+
+		Schema: map[string]*schema.Schema{
+			"prefix": {
+				Type:             schema.TypeString,
+				Required:         true,
+				Description:      "Prefix of the solution type (project name) or solution name of the node contracts to collect. It's matched as a whole name or as the leading `/` separated segment of the name, e.g. `myproject` matches `myproject` and `myproject/vm1` but not `myproject2`.",
+				ValidateDiagFunc: validation.ToDiagFunc(validation.StringIsNotWhiteSpace),
+			},
+			"keep_contracts": {
+				Type:        schema.TypeList,
+				Optional:    true,
+				Elem:        &schema.Schema{Type: schema.TypeInt},
+				Description: "Ids of the node contracts referenced by the terraform state, e.g. the `node_deployment_id` values of the network resources or the ids of the deployment resources. These contracts are never collected. It's required to cancel the orphaned contracts.",
+			},
+			"grace_period": {
+				Type:             schema.TypeString,
+				Optional:         true,
+				Default:          defaultContractGCGracePeriod.String(),
+				Description:      "Minimum age of a node contract to be collected, as a duration (e.g. `30m`, `24h`). Younger contracts might belong to deployments still being created, and are never collected.",
+				ValidateDiagFunc: validation.ToDiagFunc(validateMinDuration(time.Minute)),
+			},
+			"dry_run": {
+				Type:        schema.TypeBool,
+				Optional:    true,
+				Default:     true,
+				Description: "Only report the orphaned contracts without canceling them. Disabling it requires `keep_contracts` to be set.",
+			},
+			"triggers": {
+				Type:        schema.TypeMap,
+				Optional:    true,
+				ForceNew:    true,
+				Elem:        &schema.Schema{Type: schema.TypeString},
+				Description: "Arbitrary map of values that, when changed, runs the collector again.",
+			},
+			"orphaned_contracts": {
+				Type:        schema.TypeList,
+				Computed:    true,
+				Elem:        &schema.Schema{Type: schema.TypeInt},
+				Description: "Ids of the orphaned node contracts that are still active.",
+			},
+			"canceled_contracts": {
+				Type:        schema.TypeList,
+				Computed:    true,
+				Elem:        &schema.Schema{Type: schema.TypeInt},
+				Description: "Ids of the orphaned node contracts canceled by the last run of the collector.",
+			},
+		},
+	}
+}
+
+// nameMatchesPrefix checks if the name is the prefix itself or starts with the prefix as its first `/` separated segment
+func nameMatchesPrefix(name, prefix string) bool {
+	return name == prefix || strings.HasPrefix(name, prefix+"/")
+}
+
+// contractMatchesPrefix checks if the node contract deployment metadata has a solution type or name matching the given prefix
+func contractMatchesPrefix(ctx context.Context, contract proxyTypes.Contract, prefix string) bool {
+	details, ok := contract.Details.(proxyTypes.NodeContractDetails)
+	if !ok {
+		return false
+	}
+
+	deploymentData, err := workloads.ParseDeploymentData(details.DeploymentData)
+	if err != nil {
+		tflog.Debug(ctx, "couldn't parse contract deployment data", map[string]interface{}{
+			"contract_id": contract.ContractID,
+			"error":       err.Error(),
+		})
+		return false
+	}
+
+	return nameMatchesPrefix(deploymentData.ProjectName, prefix) || nameMatchesPrefix(deploymentData.Name, prefix)
+}
+
+// filterOrphanedContracts returns the ids of the contracts matching the collector prefix, not kept, and created before the given time
+func filterOrphanedContracts(ctx context.Context, contracts []proxyTypes.Contract, prefix string, keep []uint64, createdBefore time.Time) []uint64 {
+	orphans := make([]uint64, 0)
+	for _, contract := range contracts {
+		if slices.Contains(keep, uint64(contract.ContractID)) || !contractMatchesPrefix(ctx, contract, prefix) {
+			continue
+		}
+
+		if time.Unix(int64(contract.CreatedAt), 0).After(createdBefore) {
+			tflog.Debug(ctx, "skipping contract in its grace period", map[string]interface{}{
+				"contract_id": contract.ContractID,
+				"created_at":  contract.CreatedAt,
+			})
+			continue
+		}
+
+		orphans = append(orphans, uint64(contract.ContractID))
+	}
+
+	return orphans
+}
+
+// orphanedContracts lists the active node contracts of the twin matching the collector prefix, not referenced by the state
+// and older than the grace period
+func orphanedContracts(ctx context.Context, d *schema.ResourceData, tfPluginClient *deployer.TFPluginClient) ([]uint64, error) {
+	prefix := d.Get("prefix").(string)
+	if strings.TrimSpace(prefix) == "" {
+		return nil, errors.New("prefix is required to collect orphaned contracts")
+	}
+
+	gracePeriod, err := time.ParseDuration(d.Get("grace_period").(string))
+	if err != nil {
+		return nil, errors.Wrap(err, "couldn't parse grace period")
+	}
+
+	keep := make([]uint64, 0)
+	for _, id := range d.Get("keep_contracts").([]interface{}) {
+		keep = append(keep, uint64(id.(int)))
+	}
+
+	twin := uint64(tfPluginClient.TwinID)
+	contractType := "node"
+	contracts, err := listContracts(ctx, tfPluginClient, proxyTypes.ContractFilter{
+		TwinID: &twin,
+		Type:   &contractType,
+		State:  []string{"Created", "GracePeriod"},
+	}, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	return filterOrphanedContracts(ctx, contracts, prefix, keep, time.Now().Add(-gracePeriod)), nil
+}
+
+func contractIDsToList(ids []uint64) []interface{} {
+	list := make([]interface{}, 0, len(ids))
+	for _, id := range ids {
+		list = append(list, int(id))
+	}
+	return list
+}
+
+// collectContracts runs the collector, canceling the orphaned contracts if not in dry run mode
+func collectContracts(ctx context.Context, d *schema.ResourceData, tfPluginClient *deployer.TFPluginClient) diag.Diagnostics {
+	dryRun := d.Get("dry_run").(bool)
+	if !dryRun && len(d.Get("keep_contracts").([]interface{})) == 0 {
+		return diag.Errorf("refusing to cancel the contracts matching prefix '%s' with empty keep_contracts, set the contracts referenced by the state or enable dry_run", d.Get("prefix").(string))
+	}
+
+	orphans, err := orphanedContracts(ctx, d, tfPluginClient)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	canceled := make([]uint64, 0)
+	if !dryRun && len(orphans) != 0 {
+		tflog.Info(ctx, "canceling orphaned contracts", map[string]interface{}{"contract_ids": orphans})
+
+		err = traceCall(ctx, "Substrate.BatchCancelContract", func() error {
+			return tfPluginClient.SubstrateConn.BatchCancelContract(tfPluginClient.Identity, orphans)
+		})
+		if err != nil {
+			return diag.Errorf("couldn't cancel orphaned contracts %v with error: %v", orphans, err)
+		}
+
+		canceled, orphans = orphans, make([]uint64, 0)
+	}
+
+	if err := d.Set("orphaned_contracts", contractIDsToList(orphans)); err != nil {
+		return diag.FromErr(errors.Wrap(err, "couldn't set orphaned contracts"))
+	}
+
+	if err := d.Set("canceled_contracts", contractIDsToList(canceled)); err != nil {
+		return diag.FromErr(errors.Wrap(err, "couldn't set canceled contracts"))
+	}
+
+	return nil
+}
+
+func resourceContractGCCreate(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	tfPluginClient, ok := meta.(*deployer.TFPluginClient)
+	if !ok {
+		return diag.FromErr(fmt.Errorf("failed to cast meta into threefold plugin client"))
+	}
+
+	ctx = resourceLogContext(ctx, "grid_contract_gc", d)
+
+	diags := collectContracts(ctx, d, tfPluginClient)
+	if diags.HasError() {
+		return diags
+	}
+
+	d.SetId(strconv.FormatInt(time.Now().Unix(), 10))
+	return diags
+}
+
+func resourceContractGCRead(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	tfPluginClient, ok := meta.(*deployer.TFPluginClient)
+	if !ok {
+		return diag.FromErr(fmt.Errorf("failed to cast meta into threefold plugin client"))
+	}
+
+	ctx = resourceLogContext(ctx, "grid_contract_gc", d)
+
+	// reading only reports the orphaned contracts, they are canceled on create and update
+	orphans, err := orphanedContracts(ctx, d, tfPluginClient)
+	if err != nil {
+		return diag.Diagnostics{{
+			Severity: diag.Warning,
+			Summary:  "failed to read orphaned contracts (terraform refresh might help)",
+			Detail:   err.Error(),
+		}}
+	}
+
+	if err := d.Set("orphaned_contracts", contractIDsToList(orphans)); err != nil {
+		return diag.FromErr(errors.Wrap(err, "couldn't set orphaned contracts"))
+	}
+
+	return nil
+}
+
+func resourceContractGCUpdate(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	tfPluginClient, ok := meta.(*deployer.TFPluginClient)
+	if !ok {
+		return diag.FromErr(fmt.Errorf("failed to cast meta into threefold plugin client"))
+	}
+
+	ctx = resourceLogContext(ctx, "grid_contract_gc", d)
+
+	return collectContracts(ctx, d, tfPluginClient)
+}
+
+func resourceContractGCDelete(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	// the collector doesn't own any contract, so there is nothing to cancel
+	d.SetId("")
+	return nil
+}
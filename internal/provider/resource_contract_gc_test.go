@@ -0,0 +1,84 @@
+// Package provider is the terraform provider
+package provider
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/threefoldtech/tfgrid-sdk-go/grid-client/deployer"
+	proxyTypes "github.com/threefoldtech/tfgrid-sdk-go/grid-proxy/pkg/types"
+)
+
+func testNodeContract(id uint, createdAt time.Time, projectName, name string) proxyTypes.Contract {
+	return proxyTypes.Contract{
+		ContractID: id,
+		CreatedAt:  uint(createdAt.Unix()),
+		Type:       "node",
+		Details: proxyTypes.NodeContractDetails{
+			DeploymentData: fmt.Sprintf(`{"version":0,"type":"vm","name":"%s","projectName":"%s"}`, name, projectName),
+		},
+	}
+}
+
+func TestContractMatchesPrefix(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+
+	assert.True(t, contractMatchesPrefix(ctx, testNodeContract(1, now, "myproject/vm", "vm"), "myproject"))
+	assert.True(t, contractMatchesPrefix(ctx, testNodeContract(1, now, "myproject", "vm"), "myproject"))
+	assert.True(t, contractMatchesPrefix(ctx, testNodeContract(1, now, "other", "myproject/vm"), "myproject"))
+	assert.False(t, contractMatchesPrefix(ctx, testNodeContract(1, now, "other", "myproject_vm"), "myproject"))
+	assert.False(t, contractMatchesPrefix(ctx, testNodeContract(1, now, "myproject2/vm", "vm"), "myproject"), "the prefix should match a whole segment")
+	assert.False(t, contractMatchesPrefix(ctx, testNodeContract(1, now, "other", "vm"), "myproject"))
+
+	invalid := proxyTypes.Contract{ContractID: 1, Details: proxyTypes.NodeContractDetails{DeploymentData: "not json"}}
+	assert.False(t, contractMatchesPrefix(ctx, invalid, "myproject"))
+
+	name := proxyTypes.Contract{ContractID: 1, Type: "name", Details: proxyTypes.NameContractDetails{Name: "myproject"}}
+	assert.False(t, contractMatchesPrefix(ctx, name, "myproject"), "only node contracts should be collected")
+}
+
+func TestFilterOrphanedContracts(t *testing.T) {
+	now := time.Now()
+	old := now.Add(-48 * time.Hour)
+
+	contracts := []proxyTypes.Contract{
+		testNodeContract(1, old, "myproject", "vm"),
+		testNodeContract(2, old, "myproject", "kept"),
+		testNodeContract(3, now.Add(-time.Minute), "myproject", "creating"),
+		testNodeContract(4, old, "other", "vm"),
+	}
+
+	orphans := filterOrphanedContracts(context.Background(), contracts, "myproject", []uint64{2}, now.Add(-defaultContractGCGracePeriod))
+	assert.Equal(t, []uint64{1}, orphans, "kept, young and other projects contracts shouldn't be collected")
+}
+
+func TestCollectContractsRefusesEmptyKeepContracts(t *testing.T) {
+	d := schema.TestResourceDataRaw(t, resourceContractGC().Schema, map[string]interface{}{
+		"prefix":  "myproject",
+		"dry_run": false,
+	})
+
+	// the client is empty, so the collector fails before listing or canceling any contract
+	diags := collectContracts(context.Background(), d, &deployer.TFPluginClient{})
+	require.True(t, diags.HasError())
+	assert.Contains(t, diags[0].Summary, "empty keep_contracts")
+}
+
+func TestValidateMinDuration(t *testing.T) {
+	validate := validateMinDuration(time.Minute)
+
+	_, errs := validate("24h", "grace_period")
+	assert.Empty(t, errs)
+
+	_, errs = validate("10s", "grace_period")
+	assert.Len(t, errs, 1)
+
+	_, errs = validate("a day", "grace_period")
+	assert.Len(t, errs, 1)
+}
@@ -11,7 +11,9 @@ import (
 	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
+	"github.com/pkg/errors"
 	"github.com/threefoldtech/tfgrid-sdk-go/grid-client/deployer"
+	"github.com/threefoldtech/tfgrid-sdk-go/grid-client/zos"
 )
 
 func resourceDeployment() *schema.Resource {
@@ -22,9 +24,17 @@ func resourceDeployment() *schema.Resource {
 		ReadContext:   resourceDeploymentRead,
 		UpdateContext: resourceDeploymentUpdate,
 		DeleteContext: resourceDeploymentDelete,
+		CustomizeDiff: resourceDeploymentCustomizeDiff,
+
+		Importer: &schema.ResourceImporter{
+			StateContext: resourceDeploymentImport,
+		},
 
 		Timeouts: &schema.ResourceTimeout{
 			Create: schema.DefaultTimeout(45 * time.Minute),
+			Read:   schema.DefaultTimeout(20 * time.Minute),
+			Update: schema.DefaultTimeout(45 * time.Minute),
+			Delete: schema.DefaultTimeout(20 * time.Minute),
 		},
 
 		Schema: map[string]*schema.Schema{
@@ -52,6 +62,11 @@ func resourceDeployment() *schema.Resource {
 				Default:     0,
 				Description: "Solution provider ID for the deployed solution which allows the creator of the solution to gain a percentage of the rewards.",
 			},
+			"estimated_monthly_cost": {
+				Type:        schema.TypeFloat,
+				Computed:    true,
+				Description: "Estimated monthly cost in TFT of the deployment node contracts using the farms pricing policies and the discount package of the twin balance, known at plan time once the nodes are known. Network usage isn't included, and only the public ips are counted on nodes rented by the configured twin.",
+			},
 			"ip_range": {
 				Type:        schema.TypeString,
 				Computed:    true,
@@ -477,6 +492,28 @@ func resourceDeployment() *schema.Resource {
 	}
 }
 
+// resourceDeploymentCustomizeDiff plans the estimated monthly cost of the deployment
+func resourceDeploymentCustomizeDiff(ctx context.Context, d *schema.ResourceDiff, meta interface{}) error {
+	tfPluginClient, ok := meta.(*deployer.TFPluginClient)
+	if !ok {
+		return fmt.Errorf("failed to cast meta into threefold plugin client")
+	}
+
+	capacityAttrs := []string{"node", "vms", "disks", "zdbs", "qsfs"}
+	return planEstimatedMonthlyCost(ctx, d, tfPluginClient, capacityAttrs, func() (map[uint32]zos.Capacity, error) {
+		if d.Get("node").(int) == 0 {
+			return nil, errors.New("deployment node isn't known yet")
+		}
+
+		dl, err := newDeploymentFromSchema(ctx, d, tfPluginClient.NcPool, tfPluginClient.SubstrateConn)
+		if err != nil {
+			return nil, err
+		}
+
+		return deploymentCapacity(dl, tfPluginClient.TwinID)
+	})
+}
+
 func resourceDeploymentCreate(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
 	var diags diag.Diagnostics
 	tfPluginClient, ok := meta.(*deployer.TFPluginClient)
@@ -484,23 +521,41 @@ func resourceDeploymentCreate(ctx context.Context, d *schema.ResourceData, meta
 		return diag.FromErr(fmt.Errorf("failed to cast meta into threefold plugin client"))
 	}
 
+	ctx = resourceLogContext(ctx, "grid_deployment", d)
+
 	dl, err := newDeploymentFromSchema(ctx, d, tfPluginClient.NcPool, tfPluginClient.SubstrateConn)
 	if err != nil {
 		return diag.Errorf("couldn't load deployment data with error: %v", err)
 	}
 
-	if err := tfPluginClient.DeploymentDeployer.Deploy(ctx, dl); err != nil {
+	if len(dl.Vms) != 0 || len(dl.VmsLight) != 0 {
+		if err := ensureNetworkSubnets(ctx, tfPluginClient, dl.NetworkName, dl.NodeID); err != nil {
+			return diag.FromErr(err)
+		}
+	}
+
+	err = traceCall(ctx, "DeploymentDeployer.Deploy", func() error { return tfPluginClient.DeploymentDeployer.Deploy(ctx, dl) })
+	if err != nil && dl.ContractID == 0 {
 		return diag.Errorf("couldn't deploy deployment with error: %v", err)
 	}
 
-	if err := tfPluginClient.DeploymentDeployer.Sync(ctx, dl); err != nil {
-		return diag.Errorf("couldn't sync deployment with error: %v", err)
+	if err != nil {
+		// failed to deploy (e.g. timed out) and failed to revert, store the current state to keep track of the contract
+		diags = diag.Errorf("couldn't deploy deployment with error: %v", err)
+	} else if err := traceCall(ctx, "DeploymentDeployer.Sync", func() error { return tfPluginClient.DeploymentDeployer.Sync(ctx, dl) }); err != nil {
+		diags = diag.Errorf("couldn't sync deployment with error: %v", err)
 	}
 
 	if err := syncContractsDeployments(d, dl); err != nil {
-		return diag.Errorf("couldn't set deployment data to the resource with error: %v", err)
+		return append(diags, diag.Errorf("couldn't set deployment data to the resource with error: %v", err)...)
 	}
 
+	diags = append(diags, setEstimatedMonthlyCost(ctx, d, tfPluginClient, func() (map[uint32]zos.Capacity, error) {
+		return deploymentCapacity(dl, tfPluginClient.TwinID)
+	})...)
+
+	logNodeContracts(ctx, "deployment created", map[uint32]uint64{dl.NodeID: dl.ContractID})
+
 	return diags
 }
 
@@ -511,12 +566,16 @@ func resourceDeploymentRead(ctx context.Context, d *schema.ResourceData, meta in
 		return diag.FromErr(fmt.Errorf("failed to cast meta into threefold plugin client"))
 	}
 
+	ctx = resourceLogContext(ctx, "grid_deployment", d)
+
 	dl, err := newDeploymentFromSchema(ctx, d, tfPluginClient.NcPool, tfPluginClient.SubstrateConn)
 	if err != nil {
 		return diag.Errorf("couldn't load deployment data with error: %v", err)
 	}
 
-	if err := tfPluginClient.DeploymentDeployer.Sync(ctx, dl); err != nil {
+	recordNetworkSubnets(tfPluginClient, dl.NetworkName, map[uint32]string{dl.NodeID: dl.IPrange})
+
+	if err := traceCall(ctx, "DeploymentDeployer.Sync", func() error { return tfPluginClient.DeploymentDeployer.Sync(ctx, dl) }); err != nil {
 		diags = append(diags, diag.Diagnostic{
 			Severity: diag.Warning,
 			Summary:  "failed to read deployment data (terraform refresh might help)",
@@ -529,6 +588,12 @@ func resourceDeploymentRead(ctx context.Context, d *schema.ResourceData, meta in
 		return diag.Errorf("couldn't set deployment data to the resource with error: %v", err)
 	}
 
+	diags = append(diags, readEstimatedMonthlyCost(ctx, d, tfPluginClient, func() (map[uint32]zos.Capacity, error) {
+		return deploymentCapacity(dl, tfPluginClient.TwinID)
+	})...)
+
+	logNodeContracts(ctx, "deployment read", map[uint32]uint64{dl.NodeID: dl.ContractID})
+
 	return diags
 }
 
@@ -539,6 +604,8 @@ func resourceDeploymentUpdate(ctx context.Context, d *schema.ResourceData, meta
 		return diag.FromErr(fmt.Errorf("failed to cast meta into threefold plugin client"))
 	}
 
+	ctx = resourceLogContext(ctx, "grid_deployment", d)
+
 	if d.HasChange("node") {
 		oldContractID, err := strconv.ParseUint(d.Id(), 10, 64)
 		if err != nil {
@@ -556,18 +623,34 @@ func resourceDeploymentUpdate(ctx context.Context, d *schema.ResourceData, meta
 		return diag.Errorf("couldn't load deployment data with error: %v", err)
 	}
 
-	if err := tfPluginClient.DeploymentDeployer.Deploy(ctx, dl); err != nil {
+	if len(dl.Vms) != 0 || len(dl.VmsLight) != 0 {
+		if err := ensureNetworkSubnets(ctx, tfPluginClient, dl.NetworkName, dl.NodeID); err != nil {
+			return diag.FromErr(err)
+		}
+	}
+
+	err = traceCall(ctx, "DeploymentDeployer.Deploy", func() error { return tfPluginClient.DeploymentDeployer.Deploy(ctx, dl) })
+	if err != nil && dl.ContractID == 0 {
 		return diag.Errorf("couldn't update deployment with error: %v", err)
 	}
 
-	if err := tfPluginClient.DeploymentDeployer.Sync(ctx, dl); err != nil {
-		return diag.Errorf("couldn't sync deployment with error: %v", err)
+	if err != nil {
+		// failed to deploy (e.g. timed out) and failed to revert, store the current state to keep track of the contract
+		diags = diag.Errorf("couldn't update deployment with error: %v", err)
+	} else if err := traceCall(ctx, "DeploymentDeployer.Sync", func() error { return tfPluginClient.DeploymentDeployer.Sync(ctx, dl) }); err != nil {
+		diags = diag.Errorf("couldn't sync deployment with error: %v", err)
 	}
 
 	if err := syncContractsDeployments(d, dl); err != nil {
-		return diag.Errorf("couldn't set deployment data to the resource with error: %v", err)
+		return append(diags, diag.Errorf("couldn't set deployment data to the resource with error: %v", err)...)
 	}
 
+	diags = append(diags, setEstimatedMonthlyCost(ctx, d, tfPluginClient, func() (map[uint32]zos.Capacity, error) {
+		return deploymentCapacity(dl, tfPluginClient.TwinID)
+	})...)
+
+	logNodeContracts(ctx, "deployment updated", map[uint32]uint64{dl.NodeID: dl.ContractID})
+
 	return diags
 }
 
@@ -578,16 +661,20 @@ func resourceDeploymentDelete(ctx context.Context, d *schema.ResourceData, meta
 		return diag.FromErr(fmt.Errorf("failed to cast meta into threefold plugin client"))
 	}
 
+	ctx = resourceLogContext(ctx, "grid_deployment", d)
+
 	dl, err := newDeploymentFromSchema(ctx, d, tfPluginClient.NcPool, tfPluginClient.SubstrateConn)
 	if err != nil {
 		return diag.Errorf("couldn't load deployment data with error: %v", err)
 	}
 
-	if err := tfPluginClient.DeploymentDeployer.Cancel(ctx, dl); err != nil {
+	logNodeContracts(ctx, "deleting deployment", map[uint32]uint64{dl.NodeID: dl.ContractID})
+
+	if err := traceCall(ctx, "DeploymentDeployer.Cancel", func() error { return tfPluginClient.DeploymentDeployer.Cancel(ctx, dl) }); err != nil {
 		return diag.Errorf("couldn't cancel deployment with error: %v", err)
 	}
 
-	if err := tfPluginClient.DeploymentDeployer.Sync(ctx, dl); err != nil {
+	if err := traceCall(ctx, "DeploymentDeployer.Sync", func() error { return tfPluginClient.DeploymentDeployer.Sync(ctx, dl) }); err != nil {
 		return diag.Errorf("couldn't sync deployment with error: %v", err)
 	}
 
@@ -597,3 +684,36 @@ func resourceDeploymentDelete(ctx context.Context, d *schema.ResourceData, meta
 
 	return diags
 }
+
+func resourceDeploymentImport(ctx context.Context, d *schema.ResourceData, meta interface{}) ([]*schema.ResourceData, error) {
+	tfPluginClient, ok := meta.(*deployer.TFPluginClient)
+	if !ok {
+		return nil, fmt.Errorf("failed to cast meta into threefold plugin client")
+	}
+
+	ctx = resourceLogContext(ctx, "grid_deployment", d)
+
+	contractID, err := strconv.ParseUint(d.Id(), 10, 64)
+	if err != nil {
+		return nil, errors.Wrapf(err, "couldn't parse deployment id %s, expected a node contract id", d.Id())
+	}
+
+	dl, err := loadDeploymentFromContract(ctx, tfPluginClient, contractID)
+	if err != nil {
+		return nil, errors.Wrap(err, "couldn't load deployment data")
+	}
+
+	if err := traceCall(ctx, "DeploymentDeployer.Sync", func() error { return tfPluginClient.DeploymentDeployer.Sync(ctx, dl) }); err != nil {
+		return nil, errors.Wrap(err, "couldn't sync deployment")
+	}
+
+	if err := d.Set("name", dl.Name); err != nil {
+		return nil, errors.Wrap(err, "couldn't set deployment name")
+	}
+
+	if err := syncContractsDeployments(d, dl); err != nil {
+		return nil, errors.Wrap(err, "couldn't set deployment data to the resource")
+	}
+
+	return []*schema.ResourceData{d}, nil
+}
@@ -5,6 +5,7 @@ import (
 	"context"
 	"fmt"
 	"regexp"
+	"time"
 
 	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
@@ -22,6 +23,13 @@ func resourceGatewayFQDNProxy() *schema.Resource {
 		UpdateContext: resourceGatewayFQDNUpdate,
 		DeleteContext: resourceGatewayFQDNDelete,
 
+		Timeouts: &schema.ResourceTimeout{
+			Create: schema.DefaultTimeout(20 * time.Minute),
+			Read:   schema.DefaultTimeout(20 * time.Minute),
+			Update: schema.DefaultTimeout(20 * time.Minute),
+			Delete: schema.DefaultTimeout(20 * time.Minute),
+		},
+
 		Schema: map[string]*schema.Schema{
 			"name": {
 				Type:             schema.TypeString,
@@ -89,23 +97,32 @@ func resourceGatewayFQDNCreate(ctx context.Context, d *schema.ResourceData, meta
 		return diag.FromErr(fmt.Errorf("failed to cast meta into threefold plugin client"))
 	}
 
+	ctx = resourceLogContext(ctx, "grid_fqdn_proxy", d)
+
 	gw, err := newFQDNGatewayFromSchema(d)
 	if err != nil {
 		return diag.Errorf("couldn't load fqdn gateway data with error: %v", err)
 	}
 
-	if err := tfPluginClient.GatewayFQDNDeployer.Deploy(ctx, gw); err != nil {
+	err = traceCall(ctx, "GatewayFQDNDeployer.Deploy", func() error { return tfPluginClient.GatewayFQDNDeployer.Deploy(ctx, gw) })
+	if err != nil && gw.NodeDeploymentID[gw.NodeID] == 0 {
 		return diag.Errorf("couldn't deploy fqdn gateway with error: %v", err)
 	}
 
-	if err := tfPluginClient.GatewayFQDNDeployer.Sync(ctx, gw); err != nil {
-		return diag.Errorf("couldn't sync fqdn gateway with error: %v", err)
+	if err != nil {
+		// failed to deploy (e.g. timed out) and failed to revert, store the current state to keep track of the contract
+		gw.ContractID = gw.NodeDeploymentID[gw.NodeID]
+		diags = diag.Errorf("couldn't deploy fqdn gateway with error: %v", err)
+	} else if err := traceCall(ctx, "GatewayFQDNDeployer.Sync", func() error { return tfPluginClient.GatewayFQDNDeployer.Sync(ctx, gw) }); err != nil {
+		diags = diag.Errorf("couldn't sync fqdn gateway with error: %v", err)
 	}
 
 	if err := syncContractsFQDNGateways(d, gw); err != nil {
-		return diag.Errorf("couldn't set fqdn gateway data to the resource with error: %v", err)
+		return append(diags, diag.Errorf("couldn't set fqdn gateway data to the resource with error: %v", err)...)
 	}
 
+	logNodeContracts(ctx, "fqdn gateway created", gw.NodeDeploymentID)
+
 	return diags
 }
 
@@ -116,23 +133,32 @@ func resourceGatewayFQDNUpdate(ctx context.Context, d *schema.ResourceData, meta
 		return diag.FromErr(fmt.Errorf("failed to cast meta into threefold plugin client"))
 	}
 
+	ctx = resourceLogContext(ctx, "grid_fqdn_proxy", d)
+
 	gw, err := newFQDNGatewayFromSchema(d)
 	if err != nil {
 		return diag.Errorf("couldn't load fqdn gateway data with error: %v", err)
 	}
 
-	if err := tfPluginClient.GatewayFQDNDeployer.Deploy(ctx, gw); err != nil {
+	err = traceCall(ctx, "GatewayFQDNDeployer.Deploy", func() error { return tfPluginClient.GatewayFQDNDeployer.Deploy(ctx, gw) })
+	if err != nil && gw.NodeDeploymentID[gw.NodeID] == 0 {
 		return diag.Errorf("couldn't update fqdn gateway with error: %v", err)
 	}
 
-	if err := tfPluginClient.GatewayFQDNDeployer.Sync(ctx, gw); err != nil {
-		return diag.Errorf("couldn't sync fqdn gateway with error: %v", err)
+	if err != nil {
+		// failed to deploy (e.g. timed out) and failed to revert, store the current state to keep track of the contract
+		gw.ContractID = gw.NodeDeploymentID[gw.NodeID]
+		diags = diag.Errorf("couldn't update fqdn gateway with error: %v", err)
+	} else if err := traceCall(ctx, "GatewayFQDNDeployer.Sync", func() error { return tfPluginClient.GatewayFQDNDeployer.Sync(ctx, gw) }); err != nil {
+		diags = diag.Errorf("couldn't sync fqdn gateway with error: %v", err)
 	}
 
 	if err := syncContractsFQDNGateways(d, gw); err != nil {
-		return diag.Errorf("couldn't set fqdn gateway data to the resource with error: %v", err)
+		return append(diags, diag.Errorf("couldn't set fqdn gateway data to the resource with error: %v", err)...)
 	}
 
+	logNodeContracts(ctx, "fqdn gateway updated", gw.NodeDeploymentID)
+
 	return diags
 }
 
@@ -143,12 +169,14 @@ func resourceGatewayFQDNRead(ctx context.Context, d *schema.ResourceData, meta i
 		return diag.FromErr(fmt.Errorf("failed to cast meta into threefold plugin client"))
 	}
 
+	ctx = resourceLogContext(ctx, "grid_fqdn_proxy", d)
+
 	gw, err := newFQDNGatewayFromSchema(d)
 	if err != nil {
 		return diag.Errorf("couldn't load fqdn gateway data with error: %v", err)
 	}
 
-	if err := tfPluginClient.GatewayFQDNDeployer.Sync(ctx, gw); err != nil {
+	if err := traceCall(ctx, "GatewayFQDNDeployer.Sync", func() error { return tfPluginClient.GatewayFQDNDeployer.Sync(ctx, gw) }); err != nil {
 		diags = append(diags, diag.Diagnostic{
 			Severity: diag.Warning,
 			Summary:  "failed to read deployment data (terraform refresh might help)",
@@ -161,6 +189,8 @@ func resourceGatewayFQDNRead(ctx context.Context, d *schema.ResourceData, meta i
 		return diag.Errorf("couldn't set fqdn gateway data to the resource with error: %v", err)
 	}
 
+	logNodeContracts(ctx, "fqdn gateway read", gw.NodeDeploymentID)
+
 	return diags
 }
 
@@ -171,16 +201,20 @@ func resourceGatewayFQDNDelete(ctx context.Context, d *schema.ResourceData, meta
 		return diag.FromErr(fmt.Errorf("failed to cast meta into threefold plugin client"))
 	}
 
+	ctx = resourceLogContext(ctx, "grid_fqdn_proxy", d)
+
 	gw, err := newFQDNGatewayFromSchema(d)
 	if err != nil {
 		return diag.Errorf("couldn't load fqdn gateway data with error: %v", err)
 	}
 
-	if err := tfPluginClient.GatewayFQDNDeployer.Cancel(ctx, gw); err != nil {
+	logNodeContracts(ctx, "deleting fqdn gateway", gw.NodeDeploymentID)
+
+	if err := traceCall(ctx, "GatewayFQDNDeployer.Cancel", func() error { return tfPluginClient.GatewayFQDNDeployer.Cancel(ctx, gw) }); err != nil {
 		return diag.Errorf("couldn't update fqdn gateway with error: %v", err)
 	}
 
-	if err := tfPluginClient.GatewayFQDNDeployer.Sync(ctx, gw); err != nil {
+	if err := traceCall(ctx, "GatewayFQDNDeployer.Sync", func() error { return tfPluginClient.GatewayFQDNDeployer.Sync(ctx, gw) }); err != nil {
 		return diag.Errorf("couldn't sync fqdn gateway with error: %v", err)
 	}
 
@@ -5,6 +5,7 @@ import (
 	"context"
 	"fmt"
 	"regexp"
+	"time"
 
 	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
@@ -21,6 +22,13 @@ func resourceGatewayNameProxy() *schema.Resource {
 		UpdateContext: resourceGatewayNameUpdate,
 		DeleteContext: resourceGatewayNameDelete,
 
+		Timeouts: &schema.ResourceTimeout{
+			Create: schema.DefaultTimeout(20 * time.Minute),
+			Read:   schema.DefaultTimeout(20 * time.Minute),
+			Update: schema.DefaultTimeout(20 * time.Minute),
+			Delete: schema.DefaultTimeout(20 * time.Minute),
+		},
+
 		Schema: map[string]*schema.Schema{
 			"name": {
 				Type:             schema.TypeString,
@@ -75,10 +83,16 @@ func resourceGatewayNameProxy() *schema.Resource {
 				Elem:        &schema.Schema{Type: schema.TypeInt},
 				Description: "Mapping from each node to its deployment id.",
 			},
+			"name_contract": {
+				Type:        schema.TypeInt,
+				Optional:    true,
+				Default:     0,
+				Description: "The id of a name contract reserved using a grid_name_contract resource. If set, the gateway uses the reserved name instead of creating its own name contract, and the name is kept when the gateway is destroyed or replaced.",
+			},
 			"name_contract_id": {
 				Type:        schema.TypeInt,
 				Computed:    true,
-				Description: "The id of the created name contract.",
+				Description: "The id of the name contract used by the gateway.",
 			},
 		},
 	}
@@ -91,23 +105,40 @@ func resourceGatewayNameCreate(ctx context.Context, d *schema.ResourceData, meta
 		return diag.FromErr(fmt.Errorf("failed to cast meta into threefold plugin client"))
 	}
 
+	ctx = resourceLogContext(ctx, "grid_name_proxy", d)
+
 	gw, err := newNameGatewayFromSchema(d)
 	if err != nil {
 		return diag.Errorf("couldn't load name gateway data with error: %v", err)
 	}
 
-	if err := tfPluginClient.GatewayNameDeployer.Deploy(ctx, gw); err != nil {
+	reservedNameContractID := uint64(d.Get("name_contract").(int))
+	if reservedNameContractID != 0 {
+		if err := validateReservedNameContract(tfPluginClient.SubstrateConn, reservedNameContractID, gw.Name); err != nil {
+			return diag.FromErr(err)
+		}
+	}
+	gwDeployer := gatewayNameDeployer(tfPluginClient, reservedNameContractID)
+
+	err = traceCall(ctx, "GatewayNameDeployer.Deploy", func() error { return gwDeployer.Deploy(ctx, gw) })
+	if err != nil && gw.NodeDeploymentID[gw.NodeID] == 0 {
 		return diag.Errorf("couldn't deploy name gateway with error: %v", err)
 	}
 
-	if err := tfPluginClient.GatewayNameDeployer.Sync(ctx, gw); err != nil {
-		return diag.Errorf("couldn't sync name gateway with error: %v", err)
+	if err != nil {
+		// failed to deploy (e.g. timed out) and failed to revert, store the current state to keep track of the contract
+		gw.ContractID = gw.NodeDeploymentID[gw.NodeID]
+		diags = diag.Errorf("couldn't deploy name gateway with error: %v", err)
+	} else if err := traceCall(ctx, "GatewayNameDeployer.Sync", func() error { return gwDeployer.Sync(ctx, gw) }); err != nil {
+		diags = diag.Errorf("couldn't sync name gateway with error: %v", err)
 	}
 
 	if err := syncContractsNameGateways(d, gw); err != nil {
-		return diag.Errorf("couldn't set name gateway data to the resource with error: %v", err)
+		return append(diags, diag.Errorf("couldn't set name gateway data to the resource with error: %v", err)...)
 	}
 
+	logNodeContracts(ctx, "name gateway created", gw.NodeDeploymentID)
+
 	return diags
 }
 
@@ -118,23 +149,40 @@ func resourceGatewayNameUpdate(ctx context.Context, d *schema.ResourceData, meta
 		return diag.FromErr(fmt.Errorf("failed to cast meta into threefold plugin client"))
 	}
 
+	ctx = resourceLogContext(ctx, "grid_name_proxy", d)
+
 	gw, err := newNameGatewayFromSchema(d)
 	if err != nil {
 		return diag.Errorf("couldn't load name gateway data with error: %v", err)
 	}
 
-	if err := tfPluginClient.GatewayNameDeployer.Deploy(ctx, gw); err != nil {
+	reservedNameContractID := uint64(d.Get("name_contract").(int))
+	if reservedNameContractID != 0 {
+		if err := validateReservedNameContract(tfPluginClient.SubstrateConn, reservedNameContractID, gw.Name); err != nil {
+			return diag.FromErr(err)
+		}
+	}
+	gwDeployer := gatewayNameDeployer(tfPluginClient, reservedNameContractID)
+
+	err = traceCall(ctx, "GatewayNameDeployer.Deploy", func() error { return gwDeployer.Deploy(ctx, gw) })
+	if err != nil && gw.NodeDeploymentID[gw.NodeID] == 0 {
 		return diag.Errorf("couldn't update name gateway with error: %v", err)
 	}
 
-	if err := tfPluginClient.GatewayNameDeployer.Sync(ctx, gw); err != nil {
-		return diag.Errorf("couldn't sync name gateway with error: %v", err)
+	if err != nil {
+		// failed to deploy (e.g. timed out) and failed to revert, store the current state to keep track of the contract
+		gw.ContractID = gw.NodeDeploymentID[gw.NodeID]
+		diags = diag.Errorf("couldn't update name gateway with error: %v", err)
+	} else if err := traceCall(ctx, "GatewayNameDeployer.Sync", func() error { return gwDeployer.Sync(ctx, gw) }); err != nil {
+		diags = diag.Errorf("couldn't sync name gateway with error: %v", err)
 	}
 
 	if err := syncContractsNameGateways(d, gw); err != nil {
-		return diag.Errorf("couldn't set name gateway data to the resource with error: %v", err)
+		return append(diags, diag.Errorf("couldn't set name gateway data to the resource with error: %v", err)...)
 	}
 
+	logNodeContracts(ctx, "name gateway updated", gw.NodeDeploymentID)
+
 	return diags
 }
 
@@ -145,12 +193,15 @@ func resourceGatewayNameRead(ctx context.Context, d *schema.ResourceData, meta i
 		return diag.FromErr(fmt.Errorf("failed to cast meta into threefold plugin client"))
 	}
 
+	ctx = resourceLogContext(ctx, "grid_name_proxy", d)
+
 	gw, err := newNameGatewayFromSchema(d)
 	if err != nil {
 		return diag.Errorf("couldn't load name gateway data with error: %v", err)
 	}
+	gwDeployer := gatewayNameDeployer(tfPluginClient, uint64(d.Get("name_contract").(int)))
 
-	if err := tfPluginClient.GatewayNameDeployer.Sync(ctx, gw); err != nil {
+	if err := traceCall(ctx, "GatewayNameDeployer.Sync", func() error { return gwDeployer.Sync(ctx, gw) }); err != nil {
 		diags = append(diags, diag.Diagnostic{
 			Severity: diag.Warning,
 			Summary:  "failed to read deployment data (terraform refresh might help)",
@@ -163,6 +214,8 @@ func resourceGatewayNameRead(ctx context.Context, d *schema.ResourceData, meta i
 		return diag.Errorf("couldn't set name gateway data to the resource with error: %v", err)
 	}
 
+	logNodeContracts(ctx, "name gateway read", gw.NodeDeploymentID)
+
 	return diags
 }
 
@@ -173,16 +226,22 @@ func resourceGatewayNameDelete(ctx context.Context, d *schema.ResourceData, meta
 		return diag.FromErr(fmt.Errorf("failed to cast meta into threefold plugin client"))
 	}
 
+	ctx = resourceLogContext(ctx, "grid_name_proxy", d)
+
 	gw, err := newNameGatewayFromSchema(d)
 	if err != nil {
 		return diag.Errorf("couldn't load name gateway data with error: %v", err)
 	}
 
-	if err := tfPluginClient.GatewayNameDeployer.Cancel(ctx, gw); err != nil {
+	gwDeployer := gatewayNameDeployer(tfPluginClient, uint64(d.Get("name_contract").(int)))
+
+	logNodeContracts(ctx, "deleting name gateway", gw.NodeDeploymentID)
+
+	if err := traceCall(ctx, "GatewayNameDeployer.Cancel", func() error { return gwDeployer.Cancel(ctx, gw) }); err != nil {
 		return diag.Errorf("couldn't cancel name gateway with error: %v", err)
 	}
 
-	if err := tfPluginClient.GatewayNameDeployer.Sync(ctx, gw); err != nil {
+	if err := traceCall(ctx, "GatewayNameDeployer.Sync", func() error { return gwDeployer.Sync(ctx, gw) }); err != nil {
 		return diag.Errorf("couldn't sync name gateway with error: %v", err)
 	}
 
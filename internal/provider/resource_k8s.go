@@ -5,6 +5,7 @@ import (
 	"context"
 	"fmt"
 	"regexp"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
@@ -12,6 +13,7 @@ import (
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
 	"github.com/pkg/errors"
 	"github.com/threefoldtech/tfgrid-sdk-go/grid-client/deployer"
+	"github.com/threefoldtech/tfgrid-sdk-go/grid-client/zos"
 )
 
 func resourceKubernetes() *schema.Resource {
@@ -23,6 +25,18 @@ func resourceKubernetes() *schema.Resource {
 		ReadContext:   resourceK8sRead,
 		UpdateContext: resourceK8sUpdate,
 		DeleteContext: resourceK8sDelete,
+		CustomizeDiff: resourceK8sCustomizeDiff,
+
+		Importer: &schema.ResourceImporter{
+			StateContext: resourceK8sImport,
+		},
+
+		Timeouts: &schema.ResourceTimeout{
+			Create: schema.DefaultTimeout(45 * time.Minute),
+			Read:   schema.DefaultTimeout(20 * time.Minute),
+			Update: schema.DefaultTimeout(45 * time.Minute),
+			Delete: schema.DefaultTimeout(20 * time.Minute),
+		},
 
 		Schema: map[string]*schema.Schema{
 			"name": {
@@ -37,6 +51,11 @@ func resourceKubernetes() *schema.Resource {
 				Default:     "",
 				Description: "Solution type for the created contracts to be consistent across threefold tooling.",
 			},
+			"estimated_monthly_cost": {
+				Type:        schema.TypeFloat,
+				Computed:    true,
+				Description: "Estimated monthly cost in TFT of the cluster node contracts using the farms pricing policies and the discount package of the twin balance, known at plan time once the nodes are known. Network usage isn't included, and only the public ips are counted on nodes rented by the configured twin.",
+			},
 			"node_deployment_id": {
 				Type:        schema.TypeMap,
 				Computed:    true,
@@ -326,6 +345,30 @@ func resourceKubernetes() *schema.Resource {
 	}
 }
 
+// resourceK8sCustomizeDiff plans the estimated monthly cost of the cluster
+func resourceK8sCustomizeDiff(ctx context.Context, d *schema.ResourceDiff, meta interface{}) error {
+	tfPluginClient, ok := meta.(*deployer.TFPluginClient)
+	if !ok {
+		return fmt.Errorf("failed to cast meta into threefold plugin client")
+	}
+
+	capacityAttrs := []string{"master", "workers"}
+	return planEstimatedMonthlyCost(ctx, d, tfPluginClient, capacityAttrs, func() (map[uint32]zos.Capacity, error) {
+		k8s, err := newK8sFromSchema(d)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, nodeID := range k8sNodeIDs(k8s) {
+			if nodeID == 0 {
+				return nil, errors.New("cluster nodes aren't known yet")
+			}
+		}
+
+		return k8sCapacity(k8s)
+	})
+}
+
 func resourceK8sCreate(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
 	var diags diag.Diagnostics
 	tfPluginClient, ok := meta.(*deployer.TFPluginClient)
@@ -333,26 +376,41 @@ func resourceK8sCreate(ctx context.Context, d *schema.ResourceData, meta interfa
 		return diag.FromErr(fmt.Errorf("failed to cast meta into threefold plugin client"))
 	}
 
+	ctx = resourceLogContext(ctx, "grid_kubernetes", d)
+
 	k8sCluster, err := newK8sFromSchema(d)
 	if err != nil {
 		return diag.Errorf("couldn't load k8s cluster data with error: %v", err)
 	}
 
-	if err := tfPluginClient.K8sDeployer.Deploy(ctx, k8sCluster); err != nil {
+	if err := ensureNetworkSubnets(ctx, tfPluginClient, k8sCluster.NetworkName, k8sNodeIDs(k8sCluster)...); err != nil {
+		return diag.FromErr(err)
+	}
+
+	err = traceCall(ctx, "K8sDeployer.Deploy", func() error { return tfPluginClient.K8sDeployer.Deploy(ctx, k8sCluster) })
+	if err != nil && len(k8sCluster.NodeDeploymentID) == 0 {
 		return diag.Errorf("couldn't deploy k8s cluster with error: %v", err)
 	}
 
-	err = tfPluginClient.K8sDeployer.UpdateFromRemote(ctx, k8sCluster)
 	if err != nil {
-		return diag.Errorf("couldn't update k8s cluster from remote with error: %v", err)
+		// failed to deploy (e.g. timed out) and failed to revert, store the current state to keep track of the contracts
+		diags = diag.Errorf("couldn't deploy k8s cluster with error: %v", err)
+	} else if err := traceCall(ctx, "K8sDeployer.UpdateFromRemote", func() error { return tfPluginClient.K8sDeployer.UpdateFromRemote(ctx, k8sCluster) }); err != nil {
+		diags = diag.Errorf("couldn't update k8s cluster from remote with error: %v", err)
 	}
 
 	err = storeK8sState(d, k8sCluster)
 	if err != nil {
-		diags = diag.FromErr(err)
+		diags = append(diags, diag.FromErr(err)...)
 	}
 
 	d.SetId(uuid.New().String())
+
+	diags = append(diags, setEstimatedMonthlyCost(ctx, d, tfPluginClient, func() (map[uint32]zos.Capacity, error) {
+		return k8sCapacity(k8sCluster)
+	})...)
+
+	logNodeContracts(ctx, "k8s cluster created", k8sCluster.NodeDeploymentID)
 	return diags
 }
 
@@ -363,25 +421,40 @@ func resourceK8sUpdate(ctx context.Context, d *schema.ResourceData, meta interfa
 		return diag.FromErr(fmt.Errorf("failed to cast meta into threefold plugin client"))
 	}
 
+	ctx = resourceLogContext(ctx, "grid_kubernetes", d)
+
 	k8sCluster, err := newK8sFromSchema(d)
 	if err != nil {
 		return diag.Errorf("couldn't load k8s cluster data with error: %v", err)
 	}
 
-	if err := tfPluginClient.K8sDeployer.Deploy(ctx, k8sCluster); err != nil {
+	if err := ensureNetworkSubnets(ctx, tfPluginClient, k8sCluster.NetworkName, k8sNodeIDs(k8sCluster)...); err != nil {
+		return diag.FromErr(err)
+	}
+
+	err = traceCall(ctx, "K8sDeployer.Deploy", func() error { return tfPluginClient.K8sDeployer.Deploy(ctx, k8sCluster) })
+	if err != nil && len(k8sCluster.NodeDeploymentID) == 0 {
 		return diag.Errorf("couldn't update k8s cluster with error: %v", err)
 	}
 
-	err = tfPluginClient.K8sDeployer.UpdateFromRemote(ctx, k8sCluster)
 	if err != nil {
-		return diag.Errorf("couldn't update k8s cluster from remote with error: %v", err)
+		// failed to deploy (e.g. timed out) and failed to revert, store the current state to keep track of the contracts
+		diags = diag.Errorf("couldn't update k8s cluster with error: %v", err)
+	} else if err := traceCall(ctx, "K8sDeployer.UpdateFromRemote", func() error { return tfPluginClient.K8sDeployer.UpdateFromRemote(ctx, k8sCluster) }); err != nil {
+		diags = diag.Errorf("couldn't update k8s cluster from remote with error: %v", err)
 	}
 
 	err = storeK8sState(d, k8sCluster)
 	if err != nil {
-		diags = diag.FromErr(err)
+		diags = append(diags, diag.FromErr(err)...)
 	}
 
+	diags = append(diags, setEstimatedMonthlyCost(ctx, d, tfPluginClient, func() (map[uint32]zos.Capacity, error) {
+		return k8sCapacity(k8sCluster)
+	})...)
+
+	logNodeContracts(ctx, "k8s cluster updated", k8sCluster.NodeDeploymentID)
+
 	return diags
 }
 
@@ -392,12 +465,16 @@ func resourceK8sRead(ctx context.Context, d *schema.ResourceData, meta interface
 		return diag.FromErr(fmt.Errorf("failed to cast meta into threefold plugin client"))
 	}
 
+	ctx = resourceLogContext(ctx, "grid_kubernetes", d)
+
 	k8sCluster, err := newK8sFromSchema(d)
 	if err != nil {
 		return diag.Errorf("couldn't load k8s cluster data with error: %v", err)
 	}
 
-	if err := tfPluginClient.K8sDeployer.Validate(ctx, k8sCluster); err != nil {
+	recordNetworkSubnets(tfPluginClient, k8sCluster.NetworkName, k8sNodesSubnets(k8sCluster))
+
+	if err := traceCall(ctx, "K8sDeployer.Validate", func() error { return tfPluginClient.K8sDeployer.Validate(ctx, k8sCluster) }); err != nil {
 		return diag.FromErr(err)
 	}
 
@@ -405,7 +482,7 @@ func resourceK8sRead(ctx context.Context, d *schema.ResourceData, meta interface
 		return diag.FromErr(errors.Wrap(err, "couldn't invalidate broken attributes"))
 	}
 
-	err = tfPluginClient.K8sDeployer.UpdateFromRemote(ctx, k8sCluster)
+	err = traceCall(ctx, "K8sDeployer.UpdateFromRemote", func() error { return tfPluginClient.K8sDeployer.UpdateFromRemote(ctx, k8sCluster) })
 	if err != nil {
 		diags = append(diags, diag.Diagnostic{
 			Severity: diag.Warning,
@@ -420,6 +497,12 @@ func resourceK8sRead(ctx context.Context, d *schema.ResourceData, meta interface
 		diags = diag.FromErr(err)
 	}
 
+	diags = append(diags, readEstimatedMonthlyCost(ctx, d, tfPluginClient, func() (map[uint32]zos.Capacity, error) {
+		return k8sCapacity(k8sCluster)
+	})...)
+
+	logNodeContracts(ctx, "k8s cluster read", k8sCluster.NodeDeploymentID)
+
 	return diags
 }
 
@@ -429,15 +512,41 @@ func resourceK8sDelete(ctx context.Context, d *schema.ResourceData, meta interfa
 		return diag.FromErr(fmt.Errorf("failed to cast meta into threefold plugin client"))
 	}
 
+	ctx = resourceLogContext(ctx, "grid_kubernetes", d)
+
 	k8sCluster, err := newK8sFromSchema(d)
 	if err != nil {
 		return diag.Errorf("couldn't load k8s cluster data with error: %v", err)
 	}
 
-	if err := tfPluginClient.K8sDeployer.Cancel(ctx, k8sCluster); err != nil {
+	logNodeContracts(ctx, "deleting k8s cluster", k8sCluster.NodeDeploymentID)
+
+	if err := traceCall(ctx, "K8sDeployer.Cancel", func() error { return tfPluginClient.K8sDeployer.Cancel(ctx, k8sCluster) }); err != nil {
 		return diag.Errorf("couldn't cancel k8s cluster with error: %v", err)
 	}
 
 	d.SetId("")
 	return nil
 }
+
+func resourceK8sImport(ctx context.Context, d *schema.ResourceData, meta interface{}) ([]*schema.ResourceData, error) {
+	tfPluginClient, ok := meta.(*deployer.TFPluginClient)
+	if !ok {
+		return nil, fmt.Errorf("failed to cast meta into threefold plugin client")
+	}
+
+	ctx = resourceLogContext(ctx, "grid_kubernetes", d)
+
+	name := d.Id()
+	k8sCluster, err := loadK8sFromGrid(ctx, tfPluginClient, name)
+	if err != nil {
+		return nil, errors.Wrapf(err, "couldn't load k8s cluster '%s'", name)
+	}
+
+	if err := storeImportedK8s(d, name, k8sCluster); err != nil {
+		return nil, err
+	}
+
+	d.SetId(uuid.New().String())
+	return []*schema.ResourceData{d}, nil
+}
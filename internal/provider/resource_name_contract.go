@@ -0,0 +1,142 @@
+// Package provider is the terraform provider
+package provider
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strconv"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
+	"github.com/pkg/errors"
+	substrate "github.com/threefoldtech/tfchain/clients/tfchain-client-go"
+	"github.com/threefoldtech/tfgrid-sdk-go/grid-client/deployer"
+	"github.com/threefoldtech/tfgrid-sdk-go/grid-client/subi"
+)
+
+func resourceNameContract() *schema.Resource {
+	return &schema.Resource{
+		// This description is used by the documentation generator and the language server.
+		Description:   "Resource for reserving a gateway name using a tfchain name contract. The reserved name could be used by a grid_name_proxy resource through its `name_contract` attribute, and it's kept when the gateway is destroyed or moved to another node.",
+		CreateContext: resourceNameContractCreate,
+		ReadContext:   resourceNameContractRead,
+		DeleteContext: resourceNameContractDelete,
+
+		Timeouts: &schema.ResourceTimeout{
+			Create: schema.DefaultTimeout(10 * time.Minute),
+			Read:   schema.DefaultTimeout(10 * time.Minute),
+			Delete: schema.DefaultTimeout(10 * time.Minute),
+		},
+
+		Schema: map[string]*schema.Schema{
+			"name": {
+				Type:             schema.TypeString,
+				Required:         true,
+				ForceNew:         true,
+				Description:      "The gateway name to reserve. Must contain only alphanumeric and underscore characters.",
+				ValidateDiagFunc: validation.ToDiagFunc(validation.StringMatch(regexp.MustCompile(nameValidationRegex), nameValidationErrorMessage)),
+			},
+			"contract_id": {
+				Type:        schema.TypeInt,
+				Computed:    true,
+				Description: "The id of the name contract.",
+			},
+		},
+	}
+}
+
+func resourceNameContractCreate(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	tfPluginClient, ok := meta.(*deployer.TFPluginClient)
+	if !ok {
+		return diag.FromErr(fmt.Errorf("failed to cast meta into threefold plugin client"))
+	}
+
+	ctx = resourceLogContext(ctx, "grid_name_contract", d)
+
+	name := d.Get("name").(string)
+
+	var contractID uint64
+	err := traceCall(ctx, "Substrate.CreateNameContract", func() (err error) {
+		contractID, err = tfPluginClient.SubstrateConn.CreateNameContract(tfPluginClient.Identity, name)
+		return err
+	})
+	if err != nil {
+		return diag.Errorf("couldn't create name contract for %q with error: %v", name, err)
+	}
+
+	d.SetId(fmt.Sprint(contractID))
+	if err := d.Set("contract_id", int(contractID)); err != nil {
+		return diag.Errorf("couldn't set contract id with error: %v", err)
+	}
+
+	return nil
+}
+
+func resourceNameContractRead(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	tfPluginClient, ok := meta.(*deployer.TFPluginClient)
+	if !ok {
+		return diag.FromErr(fmt.Errorf("failed to cast meta into threefold plugin client"))
+	}
+
+	ctx = resourceLogContext(ctx, "grid_name_contract", d)
+
+	contractID, err := strconv.ParseUint(d.Id(), 10, 64)
+	if err != nil {
+		return diag.Errorf("couldn't parse name contract id with error: %v", err)
+	}
+
+	var contract subi.Contract
+	err = traceCall(ctx, "Substrate.GetContract", func() (err error) {
+		contract, err = tfPluginClient.SubstrateConn.GetContract(contractID)
+		return err
+	})
+	if errors.Is(err, substrate.ErrNotFound) || (err == nil && contract.IsDeleted()) {
+		// the name contract is no longer valid, remove it from the state so it gets recreated
+		d.SetId("")
+		return nil
+	}
+	if err != nil {
+		return diag.Diagnostics{{
+			Severity: diag.Warning,
+			Summary:  "failed to read name contract data (terraform refresh might help)",
+			Detail:   err.Error(),
+		}}
+	}
+
+	if err := d.Set("name", contract.ContractType.NameContract.Name); err != nil {
+		return diag.Errorf("couldn't set name with error: %v", err)
+	}
+
+	if err := d.Set("contract_id", int(contractID)); err != nil {
+		return diag.Errorf("couldn't set contract id with error: %v", err)
+	}
+
+	return nil
+}
+
+func resourceNameContractDelete(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	tfPluginClient, ok := meta.(*deployer.TFPluginClient)
+	if !ok {
+		return diag.FromErr(fmt.Errorf("failed to cast meta into threefold plugin client"))
+	}
+
+	ctx = resourceLogContext(ctx, "grid_name_contract", d)
+
+	contractID, err := strconv.ParseUint(d.Id(), 10, 64)
+	if err != nil {
+		return diag.Errorf("couldn't parse name contract id with error: %v", err)
+	}
+
+	err = traceCall(ctx, "Substrate.EnsureContractCanceled", func() error {
+		return tfPluginClient.SubstrateConn.EnsureContractCanceled(tfPluginClient.Identity, contractID)
+	})
+	if err != nil {
+		return diag.Errorf("couldn't cancel name contract %d with error: %v", contractID, err)
+	}
+
+	d.SetId("")
+	return nil
+}
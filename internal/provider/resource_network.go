@@ -5,13 +5,16 @@ import (
 	"context"
 	"encoding/hex"
 	"fmt"
-	"log"
+	"maps"
+	"net"
 	"regexp"
 	"slices"
 	"strconv"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/hashicorp/go-multierror"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
@@ -34,6 +37,17 @@ func resourceNetwork() *schema.Resource {
 		UpdateContext: resourceNetworkUpdate,
 		DeleteContext: resourceNetworkDelete,
 
+		Importer: &schema.ResourceImporter{
+			StateContext: resourceNetworkImport,
+		},
+
+		Timeouts: &schema.ResourceTimeout{
+			Create: schema.DefaultTimeout(20 * time.Minute),
+			Read:   schema.DefaultTimeout(20 * time.Minute),
+			Update: schema.DefaultTimeout(20 * time.Minute),
+			Delete: schema.DefaultTimeout(20 * time.Minute),
+		},
+
 		Schema: map[string]*schema.Schema{
 			"name": {
 				Type:             schema.TypeString,
@@ -241,6 +255,126 @@ func newNetwork(ctx context.Context, d *schema.ResourceData, ncPool client.NodeC
 	}, nil
 }
 
+// loadNetworkFromGrid rebuilds a network from the workloads of the twin's node contracts that were deployed with the given network name.
+func loadNetworkFromGrid(ctx context.Context, tfPluginClient *deployer.TFPluginClient, name string) (workloads.Network, error) {
+	nodeDeploymentID, deploymentData, err := nodeContractsByName(tfPluginClient, name, workloads.NetworkType, zos.NetworkLightType)
+	if err != nil {
+		return nil, err
+	}
+
+	light := true
+	znet := workloads.ZNet{
+		Name:             name,
+		SolutionType:     deploymentData.ProjectName,
+		NodesIPRange:     make(map[uint32]zos.IPNet),
+		MyceliumKeys:     make(map[uint32][]byte),
+		NodeDeploymentID: nodeDeploymentID,
+		Keys:             make(map[uint32]wgtypes.Key),
+		WGPort:           make(map[uint32]int),
+	}
+
+	for nodeID, contractID := range nodeDeploymentID {
+		nodeClient, err := tfPluginClient.NcPool.GetNodeClient(tfPluginClient.SubstrateConn, nodeID)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to get node client '%d'", nodeID)
+		}
+
+		dl, err := nodeClient.DeploymentGet(ctx, contractID)
+		if err != nil {
+			return nil, errors.Wrapf(err, "couldn't get network deployment %d from node %d", contractID, nodeID)
+		}
+
+		for _, wl := range dl.Workloads {
+			if wl.Name != name {
+				continue
+			}
+
+			var nodeNet workloads.Network
+			switch wl.Type {
+			case zos.NetworkType:
+				n, err := workloads.NewNetworkFromWorkload(wl, nodeID)
+				if err != nil {
+					return nil, errors.Wrapf(err, "couldn't load network workload from node %d", nodeID)
+				}
+
+				light = false
+				znet.IPRange = n.IPRange
+				maps.Copy(znet.Keys, n.Keys)
+				maps.Copy(znet.WGPort, n.WGPort)
+				if n.AddWGAccess {
+					znet.AddWGAccess = true
+					znet.PublicNodeID = n.PublicNodeID
+					znet.ExternalIP = n.ExternalIP
+					znet.ExternalSK = n.ExternalSK
+				}
+				nodeNet = &n
+			case zos.NetworkLightType:
+				n, err := workloads.NewNetworkLightFromWorkload(wl, nodeID)
+				if err != nil {
+					return nil, errors.Wrapf(err, "couldn't load network light workload from node %d", nodeID)
+				}
+				nodeNet = &n
+			default:
+				continue
+			}
+
+			znet.Description = wl.Description
+			znet.Nodes = append(znet.Nodes, nodeID)
+			maps.Copy(znet.NodesIPRange, nodeNet.GetNodesIPRange())
+			maps.Copy(znet.MyceliumKeys, nodeNet.GetMyceliumKeys())
+		}
+	}
+
+	if len(znet.Nodes) == 0 {
+		return nil, fmt.Errorf("couldn't find network workloads with name '%s'", name)
+	}
+	slices.Sort(znet.Nodes)
+
+	if light {
+		// light networks don't keep the network ip range on the nodes, so it's derived from the nodes' subnets
+		for _, subnet := range znet.NodesIPRange {
+			znet.IPRange = workloads.NewIPRange(net.IPNet{
+				IP:   subnet.IP.Mask(net.CIDRMask(16, 32)),
+				Mask: net.CIDRMask(16, 32),
+			})
+			break
+		}
+
+		return &workloads.ZNetLight{
+			Name:             znet.Name,
+			Description:      znet.Description,
+			Nodes:            znet.Nodes,
+			IPRange:          znet.IPRange,
+			SolutionType:     znet.SolutionType,
+			MyceliumKeys:     znet.MyceliumKeys,
+			NodesIPRange:     znet.NodesIPRange,
+			NodeDeploymentID: znet.NodeDeploymentID,
+		}, nil
+	}
+
+	if znet.AddWGAccess && znet.ExternalIP != nil {
+		nodeClient, err := tfPluginClient.NcPool.GetNodeClient(tfPluginClient.SubstrateConn, znet.PublicNodeID)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to get node client '%d'", znet.PublicNodeID)
+		}
+
+		endpoint, err := nodeClient.GetNodeEndpoint(ctx)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to get node %d endpoint", znet.PublicNodeID)
+		}
+
+		znet.AccessWGConfig = workloads.GenerateWGConfig(
+			workloads.WgIP(*znet.ExternalIP).IP.String(),
+			znet.ExternalSK.String(),
+			znet.Keys[znet.PublicNodeID].PublicKey().String(),
+			fmt.Sprintf("%s:%d", endpoint.String(), znet.WGPort[znet.PublicNodeID]),
+			znet.IPRange.String(),
+		)
+	}
+
+	return &znet, nil
+}
+
 func isZosLight(ctx context.Context, nodeID uint32, ncPool client.NodeClientGetter, sub subi.SubstrateExt) (bool, error) {
 	nodeClient, err := ncPool.GetNodeClient(sub, nodeID)
 	if err != nil {
@@ -255,7 +389,7 @@ func isZosLight(ctx context.Context, nodeID uint32, ncPool client.NodeClientGett
 	return slices.Contains(features, zos.NetworkLightType), nil
 }
 
-func storeState(d *schema.ResourceData, tfPluginClient *deployer.TFPluginClient, net workloads.Network) (errors error) {
+func storeState(ctx context.Context, d *schema.ResourceData, tfPluginClient *deployer.TFPluginClient, net workloads.Network) (errors error) {
 	nodeDeploymentID := make(map[string]interface{})
 	for node, id := range net.GetNodeDeploymentID() {
 		nodeDeploymentID[fmt.Sprintf("%d", node)] = int(id)
@@ -286,13 +420,13 @@ func storeState(d *schema.ResourceData, tfPluginClient *deployer.TFPluginClient,
 			nodes = append(nodes, node)
 		}
 	}
-	log.Printf("setting deployer object nodes: %v", nodes)
+	tflog.Debug(ctx, "setting deployer object nodes", map[string]interface{}{"nodes": nodes})
 	// update network local status
 	updateNetworkLocalState(tfPluginClient, net)
 
 	net.SetNodes(nodes)
 
-	log.Printf("storing nodes: : %v", nodes)
+	tflog.Debug(ctx, "storing network nodes", map[string]interface{}{"nodes": nodes})
 	err := d.Set("nodes", nodes)
 	if err != nil {
 		errors = multierror.Append(errors, err)
@@ -345,6 +479,9 @@ func storeState(d *schema.ResourceData, tfPluginClient *deployer.TFPluginClient,
 }
 
 func updateNetworkLocalState(tfPluginClient *deployer.TFPluginClient, net workloads.Network) {
+	subnetsLock.Lock()
+	defer subnetsLock.Unlock()
+
 	tfPluginClient.State.Networks.DeleteNetwork(net.GetName())
 	tfPluginClient.State.Networks.UpdateNetworkSubnets(net.GetName(), net.GetNodesIPRange())
 }
@@ -356,12 +493,14 @@ func resourceNetworkCreate(ctx context.Context, d *schema.ResourceData, meta int
 		return diag.FromErr(fmt.Errorf("failed to cast meta into threefold plugin client"))
 	}
 
+	ctx = resourceLogContext(ctx, "grid_network", d)
+
 	net, err := newNetwork(ctx, d, tfPluginClient.NcPool, tfPluginClient.SubstrateConn)
 	if err != nil {
 		return diag.FromErr(errors.Wrap(err, "couldn't load network data"))
 	}
 
-	err = tfPluginClient.NetworkDeployer.Deploy(ctx, net)
+	err = traceCall(ctx, "NetworkDeployer.Deploy", func() error { return tfPluginClient.NetworkDeployer.Deploy(ctx, net) })
 	if err != nil {
 		if len(net.GetNodeDeploymentID()) != 0 {
 			// failed to deploy and failed to revert, store the current state locally
@@ -371,12 +510,14 @@ func resourceNetworkCreate(ctx context.Context, d *schema.ResourceData, meta int
 		}
 	}
 
-	err = storeState(d, tfPluginClient, net)
+	err = storeState(ctx, d, tfPluginClient, net)
 	if err != nil {
 		diags = diag.FromErr(err)
 	}
 
 	d.SetId(uuid.New().String())
+
+	logNodeContracts(ctx, "network created", net.GetNodeDeploymentID())
 	return diags
 }
 
@@ -387,21 +528,25 @@ func resourceNetworkUpdate(ctx context.Context, d *schema.ResourceData, meta int
 		return diag.FromErr(fmt.Errorf("failed to cast meta into threefold plugin client"))
 	}
 
+	ctx = resourceLogContext(ctx, "grid_network", d)
+
 	net, err := newNetwork(ctx, d, tfPluginClient.NcPool, tfPluginClient.SubstrateConn)
 	if err != nil {
 		return diag.FromErr(errors.Wrap(err, "couldn't load network data"))
 	}
 
-	err = tfPluginClient.NetworkDeployer.Deploy(ctx, net)
+	err = traceCall(ctx, "NetworkDeployer.Deploy", func() error { return tfPluginClient.NetworkDeployer.Deploy(ctx, net) })
 	if err != nil {
 		diags = diag.FromErr(err)
 	}
 
-	err = storeState(d, tfPluginClient, net)
+	err = storeState(ctx, d, tfPluginClient, net)
 	if err != nil {
 		diags = diag.FromErr(err)
 	}
 
+	logNodeContracts(ctx, "network updated", net.GetNodeDeploymentID())
+
 	return diags
 }
 
@@ -412,6 +557,8 @@ func resourceNetworkRead(ctx context.Context, d *schema.ResourceData, meta inter
 		return diag.FromErr(fmt.Errorf("failed to cast meta into threefold plugin client"))
 	}
 
+	ctx = resourceLogContext(ctx, "grid_network", d)
+
 	net, err := newNetwork(ctx, d, tfPluginClient.NcPool, tfPluginClient.SubstrateConn)
 	if err != nil {
 		return diag.FromErr(errors.Wrap(err, "couldn't load network data"))
@@ -421,11 +568,13 @@ func resourceNetworkRead(ctx context.Context, d *schema.ResourceData, meta inter
 		return diag.FromErr(errors.Wrap(err, "couldn't invalidate broken attributes"))
 	}
 
-	err = storeState(d, tfPluginClient, net)
+	err = storeState(ctx, d, tfPluginClient, net)
 	if err != nil {
 		diags = diag.FromErr(err)
 	}
 
+	logNodeContracts(ctx, "network read", net.GetNodeDeploymentID())
+
 	return diags
 }
 
@@ -436,12 +585,16 @@ func resourceNetworkDelete(ctx context.Context, d *schema.ResourceData, meta int
 		return diag.FromErr(fmt.Errorf("failed to cast meta into threefold plugin client"))
 	}
 
+	ctx = resourceLogContext(ctx, "grid_network", d)
+
 	net, err := newNetwork(ctx, d, tfPluginClient.NcPool, tfPluginClient.SubstrateConn)
 	if err != nil {
 		return diag.FromErr(errors.Wrap(err, "couldn't load network data"))
 	}
 
-	err = tfPluginClient.NetworkDeployer.Cancel(ctx, net)
+	logNodeContracts(ctx, "deleting network", net.GetNodeDeploymentID())
+
+	err = traceCall(ctx, "NetworkDeployer.Cancel", func() error { return tfPluginClient.NetworkDeployer.Cancel(ctx, net) })
 	if err != nil {
 		diags = diag.FromErr(err)
 	}
@@ -449,10 +602,70 @@ func resourceNetworkDelete(ctx context.Context, d *schema.ResourceData, meta int
 	if err == nil {
 		d.SetId("")
 	} else {
-		err = storeState(d, tfPluginClient, net)
+		err = storeState(ctx, d, tfPluginClient, net)
 		if err != nil {
 			diags = diag.FromErr(err)
 		}
 	}
 	return diags
 }
+
+func resourceNetworkImport(ctx context.Context, d *schema.ResourceData, meta interface{}) ([]*schema.ResourceData, error) {
+	tfPluginClient, ok := meta.(*deployer.TFPluginClient)
+	if !ok {
+		return nil, fmt.Errorf("failed to cast meta into threefold plugin client")
+	}
+
+	ctx = resourceLogContext(ctx, "grid_network", d)
+
+	name := d.Id()
+	net, err := loadNetworkFromGrid(ctx, tfPluginClient, name)
+	if err != nil {
+		return nil, errors.Wrapf(err, "couldn't load network '%s'", name)
+	}
+
+	var description, solutionType string
+	switch n := net.(type) {
+	case *workloads.ZNet:
+		description, solutionType = n.Description, n.SolutionType
+	case *workloads.ZNetLight:
+		description, solutionType = n.Description, n.SolutionType
+	}
+
+	myceliumKeys := make(map[string]interface{})
+	for node, key := range net.GetMyceliumKeys() {
+		myceliumKeys[fmt.Sprintf("%d", node)] = hex.EncodeToString(key)
+	}
+
+	var errs error
+	if err := d.Set("name", net.GetName()); err != nil {
+		errs = multierror.Append(errs, err)
+	}
+
+	if err := d.Set("description", description); err != nil {
+		errs = multierror.Append(errs, err)
+	}
+
+	if err := d.Set("solution_type", solutionType); err != nil {
+		errs = multierror.Append(errs, err)
+	}
+
+	if err := d.Set("add_wg_access", net.GetAddWGAccess()); err != nil {
+		errs = multierror.Append(errs, err)
+	}
+
+	if err := d.Set("mycelium_keys", myceliumKeys); err != nil {
+		errs = multierror.Append(errs, err)
+	}
+
+	if err := storeState(ctx, d, tfPluginClient, net); err != nil {
+		errs = multierror.Append(errs, err)
+	}
+
+	if errs != nil {
+		return nil, errors.Wrap(errs, "couldn't set network data to the resource")
+	}
+
+	d.SetId(uuid.New().String())
+	return []*schema.ResourceData{d}, nil
+}
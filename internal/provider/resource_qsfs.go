@@ -0,0 +1,688 @@
+// Package provider is the terraform provider
+package provider
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
+	"github.com/threefoldtech/tfgrid-sdk-go/grid-client/deployer"
+	"github.com/threefoldtech/tfgrid-sdk-go/grid-client/workloads"
+)
+
+func resourceQSFS() *schema.Resource {
+	return &schema.Resource{
+		// This description is used by the documentation generator and the language server.
+		Description:   "Resource for deploying a qsfs (quantum storage file system) with its own ZDB backends, and mounting it into a vm. A user mode ZDB is deployed on each of the metadata nodes, and a seq mode ZDB is deployed on each node of every group. The ZDBs are named after their group name and node, so a group could be added, removed or have its nodes replaced (e.g. when a backend node dies) in place without touching the data of the other ZDBs. Since a qsfs could only be mounted into a vm of the same deployment, the resource deploys the vm mounting it. To mount the qsfs into a named vm of a `grid_deployment` instead, omit the `vm` block and use the `metadata_backends` and `group_backends` in the `qsfs` block of the deployment. You can read more about qsfs [here](https://github.com/threefoldtech/quantum-storage).",
+		CreateContext: resourceQSFSCreate,
+		ReadContext:   resourceQSFSRead,
+		UpdateContext: resourceQSFSUpdate,
+		DeleteContext: resourceQSFSDelete,
+		CustomizeDiff: resourceQSFSCustomizeDiff,
+
+		Timeouts: &schema.ResourceTimeout{
+			Create: schema.DefaultTimeout(45 * time.Minute),
+			Read:   schema.DefaultTimeout(20 * time.Minute),
+			Update: schema.DefaultTimeout(45 * time.Minute),
+			Delete: schema.DefaultTimeout(20 * time.Minute),
+		},
+
+		Schema: map[string]*schema.Schema{
+			"name": {
+				Type:             schema.TypeString,
+				Required:         true,
+				ForceNew:         true,
+				Description:      "Qsfs workload name. This is also used as the solution name for the created contracts. Must contain only alphanumeric and underscore characters.",
+				ValidateDiagFunc: validation.ToDiagFunc(validation.StringMatch(regexp.MustCompile(nameValidationRegex), nameValidationErrorMessage)),
+			},
+			"node": {
+				Type:         schema.TypeInt,
+				Optional:     true,
+				ForceNew:     true,
+				RequiredWith: []string{"vm"},
+				Description:  "Node id to place the qsfs and the vm mounting it on. Required with the vm.",
+			},
+			"network_name": {
+				Type:         schema.TypeString,
+				Optional:     true,
+				ForceNew:     true,
+				RequiredWith: []string{"vm"},
+				Description:  "Network name of the deployed network resource to connect the vm to. Required with the vm.",
+			},
+			"solution_type": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				Default:     "",
+				Description: "Solution type for created contracts to be consistent across threefold tooling.",
+			},
+			"description": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				Default:     "",
+				Description: "Description of the qsfs workload.",
+			},
+			"cache": {
+				Type:        schema.TypeInt,
+				Required:    true,
+				ForceNew:    true,
+				Description: "The size of the fuse mountpoint on the node in MBs (holds qsfs local data before pushing).",
+			},
+			"minimal_shards": {
+				Type:        schema.TypeInt,
+				Required:    true,
+				Description: "The minimum amount of shards which are needed to recover the original data.",
+			},
+			"expected_shards": {
+				Type:        schema.TypeInt,
+				Required:    true,
+				Description: "The amount of shards which are generated when the data is encoded. Each group must have at least this amount of nodes.",
+			},
+			"redundant_groups": {
+				Type:        schema.TypeInt,
+				Optional:    true,
+				Default:     0,
+				Description: "The amount of groups which one should be able to loose while still being able to recover the original data.",
+			},
+			"redundant_nodes": {
+				Type:        schema.TypeInt,
+				Optional:    true,
+				Default:     0,
+				Description: "The amount of nodes that can be lost in every group while still being able to recover the original data.",
+			},
+			"max_zdb_data_dir_size": {
+				Type:        schema.TypeInt,
+				Required:    true,
+				Description: "Maximum size of the data dir in MiB, if this is set and the sum of the file sizes in the data dir gets higher than this value, the least used, already encoded file will be removed.",
+			},
+			"encryption_algorithm": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				Default:     "AES",
+				ForceNew:    true,
+				Description: "configuration to use for the encryption stage of the data and metadata. Currently only AES is supported.",
+			},
+			"encryption_key": {
+				Type:        schema.TypeString,
+				Required:    true,
+				Sensitive:   true,
+				ForceNew:    true,
+				Description: "64 long hex encoded encryption key (e.g. 0000000000000000000000000000000000000000000000000000000000000000) used for the data and metadata.",
+			},
+			"compression_algorithm": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				Default:     "snappy",
+				ForceNew:    true,
+				Description: "configuration to use for the compression stage. Currently only snappy is supported.",
+			},
+			"metadata_prefix": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				Default:     "",
+				ForceNew:    true,
+				Description: "Data stored on the remote metadata is prefixed with. Defaults to the qsfs name.",
+			},
+			"metadata_nodes": {
+				Type:        schema.TypeList,
+				Required:    true,
+				MinItems:    1,
+				Description: "List of distinct node ids to deploy the metadata ZDBs on, a user mode ZDB is deployed on each node.",
+				Elem:        &schema.Schema{Type: schema.TypeInt},
+			},
+			"metadata_zdb_size": {
+				Type:        schema.TypeInt,
+				Optional:    true,
+				Default:     1,
+				Description: "Size of each metadata ZDB in GBs.",
+			},
+			"groups": {
+				Type:        schema.TypeList,
+				Required:    true,
+				MinItems:    1,
+				Description: "The backend groups to write the data to. A seq mode ZDB is deployed on each node of the group.",
+				Elem: &schema.Resource{
+					Schema: map[string]*schema.Schema{
+						"name": {
+							Type:             schema.TypeString,
+							Required:         true,
+							Description:      "Group name, unique within the qsfs. The group ZDBs are named after it, so it must not be changed. Must contain only alphanumeric and underscore characters.",
+							ValidateDiagFunc: validation.ToDiagFunc(validation.StringMatch(regexp.MustCompile(nameValidationRegex), nameValidationErrorMessage)),
+						},
+						"nodes": {
+							Type:        schema.TypeList,
+							Required:    true,
+							MinItems:    1,
+							Description: "List of distinct node ids to deploy the group data ZDBs on. A dead node could be replaced by another one.",
+							Elem:        &schema.Schema{Type: schema.TypeInt},
+						},
+					},
+				},
+			},
+			"zdb_size": {
+				Type:        schema.TypeInt,
+				Required:    true,
+				Description: "Size of each data ZDB in GBs.",
+			},
+			"zdb_password": {
+				Type:        schema.TypeString,
+				Required:    true,
+				Sensitive:   true,
+				Description: "Password of the deployed ZDBs.",
+			},
+			"vm": {
+				Type:        schema.TypeList,
+				Optional:    true,
+				MaxItems:    1,
+				Description: "The vm (ZMachine) to mount the qsfs into. If omitted, only the ZDBs are deployed. Removing it cancels the qsfs deployment and keeps the ZDBs, as long as the `node` is kept.",
+				Elem: &schema.Resource{
+					Schema: map[string]*schema.Schema{
+						"name": {
+							Type:             schema.TypeString,
+							Required:         true,
+							ForceNew:         true,
+							Description:      "Vm (zmachine) workload name. Must contain only alphanumeric and underscore characters.",
+							ValidateDiagFunc: validation.ToDiagFunc(validation.StringMatch(regexp.MustCompile(nameValidationRegex), nameValidationErrorMessage)),
+						},
+						"mount_point": {
+							Type:        schema.TypeString,
+							Required:    true,
+							ForceNew:    true,
+							Description: "Directory to mount the qsfs on inside the ZMachine.",
+						},
+						"flist": {
+							Type:        schema.TypeString,
+							Required:    true,
+							ForceNew:    true,
+							Description: "Flist used on this vm, e.g. https://hub.grid.tf/tf-official-apps/base:latest.flist. All flists could be found in `https://hub.grid.tf/`.",
+						},
+						"flist_checksum": {
+							Type:        schema.TypeString,
+							Optional:    true,
+							ForceNew:    true,
+							Description: "if present, the flist is rejected if it has a different hash.",
+						},
+						"publicip": {
+							Type:        schema.TypeBool,
+							Optional:    true,
+							ForceNew:    true,
+							Description: "Flag to enable public ipv4 reservation.",
+						},
+						"publicip6": {
+							Type:        schema.TypeBool,
+							Optional:    true,
+							ForceNew:    true,
+							Description: "Flag to enable public ipv6 reservation.",
+						},
+						"computedip": {
+							Type:        schema.TypeString,
+							Computed:    true,
+							Description: "The reserved public ipv4 if any.",
+						},
+						"computedip6": {
+							Type:        schema.TypeString,
+							Computed:    true,
+							Description: "The reserved public ipv6 if any.",
+						},
+						"ip": {
+							Type:             schema.TypeString,
+							Optional:         true,
+							Computed:         true,
+							ForceNew:         true,
+							Description:      "The private wireguard IP of the vm.",
+							ValidateDiagFunc: validation.ToDiagFunc(validation.IsIPAddress),
+						},
+						"mycelium_ip_seed": {
+							Type:        schema.TypeString,
+							Optional:    true,
+							ForceNew:    true,
+							Description: "Mycelium seed used to get the same mycelium ip for the vm. Hex encoded 6 bytes (e.g. b60f2b7ec39c).",
+						},
+						"mycelium_ip": {
+							Type:        schema.TypeString,
+							Computed:    true,
+							Description: "The allocated mycelium IP.",
+						},
+						"cpu": {
+							Type:             schema.TypeInt,
+							Optional:         true,
+							Default:          1,
+							ForceNew:         true,
+							Description:      "Number of virtual CPUs. Must be between 1 and 32.",
+							ValidateDiagFunc: validation.ToDiagFunc(validation.IntBetween(1, 32)),
+						},
+						"description": {
+							Type:        schema.TypeString,
+							Optional:    true,
+							Default:     "",
+							ForceNew:    true,
+							Description: "Description of the vm.",
+						},
+						"memory": {
+							Type:             schema.TypeInt,
+							Optional:         true,
+							ForceNew:         true,
+							Description:      "Memory size in MB. Must be between 256MBs and 262144MBs (256GBs).",
+							ValidateDiagFunc: validation.ToDiagFunc(validation.IntBetween(256, 256*1024)),
+						},
+						"rootfs_size": {
+							Type:             schema.TypeInt,
+							Optional:         true,
+							ForceNew:         true,
+							Description:      "Root file system size in MB. Must be between 1024MBs and 10485760MBs (10TBs).",
+							ValidateDiagFunc: validation.ToDiagFunc(validation.IntBetween(1024, 10*1024*1024)),
+						},
+						"entrypoint": {
+							Type:        schema.TypeString,
+							Optional:    true,
+							ForceNew:    true,
+							Description: "Command to execute as the ZMachine init.",
+						},
+						"env_vars": {
+							Type:        schema.TypeMap,
+							Optional:    true,
+							ForceNew:    true,
+							Elem:        &schema.Schema{Type: schema.TypeString},
+							Description: "Environment variables to pass to the zmachine.",
+						},
+						"planetary": {
+							Type:        schema.TypeBool,
+							Optional:    true,
+							Default:     false,
+							ForceNew:    true,
+							Description: "Flag to enable Yggdrasil IP allocation.",
+						},
+						"planetary_ip": {
+							Type:        schema.TypeString,
+							Computed:    true,
+							Description: "The allocated Yggdrasil IP.",
+						},
+						"console_url": {
+							Type:        schema.TypeString,
+							Computed:    true,
+							Description: "The url to access the vm via cloud console on private interface using wireguard.",
+						},
+					},
+				},
+			},
+			"ip_range": {
+				Type:        schema.TypeString,
+				Computed:    true,
+				Description: "IP range of the node for the wireguard network (e.g. 10.1.2.0/24). Has to have a subnet mask of 24.",
+			},
+			"metrics_endpoint": {
+				Type:        schema.TypeString,
+				Computed:    true,
+				Description: "QSFS exposed metrics endpoint.",
+			},
+			"deployment_id": {
+				Type:        schema.TypeInt,
+				Computed:    true,
+				Description: "Contract id of the deployment holding the qsfs and the vm.",
+			},
+			"metadata_backends": {
+				Type:        schema.TypeList,
+				Computed:    true,
+				Description: "The backends of the metadata ZDBs, in the order of the metadata nodes.",
+				Elem:        qsfsBackendResource(),
+			},
+			"group_backends": {
+				Type:        schema.TypeList,
+				Computed:    true,
+				Description: "The backends of the data ZDBs of each group, in the order of the groups.",
+				Elem: &schema.Resource{
+					Schema: map[string]*schema.Schema{
+						"name": {
+							Type:        schema.TypeString,
+							Computed:    true,
+							Description: "Group name.",
+						},
+						"backends": {
+							Type:        schema.TypeList,
+							Computed:    true,
+							Description: "The backends of the group ZDBs, in the order of the group nodes.",
+							Elem:        qsfsBackendResource(),
+						},
+					},
+				},
+			},
+			"zdb_deployment_ids": {
+				Type:        schema.TypeMap,
+				Computed:    true,
+				Elem:        &schema.Schema{Type: schema.TypeInt},
+				Description: "Mapping from each backend node to the contract id of its ZDBs deployment.",
+			},
+		},
+	}
+}
+
+// qsfsBackendResource is the schema of a zdb backend of the qsfs
+func qsfsBackendResource() *schema.Resource {
+	return &schema.Resource{
+		Schema: map[string]*schema.Schema{
+			"address": {
+				Type:        schema.TypeString,
+				Computed:    true,
+				Description: "Address of the ZDB in the format [ip]:port.",
+			},
+			"namespace": {
+				Type:        schema.TypeString,
+				Computed:    true,
+				Description: "ZDB namespace.",
+			},
+			"password": {
+				Type:        schema.TypeString,
+				Computed:    true,
+				Sensitive:   true,
+				Description: "ZDB password.",
+			},
+		},
+	}
+}
+
+// resourceQSFSCustomizeDiff validates the backends, and plans a redeployment of the zdbs or the qsfs deployments that are gone from the grid
+func resourceQSFSCustomizeDiff(ctx context.Context, d *schema.ResourceDiff, meta interface{}) error {
+	metadataNodes := qsfsMetadataNodes(d.Get("metadata_nodes").([]interface{}))
+	groups := qsfsGroups(d.Get("groups").([]interface{}))
+	if err := validateQSFSBackends(metadataNodes, groups); err != nil {
+		return err
+	}
+
+	if d.Id() == "" {
+		return nil
+	}
+
+	if d.HasChanges("metadata_nodes", "groups") {
+		if err := d.SetNewComputed("metadata_backends"); err != nil {
+			return err
+		}
+		if err := d.SetNewComputed("group_backends"); err != nil {
+			return err
+		}
+	}
+
+	zdbDeploymentIDs := d.Get("zdb_deployment_ids").(map[string]interface{})
+	for _, node := range qsfsBackendNodes(metadataNodes, groups) {
+		if _, ok := zdbDeploymentIDs[fmt.Sprint(node)]; !ok {
+			return d.SetNewComputed("zdb_deployment_ids")
+		}
+	}
+
+	if qsfsHasVM(d) && d.Get("deployment_id").(int) == 0 {
+		return d.SetNewComputed("deployment_id")
+	}
+
+	return nil
+}
+
+// deployQSFS deploys the zdb backends of the qsfs, then deploys the qsfs and its vm if configured, and finally cancels the zdbs
+// deployments on nodes that are no longer configured to host backends.
+// The contracts deployed so far are stored in the state even if the deployment fails.
+func deployQSFS(ctx context.Context, d *schema.ResourceData, tfPluginClient *deployer.TFPluginClient) (diags diag.Diagnostics) {
+	zdbDls, stale, err := newQSFSZDBDeploymentsFromSchema(d)
+	if err != nil {
+		return diag.Errorf("couldn't load qsfs zdbs data with error: %v", err)
+	}
+
+	storeContracts := func(dl *workloads.Deployment) diag.Diagnostics {
+		if err := syncContractsQSFS(d, dl, qsfsZDBContracts(zdbDls, stale)); err != nil {
+			return append(diags, diag.Errorf("couldn't set qsfs data to the resource with error: %v", err)...)
+		}
+		return diags
+	}
+
+	nodes := make([]uint32, 0, len(zdbDls))
+	for nodeID := range zdbDls {
+		nodes = append(nodes, nodeID)
+	}
+	slices.Sort(nodes)
+
+	for _, nodeID := range nodes {
+		dl := zdbDls[nodeID]
+		if err := traceCall(ctx, "DeploymentDeployer.Deploy", func() error { return tfPluginClient.DeploymentDeployer.Deploy(ctx, dl) }); err != nil {
+			diags = diag.Errorf("couldn't deploy qsfs zdbs on node %d (if the node is down, replace it in the qsfs configuration) with error: %v", nodeID, err)
+			return storeContracts(nil)
+		}
+
+		if err := traceCall(ctx, "DeploymentDeployer.Sync", func() error { return tfPluginClient.DeploymentDeployer.Sync(ctx, dl) }); err != nil {
+			diags = diag.Errorf("couldn't sync qsfs zdbs on node %d with error: %v", nodeID, err)
+			return storeContracts(nil)
+		}
+	}
+
+	if err := syncQSFSBackends(d, zdbDls); err != nil {
+		diags = diag.Errorf("couldn't set qsfs backends to the resource with error: %v", err)
+		return storeContracts(nil)
+	}
+
+	var dl *workloads.Deployment
+	if qsfsHasVM(d) {
+		dl, err = newQSFSDeploymentFromSchema(ctx, d, tfPluginClient, zdbDls)
+		if err != nil {
+			diags = diag.Errorf("couldn't load qsfs data with error: %v", err)
+			return storeContracts(nil)
+		}
+
+		if err := ensureNetworkSubnets(ctx, tfPluginClient, dl.NetworkName, dl.NodeID); err != nil {
+			diags = diag.FromErr(err)
+			return storeContracts(nil)
+		}
+
+		err = traceCall(ctx, "DeploymentDeployer.Deploy", func() error { return tfPluginClient.DeploymentDeployer.Deploy(ctx, dl) })
+		if err != nil {
+			// failed to deploy (e.g. timed out), store the current state to keep track of the contracts
+			diags = diag.Errorf("couldn't deploy qsfs with error: %v", err)
+			return storeContracts(dl)
+		}
+
+		if err := traceCall(ctx, "DeploymentDeployer.Sync", func() error { return tfPluginClient.DeploymentDeployer.Sync(ctx, dl) }); err != nil {
+			diags = diag.Errorf("couldn't sync qsfs with error: %v", err)
+			return storeContracts(dl)
+		}
+	} else if contractID := uint64(d.Get("deployment_id").(int)); contractID != 0 {
+		// the vm was removed from the configuration, the qsfs is only kept in its zdbs
+		nodeID := uint32(d.Get("node").(int))
+		oldDl := &workloads.Deployment{
+			Name:             d.Get("name").(string),
+			NodeID:           nodeID,
+			ContractID:       contractID,
+			NodeDeploymentID: map[uint32]uint64{nodeID: contractID},
+		}
+
+		if err := traceCall(ctx, "DeploymentDeployer.Cancel", func() error { return tfPluginClient.DeploymentDeployer.Cancel(ctx, oldDl) }); err != nil {
+			diags = diag.Errorf("couldn't cancel qsfs with error: %v", err)
+			return storeContracts(nil)
+		}
+
+		if err := d.Set("deployment_id", 0); err != nil {
+			diags = diag.Errorf("couldn't set qsfs data to the resource with error: %v", err)
+			return storeContracts(nil)
+		}
+	}
+
+	for nodeID, contractID := range stale {
+		staleDl := &workloads.Deployment{
+			Name:             qsfsZDBDeploymentName(d.Get("name").(string)),
+			NodeID:           nodeID,
+			ContractID:       contractID,
+			NodeDeploymentID: map[uint32]uint64{nodeID: contractID},
+		}
+
+		if err := traceCall(ctx, "DeploymentDeployer.Cancel", func() error { return tfPluginClient.DeploymentDeployer.Cancel(ctx, staleDl) }); err != nil {
+			diags = append(diags, diag.Errorf("couldn't cancel qsfs zdbs on node %d with error: %v", nodeID, err)...)
+			continue
+		}
+		delete(stale, nodeID)
+	}
+
+	return storeContracts(dl)
+}
+
+func resourceQSFSCreate(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	tfPluginClient, ok := meta.(*deployer.TFPluginClient)
+	if !ok {
+		return diag.FromErr(fmt.Errorf("failed to cast meta into threefold plugin client"))
+	}
+
+	ctx = resourceLogContext(ctx, "grid_qsfs", d)
+
+	diags := deployQSFS(ctx, d, tfPluginClient)
+	if d.Get("deployment_id").(int) == 0 && len(d.Get("zdb_deployment_ids").(map[string]interface{})) == 0 {
+		return diags
+	}
+
+	d.SetId(uuid.New().String())
+
+	zdbContracts, err := parseNodeContracts(d.Get("zdb_deployment_ids").(map[string]interface{}))
+	if err == nil {
+		logNodeContracts(ctx, "qsfs zdbs created", zdbContracts)
+	}
+	logNodeContracts(ctx, "qsfs created", map[uint32]uint64{uint32(d.Get("node").(int)): uint64(d.Get("deployment_id").(int))})
+
+	return diags
+}
+
+func resourceQSFSRead(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	var diags diag.Diagnostics
+	tfPluginClient, ok := meta.(*deployer.TFPluginClient)
+	if !ok {
+		return diag.FromErr(fmt.Errorf("failed to cast meta into threefold plugin client"))
+	}
+
+	ctx = resourceLogContext(ctx, "grid_qsfs", d)
+
+	zdbDls, stale, err := newQSFSZDBDeploymentsFromSchema(d)
+	if err != nil {
+		return diag.Errorf("couldn't load qsfs zdbs data with error: %v", err)
+	}
+
+	for nodeID, dl := range zdbDls {
+		if dl.ContractID == 0 {
+			continue
+		}
+
+		if err := traceCall(ctx, "DeploymentDeployer.Sync", func() error { return tfPluginClient.DeploymentDeployer.Sync(ctx, dl) }); err != nil {
+			diags = append(diags, diag.Diagnostic{
+				Severity: diag.Warning,
+				Summary:  fmt.Sprintf("failed to read qsfs zdbs on node %d (if the node is down, replace it in the qsfs configuration)", nodeID),
+				Detail:   err.Error(),
+			})
+		}
+	}
+
+	if err := syncQSFSBackends(d, zdbDls); err != nil {
+		diags = append(diags, diag.Diagnostic{
+			Severity: diag.Warning,
+			Summary:  "failed to read qsfs backends (if a backend node is down, replace it in the qsfs configuration)",
+			Detail:   err.Error(),
+		})
+	}
+
+	var dl *workloads.Deployment
+	if qsfsHasVM(d) {
+		dl, err = newQSFSDeploymentFromSchema(ctx, d, tfPluginClient, nil)
+		if err != nil {
+			return diag.Errorf("couldn't load qsfs data with error: %v", err)
+		}
+
+		recordNetworkSubnets(tfPluginClient, dl.NetworkName, map[uint32]string{dl.NodeID: dl.IPrange})
+
+		if err := traceCall(ctx, "DeploymentDeployer.Sync", func() error { return tfPluginClient.DeploymentDeployer.Sync(ctx, dl) }); err != nil {
+			diags = append(diags, diag.Diagnostic{
+				Severity: diag.Warning,
+				Summary:  "failed to read qsfs data (terraform refresh might help)",
+				Detail:   err.Error(),
+			})
+			dl = nil
+		}
+	}
+
+	if err := syncContractsQSFS(d, dl, qsfsZDBContracts(zdbDls, stale)); err != nil {
+		return append(diags, diag.Errorf("couldn't set qsfs data to the resource with error: %v", err)...)
+	}
+
+	logNodeContracts(ctx, "qsfs read", map[uint32]uint64{uint32(d.Get("node").(int)): uint64(d.Get("deployment_id").(int))})
+
+	return diags
+}
+
+func resourceQSFSUpdate(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	tfPluginClient, ok := meta.(*deployer.TFPluginClient)
+	if !ok {
+		return diag.FromErr(fmt.Errorf("failed to cast meta into threefold plugin client"))
+	}
+
+	ctx = resourceLogContext(ctx, "grid_qsfs", d)
+
+	diags := deployQSFS(ctx, d, tfPluginClient)
+
+	logNodeContracts(ctx, "qsfs updated", map[uint32]uint64{uint32(d.Get("node").(int)): uint64(d.Get("deployment_id").(int))})
+
+	return diags
+}
+
+func resourceQSFSDelete(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	var diags diag.Diagnostics
+	tfPluginClient, ok := meta.(*deployer.TFPluginClient)
+	if !ok {
+		return diag.FromErr(fmt.Errorf("failed to cast meta into threefold plugin client"))
+	}
+
+	ctx = resourceLogContext(ctx, "grid_qsfs", d)
+
+	zdbDls, stale, err := newQSFSZDBDeploymentsFromSchema(d)
+	if err != nil {
+		return diag.Errorf("couldn't load qsfs zdbs data with error: %v", err)
+	}
+
+	var dl *workloads.Deployment
+	if qsfsHasVM(d) {
+		dl, err = newQSFSDeploymentFromSchema(ctx, d, tfPluginClient, nil)
+		if err != nil {
+			return diag.Errorf("couldn't load qsfs data with error: %v", err)
+		}
+
+		logNodeContracts(ctx, "deleting qsfs", map[uint32]uint64{dl.NodeID: dl.ContractID})
+
+		if dl.ContractID != 0 {
+			if err := traceCall(ctx, "DeploymentDeployer.Cancel", func() error { return tfPluginClient.DeploymentDeployer.Cancel(ctx, dl) }); err != nil {
+				return diag.Errorf("couldn't cancel qsfs with error: %v", err)
+			}
+		}
+	}
+
+	zdbContracts := qsfsZDBContracts(zdbDls, stale)
+	logNodeContracts(ctx, "deleting qsfs zdbs", zdbContracts)
+
+	for nodeID, contractID := range zdbContracts {
+		zdbDl := &workloads.Deployment{
+			Name:             qsfsZDBDeploymentName(d.Get("name").(string)),
+			NodeID:           nodeID,
+			ContractID:       contractID,
+			NodeDeploymentID: map[uint32]uint64{nodeID: contractID},
+		}
+
+		if err := traceCall(ctx, "DeploymentDeployer.Cancel", func() error { return tfPluginClient.DeploymentDeployer.Cancel(ctx, zdbDl) }); err != nil {
+			diags = append(diags, diag.Errorf("couldn't cancel qsfs zdbs on node %d with error: %v", nodeID, err)...)
+			continue
+		}
+		delete(zdbContracts, nodeID)
+	}
+
+	if diags.HasError() {
+		// keep track of the contracts that couldn't be canceled
+		if err := syncContractsQSFS(d, dl, zdbContracts); err != nil {
+			diags = append(diags, diag.Errorf("couldn't set qsfs data to the resource with error: %v", err)...)
+		}
+		return diags
+	}
+
+	d.SetId("")
+
+	return diags
+}
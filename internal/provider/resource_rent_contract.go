@@ -0,0 +1,239 @@
+// Package provider is the terraform provider
+package provider
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/pkg/errors"
+	substrate "github.com/threefoldtech/tfchain/clients/tfchain-client-go"
+	"github.com/threefoldtech/tfgrid-sdk-go/grid-client/deployer"
+	"github.com/threefoldtech/tfgrid-sdk-go/grid-client/subi"
+)
+
+func resourceRentContract() *schema.Resource {
+	return &schema.Resource{
+		// This description is used by the documentation generator and the language server.
+		Description:   "Resource for renting a whole node (dedicated node) using a tfchain rent contract. Only the renter could deploy on a rented node.",
+		CreateContext: resourceRentContractCreate,
+		ReadContext:   resourceRentContractRead,
+		UpdateContext: resourceRentContractUpdate,
+		DeleteContext: resourceRentContractDelete,
+
+		Timeouts: &schema.ResourceTimeout{
+			Create: schema.DefaultTimeout(10 * time.Minute),
+			Read:   schema.DefaultTimeout(10 * time.Minute),
+			Update: schema.DefaultTimeout(10 * time.Minute),
+			Delete: schema.DefaultTimeout(10 * time.Minute),
+		},
+
+		Schema: map[string]*schema.Schema{
+			"node": {
+				Type:        schema.TypeInt,
+				Required:    true,
+				ForceNew:    true,
+				Description: "Node id to rent. The node must be rentable.",
+			},
+			"solution_provider": {
+				Type:        schema.TypeInt,
+				Optional:    true,
+				ForceNew:    true,
+				Description: "Solution provider ID for the rent contract which allows the creator of the solution to gain a percentage of the rewards.",
+			},
+			"force_destroy": {
+				Type:        schema.TypeBool,
+				Optional:    true,
+				Default:     false,
+				Description: "Cancel the active deployments contracts of this twin on the node when destroying the rent contract. If false, destroying the rent contract fails while there are deployments on the node. Contracts of other twins are never canceled.",
+			},
+			"contract_id": {
+				Type:        schema.TypeInt,
+				Computed:    true,
+				Description: "Rent contract id.",
+			},
+			"billing_rate": {
+				Type:        schema.TypeFloat,
+				Computed:    true,
+				Description: "Monthly cost in TFT of renting the node, with the dedicated nodes discount and the discount package of the twin balance applied.",
+			},
+		},
+	}
+}
+
+// syncContractsRentContract updates the terraform local state with the rent contract data
+func syncContractsRentContract(ctx context.Context, d *schema.ResourceData, tfPluginClient *deployer.TFPluginClient, nodeID uint32, contractID uint64) diag.Diagnostics {
+	var diags diag.Diagnostics
+
+	if err := d.Set("contract_id", int(contractID)); err != nil {
+		return diag.Errorf("couldn't set contract id with error: %v", err)
+	}
+
+	if err := d.Set("node", int(nodeID)); err != nil {
+		return diag.Errorf("couldn't set node with error: %v", err)
+	}
+
+	billingRate, err := nodeRentBillingRate(ctx, tfPluginClient, nodeID)
+	if err != nil {
+		diags = append(diags, diag.Diagnostic{
+			Severity: diag.Warning,
+			Summary:  "failed to compute the rent contract billing rate",
+			Detail:   err.Error(),
+		})
+		return diags
+	}
+
+	if err := d.Set("billing_rate", billingRate); err != nil {
+		return append(diags, diag.Errorf("couldn't set billing rate with error: %v", err)...)
+	}
+
+	return diags
+}
+
+func resourceRentContractCreate(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	tfPluginClient, ok := meta.(*deployer.TFPluginClient)
+	if !ok {
+		return diag.FromErr(fmt.Errorf("failed to cast meta into threefold plugin client"))
+	}
+
+	ctx = resourceLogContext(ctx, "grid_rent_contract", d)
+
+	nodeID := uint32(d.Get("node").(int))
+
+	solutionProviderVal := uint64(d.Get("solution_provider").(int))
+	var solutionProvider *uint64
+	if solutionProviderVal != 0 {
+		solutionProvider = &solutionProviderVal
+	}
+
+	sub, retrying, err := substrateImpl(tfPluginClient.SubstrateConn)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	var contractID uint64
+	err = traceCall(ctx, "Substrate.CreateRentContract", func() (err error) {
+		contractID, err = retrying.createRentContract(sub, tfPluginClient.Identity, nodeID, solutionProvider)
+		return err
+	})
+	if err != nil {
+		return diag.Errorf("couldn't create rent contract on node %d with error: %v", nodeID, err)
+	}
+
+	d.SetId(fmt.Sprint(contractID))
+
+	logNodeContracts(ctx, "rent contract created", map[uint32]uint64{nodeID: contractID})
+
+	return syncContractsRentContract(ctx, d, tfPluginClient, nodeID, contractID)
+}
+
+func resourceRentContractRead(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	tfPluginClient, ok := meta.(*deployer.TFPluginClient)
+	if !ok {
+		return diag.FromErr(fmt.Errorf("failed to cast meta into threefold plugin client"))
+	}
+
+	ctx = resourceLogContext(ctx, "grid_rent_contract", d)
+
+	contractID, err := strconv.ParseUint(d.Id(), 10, 64)
+	if err != nil {
+		return diag.Errorf("couldn't parse rent contract id with error: %v", err)
+	}
+
+	var contract subi.Contract
+	err = traceCall(ctx, "Substrate.GetContract", func() (err error) {
+		contract, err = tfPluginClient.SubstrateConn.GetContract(contractID)
+		return err
+	})
+	if errors.Is(err, substrate.ErrNotFound) || (err == nil && contract.IsDeleted()) {
+		// the rent contract is no longer valid, remove it from the state so it gets recreated
+		d.SetId("")
+		return nil
+	}
+	if err != nil {
+		return diag.Diagnostics{{
+			Severity: diag.Warning,
+			Summary:  "failed to read rent contract data (terraform refresh might help)",
+			Detail:   err.Error(),
+		}}
+	}
+
+	nodeID := uint32(contract.ContractType.RentContract.Node)
+
+	logNodeContracts(ctx, "rent contract read", map[uint32]uint64{nodeID: contractID})
+
+	return syncContractsRentContract(ctx, d, tfPluginClient, nodeID, contractID)
+}
+
+func resourceRentContractUpdate(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	// only force_destroy could be updated, and it's only used on destroy
+	return resourceRentContractRead(ctx, d, meta)
+}
+
+func resourceRentContractDelete(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	tfPluginClient, ok := meta.(*deployer.TFPluginClient)
+	if !ok {
+		return diag.FromErr(fmt.Errorf("failed to cast meta into threefold plugin client"))
+	}
+
+	ctx = resourceLogContext(ctx, "grid_rent_contract", d)
+
+	nodeID := uint32(d.Get("node").(int))
+	contractID, err := strconv.ParseUint(d.Id(), 10, 64)
+	if err != nil {
+		return diag.Errorf("couldn't parse rent contract id with error: %v", err)
+	}
+
+	var ownContracts, otherContracts []uint64
+	err = traceCall(ctx, "Substrate.GetNodeContracts", func() (err error) {
+		ownContracts, otherContracts, err = nodeActiveContracts(tfPluginClient, nodeID, tfPluginClient.TwinID)
+		return err
+	})
+	if err != nil {
+		return diag.Errorf("couldn't list node %d contracts with error: %v", nodeID, err)
+	}
+
+	if len(otherContracts) != 0 {
+		return diag.Errorf("couldn't cancel rent contract %d, node %d still has active deployments with contracts %v of other twins", contractID, nodeID, otherContracts)
+	}
+
+	var diags diag.Diagnostics
+	if len(ownContracts) != 0 {
+		if !d.Get("force_destroy").(bool) {
+			return diag.Errorf("couldn't cancel rent contract %d, node %d still has active deployments with contracts %v. Destroy them first or set force_destroy", contractID, nodeID, ownContracts)
+		}
+
+		err = traceCall(ctx, "Substrate.BatchCancelContract", func() error {
+			return tfPluginClient.SubstrateConn.BatchCancelContract(tfPluginClient.Identity, ownContracts)
+		})
+		if err != nil {
+			return diag.Errorf("couldn't cancel node %d contracts %v with error: %v", nodeID, ownContracts, err)
+		}
+
+		tflog.Info(ctx, "canceled node contracts before the rent contract", map[string]interface{}{
+			"node_id":      nodeID,
+			"contract_ids": ownContracts,
+		})
+		diags = append(diags, diag.Diagnostic{
+			Severity: diag.Warning,
+			Summary:  fmt.Sprintf("canceled the active deployments contracts %v on node %d", ownContracts, nodeID),
+			Detail:   "force_destroy is set, so the deployments on the rented node were canceled with the rent contract",
+		})
+	}
+
+	logNodeContracts(ctx, "deleting rent contract", map[uint32]uint64{nodeID: contractID})
+
+	err = traceCall(ctx, "Substrate.EnsureContractCanceled", func() error {
+		return tfPluginClient.SubstrateConn.EnsureContractCanceled(tfPluginClient.Identity, contractID)
+	})
+	if err != nil {
+		return append(diags, diag.Errorf("couldn't cancel rent contract %d with error: %v", contractID, err)...)
+	}
+
+	d.SetId("")
+	return diags
+}
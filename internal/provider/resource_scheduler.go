@@ -4,11 +4,14 @@ package provider
 import (
 	"context"
 	"fmt"
+	"maps"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
 	"github.com/pkg/errors"
 	"github.com/threefoldtech/terraform-provider-grid/internal/provider/scheduler"
 	"github.com/threefoldtech/tfgrid-sdk-go/grid-client/deployer"
@@ -16,6 +19,9 @@ import (
 	"github.com/threefoldtech/zos/pkg/gridtypes"
 )
 
+// gpusSeparator separates the ids of the gpus assigned to a request
+const gpusSeparator = ","
+
 func resourceScheduler() *schema.Resource {
 	return &schema.Resource{
 		Description:   "Resource to dynamically assign resource requests to nodes. A user could specify their desired node configurations, and the scheduler searches the grid for eligible nodes.",
@@ -23,6 +29,12 @@ func resourceScheduler() *schema.Resource {
 		UpdateContext: ResourceSchedUpdate,
 		ReadContext:   ResourceSchedRead,
 		DeleteContext: ResourceSchedDelete,
+		Timeouts: &schema.ResourceTimeout{
+			Create: schema.DefaultTimeout(10 * time.Minute),
+			Read:   schema.DefaultTimeout(10 * time.Minute),
+			Update: schema.DefaultTimeout(10 * time.Minute),
+			Delete: schema.DefaultTimeout(10 * time.Minute),
+		},
 		Schema: map[string]*schema.Schema{
 			"requests": {
 				Type:        schema.TypeList,
@@ -104,15 +116,90 @@ func resourceScheduler() *schema.Resource {
 							Default:     false,
 							Description: "True to ensure this request returns a distinct node relative to this scheduler resource.",
 						},
+						"country": {
+							Type:        schema.TypeString,
+							Optional:    true,
+							Description: "Pick only nodes in this country (case insensitive).",
+						},
+						"city": {
+							Type:        schema.TypeString,
+							Optional:    true,
+							Description: "Pick only nodes in this city (case insensitive).",
+						},
+						"region": {
+							Type:        schema.TypeString,
+							Optional:    true,
+							Description: "Pick only nodes in this region (case insensitive), e.g. `Europe`.",
+						},
+						"exclude_countries": {
+							Type:     schema.TypeList,
+							Optional: true,
+							Elem: &schema.Schema{
+								Type: schema.TypeString,
+							},
+							Description: "List of countries whose nodes are excluded from the search (case insensitive).",
+						},
+						"group": {
+							Type:        schema.TypeString,
+							Optional:    true,
+							Description: "Placement group of the request. The requests of a group are spread by `spread_by`, or share the `colocate_by` domain.",
+						},
+						"spread_by": {
+							Type:             schema.TypeString,
+							Optional:         true,
+							Description:      "Failure domain the requests of the group are spread by, one of `node`, `farm`, `country` or `city`. Each request of the group is assigned a node in a different domain, e.g. `farm` keeps the other replicas running if a whole farm goes offline. All the requests of the group must have the same `spread_by`.",
+							ValidateDiagFunc: validation.ToDiagFunc(validation.StringInSlice(scheduler.SpreadDomains, false)),
+						},
+						"colocate_by": {
+							Type:             schema.TypeString,
+							Optional:         true,
+							Description:      "Domain the requests of the group must share, one of `node` or `farm`. All the requests of the group must have the same `colocate_by`, and a group couldn't be both spread and colocated.",
+							ValidateDiagFunc: validation.ToDiagFunc(validation.StringInSlice(scheduler.ColocationDomains, false)),
+						},
+						"gpu_count": {
+							Type:             schema.TypeInt,
+							Optional:         true,
+							Description:      "Number of required free GPUs. GPUs could only be attached to vms on nodes rented by the twin, so it's usually used with `dedicated`. Requests with GPUs are never scheduled by the farmerbot.",
+							ValidateDiagFunc: validation.ToDiagFunc(validation.IntAtLeast(0)),
+						},
+						"gpu_vendor": {
+							Type:        schema.TypeString,
+							Optional:    true,
+							Description: "Pick only GPUs whose vendor name contains this value (case insensitive), e.g. `nvidia`.",
+						},
+						"gpu_device": {
+							Type:        schema.TypeString,
+							Optional:    true,
+							Description: "Pick only GPUs whose device name contains this value (case insensitive).",
+						},
 					},
 				},
 			},
+			"strategy": {
+				Type:             schema.TypeString,
+				Optional:         true,
+				Default:          string(scheduler.StrategyRandom),
+				Description:      "Strategy to pick a node among the eligible nodes for new requests. `random` picks a random node, `spread` picks the least loaded node, `pack` picks the most loaded node, `cheapest` picks the node with the lowest price using the farms pricing and rent discounts, and `rented_first` prefers nodes rented by the twin. Strategies other than `random` are deterministic. Requests on farms with a farmerbot are scheduled by the farmerbot, unless they have GPUs or location constraints.",
+				ValidateDiagFunc: validation.ToDiagFunc(validation.StringInSlice(scheduler.Strategies, false)),
+			},
 			"nodes": {
 				Type:        schema.TypeMap,
 				Computed:    true,
 				Elem:        &schema.Schema{Type: schema.TypeInt},
 				Description: "Mapping from the request name to the node id.",
 			},
+			"gpus": {
+				Type:        schema.TypeMap,
+				Computed:    true,
+				Elem:        &schema.Schema{Type: schema.TypeString},
+				Description: "Mapping from the name of the requests with GPUs to the comma separated ids of the GPUs assigned on their node, e.g. `split(\",\", grid_scheduler.sched.gpus[\"req\"])` could be used as the vm `gpus`.",
+			},
+			"strategies": {
+				Type:        schema.TypeMap,
+				Computed:    true,
+				Elem:        &schema.Schema{Type: schema.TypeString},
+				Description: "Mapping from the request name to the strategy used to assign its node. Assigned requests keep their node when the strategy changes.",
+			},
 		},
 	}
 }
@@ -126,20 +213,32 @@ func parseAssignment(d *schema.ResourceData) map[string]uint32 {
 	return assignment
 }
 
-func parseRequests(d *schema.ResourceData, assignment map[string]uint32) []scheduler.Request {
+// parseGPUs reads the previously assigned gpus of the requests
+func parseGPUs(d *schema.ResourceData) map[string][]string {
+	gpusIfs := d.Get("gpus").(map[string]interface{})
+	gpus := make(map[string][]string)
+	for k, v := range gpusIfs {
+		gpus[k] = strings.Split(v.(string), gpusSeparator)
+	}
+	return gpus
+}
+
+// parseRequests reads all the requests, the already assigned ones are skipped by the scheduler but still constrain their placement groups
+func parseRequests(d *schema.ResourceData) []scheduler.Request {
 	reqsIfs := d.Get("requests").([]interface{})
 	reqs := make([]scheduler.Request, 0)
 	for _, r := range reqsIfs {
 		mp := r.(map[string]interface{})
-		if _, ok := assignment[mp["name"].(string)]; ok {
-			// skip already assigned ones
-			continue
-		}
 		nodesToExcludeIF := mp["node_exclude"].([]interface{})
 		nodesToExclude := make([]uint32, len(nodesToExcludeIF))
 		for idx, n := range nodesToExcludeIF {
 			nodesToExclude[idx] = uint32(n.(int))
 		}
+		countriesToExcludeIF := mp["exclude_countries"].([]interface{})
+		countriesToExclude := make([]string, len(countriesToExcludeIF))
+		for idx, c := range countriesToExcludeIF {
+			countriesToExclude[idx] = c.(string)
+		}
 
 		reqs = append(reqs, scheduler.Request{
 			Name:           mp["name"].(string),
@@ -150,34 +249,70 @@ func parseRequests(d *schema.ResourceData, assignment map[string]uint32) []sched
 			Dedicated:      mp["dedicated"].(bool),
 			NodeExclude:    nodesToExclude,
 			Capacity: scheduler.Capacity{
+				CRU: uint64(mp["cru"].(int)),
 				MRU: uint64(mp["mru"].(int)) * uint64(gridtypes.Megabyte),
 				HRU: uint64(mp["hru"].(int)) * uint64(gridtypes.Megabyte),
 				SRU: uint64(mp["sru"].(int)) * uint64(gridtypes.Megabyte),
 			},
-			Distinct:  mp["distinct"].(bool),
-			Yggdrasil: mp["yggdrasil"].(bool),
-			Wireguard: mp["wireguard"].(bool),
+			Distinct:         mp["distinct"].(bool),
+			Yggdrasil:        mp["yggdrasil"].(bool),
+			Wireguard:        mp["wireguard"].(bool),
+			GPUCount:         uint32(mp["gpu_count"].(int)),
+			GPUVendor:        mp["gpu_vendor"].(string),
+			GPUDevice:        mp["gpu_device"].(string),
+			Country:          mp["country"].(string),
+			City:             mp["city"].(string),
+			Region:           mp["region"].(string),
+			ExcludeCountries: countriesToExclude,
+			Group:            mp["group"].(string),
+			SpreadBy:         scheduler.Domain(mp["spread_by"].(string)),
+			ColocateBy:       scheduler.Domain(mp["colocate_by"].(string)),
 		})
 	}
 	return reqs
 }
 
+// requestsStrategies returns the strategy used to assign the node of each request. The previously assigned requests keep
+// their recorded strategy, the newly assigned ones get the given strategy, and the removed requests are dropped
+func requestsStrategies(previous map[string]interface{}, previousAssignment map[string]uint32, reqs []scheduler.Request, strategy scheduler.Strategy) map[string]interface{} {
+	strategies := make(map[string]interface{}, len(reqs))
+	for _, r := range reqs {
+		if _, ok := previousAssignment[r.Name]; !ok {
+			strategies[r.Name] = string(strategy)
+			continue
+		}
+
+		if recorded, ok := previous[r.Name]; ok {
+			strategies[r.Name] = recorded
+		}
+	}
+
+	return strategies
+}
+
 func schedule(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
 	tfPluginClient, ok := meta.(*deployer.TFPluginClient)
 	if !ok {
 		return diag.FromErr(fmt.Errorf("failed to cast meta into api client"))
 	}
+
+	ctx = resourceLogContext(ctx, "grid_scheduler", d)
+
 	// read previously assigned nodes
 	assignment := parseAssignment(d)
-	reqs := parseRequests(d, assignment)
+	gpus := parseGPUs(d)
+	reqs := parseRequests(d)
+
+	previousAssignment := maps.Clone(assignment)
 
 	rpcClient, ok := tfPluginClient.RMB.(*peer.RpcClient)
 	if !ok {
 		return diag.FromErr(fmt.Errorf("failed to cast rmb client into rpc client"))
 	}
 
-	scheduler := scheduler.NewScheduler(tfPluginClient.GridProxyClient, uint64(tfPluginClient.TwinID), rpcClient)
-	if err := scheduler.ProcessRequests(ctx, reqs, assignment); err != nil {
+	strategy := scheduler.Strategy(d.Get("strategy").(string))
+	sched := scheduler.NewScheduler(tfPluginClient.GridProxyClient, uint64(tfPluginClient.TwinID), rpcClient, strategy)
+	if err := sched.ProcessRequests(ctx, reqs, assignment, gpus); err != nil {
 		return diag.FromErr(err)
 	}
 
@@ -185,6 +320,22 @@ func schedule(ctx context.Context, d *schema.ResourceData, meta interface{}) dia
 	if err != nil {
 		return diag.FromErr(errors.Wrapf(err, "couldn't set nodes with %v", assignment))
 	}
+
+	gpusIDs := make(map[string]string, len(gpus))
+	for name, ids := range gpus {
+		gpusIDs[name] = strings.Join(ids, gpusSeparator)
+	}
+
+	err = d.Set("gpus", gpusIDs)
+	if err != nil {
+		return diag.FromErr(errors.Wrapf(err, "couldn't set gpus with %v", gpusIDs))
+	}
+
+	strategies := requestsStrategies(d.Get("strategies").(map[string]interface{}), previousAssignment, reqs, strategy)
+	err = d.Set("strategies", strategies)
+	if err != nil {
+		return diag.FromErr(errors.Wrapf(err, "couldn't set strategies with %v", strategies))
+	}
 	return nil
 
 }
@@ -0,0 +1,21 @@
+// Package provider is the terraform provider
+package provider
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/threefoldtech/terraform-provider-grid/internal/provider/scheduler"
+)
+
+func TestRequestsStrategies(t *testing.T) {
+	previous := map[string]interface{}{"kept": string(scheduler.StrategySpread), "removed": string(scheduler.StrategySpread)}
+	previousAssignment := map[string]uint32{"kept": 1, "removed": 2}
+	reqs := []scheduler.Request{{Name: "kept"}, {Name: "new"}}
+
+	strategies := requestsStrategies(previous, previousAssignment, reqs, scheduler.StrategyPack)
+	assert.Equal(t, map[string]interface{}{
+		"kept": string(scheduler.StrategySpread),
+		"new":  string(scheduler.StrategyPack),
+	}, strategies, "assigned requests should keep their strategy, and removed requests should be dropped")
+}
@@ -0,0 +1,404 @@
+// Package provider is the terraform provider
+package provider
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
+	"github.com/threefoldtech/tfgrid-sdk-go/grid-client/deployer"
+)
+
+func resourceVM() *schema.Resource {
+	return &schema.Resource{
+		// This description is used by the documentation generator and the language server.
+		Description:   "Resource for deploying a single vm (ZMachine) with its own disks. A user should specify the node id for the vm, and the (already) deployed network that this vm should be a part of. Changing any of the vm attributes replaces the vm, while its disks could be resized in place.",
+		CreateContext: resourceVMCreate,
+		ReadContext:   resourceVMRead,
+		UpdateContext: resourceVMUpdate,
+		DeleteContext: resourceVMDelete,
+
+		Timeouts: &schema.ResourceTimeout{
+			Create: schema.DefaultTimeout(45 * time.Minute),
+			Read:   schema.DefaultTimeout(20 * time.Minute),
+			Update: schema.DefaultTimeout(45 * time.Minute),
+			Delete: schema.DefaultTimeout(20 * time.Minute),
+		},
+
+		Schema: map[string]*schema.Schema{
+			"name": {
+				Type:             schema.TypeString,
+				Required:         true,
+				ForceNew:         true,
+				Description:      "Vm (zmachine) workload name. This is also used as the solution name for the created contract. Must contain only alphanumeric and underscore characters.",
+				ValidateDiagFunc: validation.ToDiagFunc(validation.StringMatch(regexp.MustCompile(nameValidationRegex), nameValidationErrorMessage)),
+			},
+			"node": {
+				Type:        schema.TypeInt,
+				Required:    true,
+				ForceNew:    true,
+				Description: "Node id to place the vm on.",
+			},
+			"network_name": {
+				Type:        schema.TypeString,
+				Required:    true,
+				ForceNew:    true,
+				Description: "Network name of the deployed network resource to connect the vm to.",
+			},
+			"solution_type": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				Default:     "",
+				Description: "Solution type for created contract to be consistent across threefold tooling.",
+			},
+			"solution_provider": {
+				Type:        schema.TypeInt,
+				Optional:    true,
+				Default:     0,
+				ForceNew:    true,
+				Description: "Solution provider ID for the deployed solution which allows the creator of the solution to gain a percentage of the rewards.",
+			},
+			"ip_range": {
+				Type:        schema.TypeString,
+				Computed:    true,
+				Description: "IP range of the node for the wireguard network (e.g. 10.1.2.0/24). Has to have a subnet mask of 24.",
+			},
+			"flist": {
+				Type:        schema.TypeString,
+				Required:    true,
+				ForceNew:    true,
+				Description: "Flist used on this vm, e.g. https://hub.grid.tf/tf-official-apps/base:latest.flist. All flists could be found in `https://hub.grid.tf/`.",
+			},
+			"flist_checksum": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				ForceNew:    true,
+				Description: "if present, the flist is rejected if it has a different hash.",
+			},
+			"publicip": {
+				Type:        schema.TypeBool,
+				Optional:    true,
+				ForceNew:    true,
+				Description: "Flag to enable public ipv4 reservation.",
+			},
+			"publicip6": {
+				Type:        schema.TypeBool,
+				Optional:    true,
+				ForceNew:    true,
+				Description: "Flag to enable public ipv6 reservation.",
+			},
+			"computedip": {
+				Type:        schema.TypeString,
+				Computed:    true,
+				Description: "The reserved public ipv4 if any.",
+			},
+			"computedip6": {
+				Type:        schema.TypeString,
+				Computed:    true,
+				Description: "The reserved public ipv6 if any.",
+			},
+			"ip": {
+				Type:             schema.TypeString,
+				Optional:         true,
+				Computed:         true,
+				ForceNew:         true,
+				Description:      "The private wireguard IP of the vm.",
+				ValidateDiagFunc: validation.ToDiagFunc(validation.IsIPAddress),
+			},
+			"mycelium_ip_seed": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				ForceNew:    true,
+				Description: "Mycelium seed used to get the same mycelium ip for the vm. Hex encoded 6 bytes (e.g. b60f2b7ec39c).",
+			},
+			"mycelium_ip": {
+				Type:        schema.TypeString,
+				Computed:    true,
+				Description: "The allocated mycelium IP.",
+			},
+			"cpu": {
+				Type:             schema.TypeInt,
+				Optional:         true,
+				Default:          1,
+				ForceNew:         true,
+				Description:      "Number of virtual CPUs. Must be between 1 and 32.",
+				ValidateDiagFunc: validation.ToDiagFunc(validation.IntBetween(1, 32)),
+			},
+			"description": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				Default:     "",
+				ForceNew:    true,
+				Description: "Description of the vm.",
+			},
+			"memory": {
+				Type:             schema.TypeInt,
+				Optional:         true,
+				ForceNew:         true,
+				Description:      "Memory size in MB. Must be between 256MBs and 262144MBs (256GBs).",
+				ValidateDiagFunc: validation.ToDiagFunc(validation.IntBetween(256, 256*1024)),
+			},
+			"rootfs_size": {
+				Type:             schema.TypeInt,
+				Optional:         true,
+				ForceNew:         true,
+				Description:      "Root file system size in MB. Must be between 1024MBs and 10485760MBs (10TBs).",
+				ValidateDiagFunc: validation.ToDiagFunc(validation.IntBetween(1024, 10*1024*1024)),
+			},
+			"entrypoint": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				ForceNew:    true,
+				Description: "Command to execute as the ZMachine init.",
+			},
+			"mounts": {
+				Type:        schema.TypeList,
+				Optional:    true,
+				ForceNew:    true,
+				Description: "List of vm (ZMachine) mounts. Can reference the disks of this vm.",
+				Elem: &schema.Resource{
+					Schema: map[string]*schema.Schema{
+						"name": {
+							Type:        schema.TypeString,
+							Required:    true,
+							ForceNew:    true,
+							Description: "Name of the disk to mount.",
+						},
+						"mount_point": {
+							Type:        schema.TypeString,
+							Required:    true,
+							ForceNew:    true,
+							Description: "Directory to mount the disk on inside the ZMachine.",
+						},
+					},
+				},
+			},
+			"env_vars": {
+				Type:        schema.TypeMap,
+				Optional:    true,
+				ForceNew:    true,
+				Elem:        &schema.Schema{Type: schema.TypeString},
+				Description: "Environment variables to pass to the zmachine.",
+			},
+			"planetary": {
+				Type:        schema.TypeBool,
+				Optional:    true,
+				Default:     false,
+				ForceNew:    true,
+				Description: "Flag to enable Yggdrasil IP allocation.",
+			},
+			"corex": {
+				Type:        schema.TypeBool,
+				Optional:    true,
+				Default:     false,
+				ForceNew:    true,
+				Description: "Flag to enable corex. More information about corex could be found [here](https://github.com/threefoldtech/corex)",
+			},
+			"planetary_ip": {
+				Type:        schema.TypeString,
+				Computed:    true,
+				Description: "The allocated Yggdrasil IP.",
+			},
+			"zlogs": {
+				Type:        schema.TypeList,
+				Optional:    true,
+				ForceNew:    true,
+				Description: "List of Zlogs workloads configurations (URLs). Zlogs is a utility workload that allows you to stream `ZMachine` logs to a remote location.",
+				Elem: &schema.Schema{
+					Type:        schema.TypeString,
+					Description: "Url of the remote location receiving logs. URLs should use one of `redis, ws, wss` schema. e.g. wss://example_ip.com:9000"},
+			},
+			"gpus": {
+				Type:        schema.TypeList,
+				Optional:    true,
+				ForceNew:    true,
+				Description: "List of the GPUs to be attached to the vm and must not be used by other vms",
+				Elem: &schema.Schema{
+					Type:             schema.TypeString,
+					Description:      "Id of the GPU",
+					ValidateDiagFunc: validation.ToDiagFunc(validation.StringMatch(regexp.MustCompile(gpuValidationRegex), gpuValidationErrMsg)),
+				},
+			},
+			"console_url": {
+				Type:        schema.TypeString,
+				Computed:    true,
+				Description: "The url to access the vm via cloud console on private interface using wireguard.",
+			},
+			"disks": {
+				Type:        schema.TypeList,
+				Optional:    true,
+				Description: "List of disk workloads configurations. Disks could be resized in place, they can't be shrunk though.",
+				Elem: &schema.Resource{
+					Schema: map[string]*schema.Schema{
+						"name": {
+							Type:             schema.TypeString,
+							Required:         true,
+							ForceNew:         true,
+							Description:      "Disk workload name. This has to be unique within the vm disks. Must contain only alphanumeric and underscore characters.",
+							ValidateDiagFunc: validation.ToDiagFunc(validation.StringMatch(regexp.MustCompile(nameValidationRegex), nameValidationErrorMessage)),
+						},
+						"size": {
+							Type:             schema.TypeInt,
+							Required:         true,
+							Description:      "Disk size in GBs. Must be between 1GB and 10240GBs (10TBs)",
+							ValidateDiagFunc: validation.ToDiagFunc(validation.IntBetween(1, 10*1024)),
+						},
+						"description": {
+							Type:        schema.TypeString,
+							Optional:    true,
+							Default:     "",
+							Description: "Description of disk workload.",
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+func resourceVMCreate(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	var diags diag.Diagnostics
+	tfPluginClient, ok := meta.(*deployer.TFPluginClient)
+	if !ok {
+		return diag.FromErr(fmt.Errorf("failed to cast meta into threefold plugin client"))
+	}
+
+	ctx = resourceLogContext(ctx, "grid_vm", d)
+
+	dl, err := newVMDeploymentFromSchema(ctx, d, tfPluginClient.NcPool, tfPluginClient.SubstrateConn)
+	if err != nil {
+		return diag.Errorf("couldn't load vm data with error: %v", err)
+	}
+
+	if err := ensureNetworkSubnets(ctx, tfPluginClient, dl.NetworkName, dl.NodeID); err != nil {
+		return diag.FromErr(err)
+	}
+
+	err = traceCall(ctx, "DeploymentDeployer.Deploy", func() error { return tfPluginClient.DeploymentDeployer.Deploy(ctx, dl) })
+	if err != nil && dl.ContractID == 0 {
+		return diag.Errorf("couldn't deploy vm with error: %v", err)
+	}
+
+	if err != nil {
+		// failed to deploy (e.g. timed out) and failed to revert, store the current state to keep track of the contract
+		diags = diag.Errorf("couldn't deploy vm with error: %v", err)
+	} else if err := traceCall(ctx, "DeploymentDeployer.Sync", func() error { return tfPluginClient.DeploymentDeployer.Sync(ctx, dl) }); err != nil {
+		diags = diag.Errorf("couldn't sync vm with error: %v", err)
+	}
+
+	if err := syncContractsVM(d, dl); err != nil {
+		return append(diags, diag.Errorf("couldn't set vm data to the resource with error: %v", err)...)
+	}
+
+	logNodeContracts(ctx, "vm created", map[uint32]uint64{dl.NodeID: dl.ContractID})
+
+	return diags
+}
+
+func resourceVMRead(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	var diags diag.Diagnostics
+	tfPluginClient, ok := meta.(*deployer.TFPluginClient)
+	if !ok {
+		return diag.FromErr(fmt.Errorf("failed to cast meta into threefold plugin client"))
+	}
+
+	ctx = resourceLogContext(ctx, "grid_vm", d)
+
+	dl, err := newVMDeploymentFromSchema(ctx, d, tfPluginClient.NcPool, tfPluginClient.SubstrateConn)
+	if err != nil {
+		return diag.Errorf("couldn't load vm data with error: %v", err)
+	}
+
+	recordNetworkSubnets(tfPluginClient, dl.NetworkName, map[uint32]string{dl.NodeID: dl.IPrange})
+
+	if err := traceCall(ctx, "DeploymentDeployer.Sync", func() error { return tfPluginClient.DeploymentDeployer.Sync(ctx, dl) }); err != nil {
+		diags = append(diags, diag.Diagnostic{
+			Severity: diag.Warning,
+			Summary:  "failed to read vm data (terraform refresh might help)",
+			Detail:   err.Error(),
+		})
+		return diags
+	}
+
+	if dl.ContractID == 0 {
+		// the vm contract is no longer valid, remove it from the state so it gets recreated
+		d.SetId("")
+		return diags
+	}
+
+	if err := syncContractsVM(d, dl); err != nil {
+		return diag.Errorf("couldn't set vm data to the resource with error: %v", err)
+	}
+
+	logNodeContracts(ctx, "vm read", map[uint32]uint64{dl.NodeID: dl.ContractID})
+
+	return diags
+}
+
+func resourceVMUpdate(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	var diags diag.Diagnostics
+	tfPluginClient, ok := meta.(*deployer.TFPluginClient)
+	if !ok {
+		return diag.FromErr(fmt.Errorf("failed to cast meta into threefold plugin client"))
+	}
+
+	ctx = resourceLogContext(ctx, "grid_vm", d)
+
+	dl, err := newVMDeploymentFromSchema(ctx, d, tfPluginClient.NcPool, tfPluginClient.SubstrateConn)
+	if err != nil {
+		return diag.Errorf("couldn't load vm data with error: %v", err)
+	}
+
+	if err := ensureNetworkSubnets(ctx, tfPluginClient, dl.NetworkName, dl.NodeID); err != nil {
+		return diag.FromErr(err)
+	}
+
+	err = traceCall(ctx, "DeploymentDeployer.Deploy", func() error { return tfPluginClient.DeploymentDeployer.Deploy(ctx, dl) })
+	if err != nil && dl.ContractID == 0 {
+		return diag.Errorf("couldn't update vm with error: %v", err)
+	}
+
+	if err != nil {
+		// failed to deploy (e.g. timed out) and failed to revert, store the current state to keep track of the contract
+		diags = diag.Errorf("couldn't update vm with error: %v", err)
+	} else if err := traceCall(ctx, "DeploymentDeployer.Sync", func() error { return tfPluginClient.DeploymentDeployer.Sync(ctx, dl) }); err != nil {
+		diags = diag.Errorf("couldn't sync vm with error: %v", err)
+	}
+
+	if err := syncContractsVM(d, dl); err != nil {
+		return append(diags, diag.Errorf("couldn't set vm data to the resource with error: %v", err)...)
+	}
+
+	logNodeContracts(ctx, "vm updated", map[uint32]uint64{dl.NodeID: dl.ContractID})
+
+	return diags
+}
+
+func resourceVMDelete(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	var diags diag.Diagnostics
+	tfPluginClient, ok := meta.(*deployer.TFPluginClient)
+	if !ok {
+		return diag.FromErr(fmt.Errorf("failed to cast meta into threefold plugin client"))
+	}
+
+	ctx = resourceLogContext(ctx, "grid_vm", d)
+
+	dl, err := newVMDeploymentFromSchema(ctx, d, tfPluginClient.NcPool, tfPluginClient.SubstrateConn)
+	if err != nil {
+		return diag.Errorf("couldn't load vm data with error: %v", err)
+	}
+
+	logNodeContracts(ctx, "deleting vm", map[uint32]uint64{dl.NodeID: dl.ContractID})
+
+	if err := traceCall(ctx, "DeploymentDeployer.Cancel", func() error { return tfPluginClient.DeploymentDeployer.Cancel(ctx, dl) }); err != nil {
+		return diag.Errorf("couldn't cancel vm with error: %v", err)
+	}
+
+	d.SetId("")
+
+	return diags
+}
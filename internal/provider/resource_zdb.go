@@ -0,0 +1,245 @@
+// Package provider is the terraform provider
+package provider
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
+	"github.com/threefoldtech/tfgrid-sdk-go/grid-client/deployer"
+	"github.com/threefoldtech/tfgrid-sdk-go/grid-client/workloads"
+)
+
+func resourceZDB() *schema.Resource {
+	return &schema.Resource{
+		// This description is used by the documentation generator and the language server.
+		Description:   "Resource for deploying a single ZDB (0-db) namespace on a node. You can read more about 0-db (ZDB) [here](https://github.com/threefoldtech/0-db/).",
+		CreateContext: resourceZDBCreate,
+		ReadContext:   resourceZDBRead,
+		UpdateContext: resourceZDBUpdate,
+		DeleteContext: resourceZDBDelete,
+
+		Timeouts: &schema.ResourceTimeout{
+			Create: schema.DefaultTimeout(20 * time.Minute),
+			Read:   schema.DefaultTimeout(20 * time.Minute),
+			Update: schema.DefaultTimeout(20 * time.Minute),
+			Delete: schema.DefaultTimeout(20 * time.Minute),
+		},
+
+		Schema: map[string]*schema.Schema{
+			"name": {
+				Type:             schema.TypeString,
+				Required:         true,
+				ForceNew:         true,
+				Description:      "ZDB workload name. This is also used as the solution name for the created contract. Must contain only alphanumeric and underscore characters.",
+				ValidateDiagFunc: validation.ToDiagFunc(validation.StringMatch(regexp.MustCompile(nameValidationRegex), nameValidationErrorMessage)),
+			},
+			"node": {
+				Type:        schema.TypeInt,
+				Required:    true,
+				ForceNew:    true,
+				Description: "Node id to place the ZDB on.",
+			},
+			"solution_type": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				Default:     "",
+				Description: "Solution type for created contract to be consistent across threefold tooling.",
+			},
+			"solution_provider": {
+				Type:        schema.TypeInt,
+				Optional:    true,
+				Default:     0,
+				ForceNew:    true,
+				Description: "Solution provider ID for the deployed solution which allows the creator of the solution to gain a percentage of the rewards.",
+			},
+			"password": {
+				Type:        schema.TypeString,
+				Required:    true,
+				Sensitive:   true,
+				Description: "ZDB password.",
+			},
+			"public": {
+				Type:        schema.TypeBool,
+				Optional:    true,
+				Default:     false,
+				Description: "Makes it read-only if password is set, writable if no password set.",
+			},
+			"size": {
+				Type:             schema.TypeInt,
+				Required:         true,
+				Description:      "Size of the ZDB in GBs. The ZDB could be resized in place, it can't be shrunk though.",
+				ValidateDiagFunc: validation.ToDiagFunc(validation.IntAtLeast(1)),
+			},
+			"description": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				Default:     "",
+				Description: "ZDB workload description.",
+			},
+			"mode": {
+				Type:             schema.TypeString,
+				Optional:         true,
+				Default:          workloads.ZDBModeUser,
+				ForceNew:         true,
+				Description:      "Mode of the ZDB, `user` or `seq`. `user` is the default mode where a user can SET their own keys, like any key-value store. All keys are kept in memory. in `seq` mode, keys are sequential and autoincremented.",
+				ValidateDiagFunc: validation.ToDiagFunc(validation.StringInSlice([]string{workloads.ZDBModeUser, workloads.ZDBModeSeq}, false)),
+			},
+			"ips": {
+				Type: schema.TypeList,
+				Elem: &schema.Schema{
+					Type: schema.TypeString,
+				},
+				Computed:    true,
+				Description: "Computed IPs of the ZDB. Two IPs are returned: a public IPv6, and a YggIP, in this order",
+			},
+			"namespace": {
+				Type:        schema.TypeString,
+				Computed:    true,
+				Description: "Namespace of the ZDB.",
+			},
+			"port": {
+				Type:        schema.TypeInt,
+				Computed:    true,
+				Description: "Port of the ZDB.",
+			},
+		},
+	}
+}
+
+func resourceZDBCreate(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	var diags diag.Diagnostics
+	tfPluginClient, ok := meta.(*deployer.TFPluginClient)
+	if !ok {
+		return diag.FromErr(fmt.Errorf("failed to cast meta into threefold plugin client"))
+	}
+
+	ctx = resourceLogContext(ctx, "grid_zdb", d)
+
+	dl, err := newZDBDeploymentFromSchema(d)
+	if err != nil {
+		return diag.Errorf("couldn't load zdb data with error: %v", err)
+	}
+
+	err = traceCall(ctx, "DeploymentDeployer.Deploy", func() error { return tfPluginClient.DeploymentDeployer.Deploy(ctx, dl) })
+	if err != nil && dl.ContractID == 0 {
+		return diag.Errorf("couldn't deploy zdb with error: %v", err)
+	}
+
+	if err != nil {
+		// failed to deploy (e.g. timed out) and failed to revert, store the current state to keep track of the contract
+		diags = diag.Errorf("couldn't deploy zdb with error: %v", err)
+	} else if err := traceCall(ctx, "DeploymentDeployer.Sync", func() error { return tfPluginClient.DeploymentDeployer.Sync(ctx, dl) }); err != nil {
+		diags = diag.Errorf("couldn't sync zdb with error: %v", err)
+	}
+
+	if err := syncContractsZDB(d, dl); err != nil {
+		return append(diags, diag.Errorf("couldn't set zdb data to the resource with error: %v", err)...)
+	}
+
+	logNodeContracts(ctx, "zdb created", map[uint32]uint64{dl.NodeID: dl.ContractID})
+
+	return diags
+}
+
+func resourceZDBRead(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	var diags diag.Diagnostics
+	tfPluginClient, ok := meta.(*deployer.TFPluginClient)
+	if !ok {
+		return diag.FromErr(fmt.Errorf("failed to cast meta into threefold plugin client"))
+	}
+
+	ctx = resourceLogContext(ctx, "grid_zdb", d)
+
+	dl, err := newZDBDeploymentFromSchema(d)
+	if err != nil {
+		return diag.Errorf("couldn't load zdb data with error: %v", err)
+	}
+
+	if err := traceCall(ctx, "DeploymentDeployer.Sync", func() error { return tfPluginClient.DeploymentDeployer.Sync(ctx, dl) }); err != nil {
+		diags = append(diags, diag.Diagnostic{
+			Severity: diag.Warning,
+			Summary:  "failed to read zdb data (terraform refresh might help)",
+			Detail:   err.Error(),
+		})
+		return diags
+	}
+
+	if dl.ContractID == 0 {
+		// the zdb contract is no longer valid, remove it from the state so it gets recreated
+		d.SetId("")
+		return diags
+	}
+
+	if err := syncContractsZDB(d, dl); err != nil {
+		return diag.Errorf("couldn't set zdb data to the resource with error: %v", err)
+	}
+
+	logNodeContracts(ctx, "zdb read", map[uint32]uint64{dl.NodeID: dl.ContractID})
+
+	return diags
+}
+
+func resourceZDBUpdate(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	var diags diag.Diagnostics
+	tfPluginClient, ok := meta.(*deployer.TFPluginClient)
+	if !ok {
+		return diag.FromErr(fmt.Errorf("failed to cast meta into threefold plugin client"))
+	}
+
+	ctx = resourceLogContext(ctx, "grid_zdb", d)
+
+	dl, err := newZDBDeploymentFromSchema(d)
+	if err != nil {
+		return diag.Errorf("couldn't load zdb data with error: %v", err)
+	}
+
+	err = traceCall(ctx, "DeploymentDeployer.Deploy", func() error { return tfPluginClient.DeploymentDeployer.Deploy(ctx, dl) })
+	if err != nil && dl.ContractID == 0 {
+		return diag.Errorf("couldn't update zdb with error: %v", err)
+	}
+
+	if err != nil {
+		// failed to deploy (e.g. timed out) and failed to revert, store the current state to keep track of the contract
+		diags = diag.Errorf("couldn't update zdb with error: %v", err)
+	} else if err := traceCall(ctx, "DeploymentDeployer.Sync", func() error { return tfPluginClient.DeploymentDeployer.Sync(ctx, dl) }); err != nil {
+		diags = diag.Errorf("couldn't sync zdb with error: %v", err)
+	}
+
+	if err := syncContractsZDB(d, dl); err != nil {
+		return append(diags, diag.Errorf("couldn't set zdb data to the resource with error: %v", err)...)
+	}
+
+	logNodeContracts(ctx, "zdb updated", map[uint32]uint64{dl.NodeID: dl.ContractID})
+
+	return diags
+}
+
+func resourceZDBDelete(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	var diags diag.Diagnostics
+	tfPluginClient, ok := meta.(*deployer.TFPluginClient)
+	if !ok {
+		return diag.FromErr(fmt.Errorf("failed to cast meta into threefold plugin client"))
+	}
+
+	ctx = resourceLogContext(ctx, "grid_zdb", d)
+
+	dl, err := newZDBDeploymentFromSchema(d)
+	if err != nil {
+		return diag.Errorf("couldn't load zdb data with error: %v", err)
+	}
+
+	logNodeContracts(ctx, "deleting zdb", map[uint32]uint64{dl.NodeID: dl.ContractID})
+
+	if err := traceCall(ctx, "DeploymentDeployer.Cancel", func() error { return tfPluginClient.DeploymentDeployer.Cancel(ctx, dl) }); err != nil {
+		return diag.Errorf("couldn't cancel zdb with error: %v", err)
+	}
+
+	d.SetId("")
+
+	return diags
+}
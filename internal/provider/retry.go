@@ -0,0 +1,681 @@
+// Package provider is the terraform provider
+package provider
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"regexp"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/centrifuge/go-substrate-rpc-client/v4/types"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/pkg/errors"
+	substrate "github.com/threefoldtech/tfchain/clients/tfchain-client-go"
+	"github.com/threefoldtech/tfgrid-sdk-go/grid-client/calculator"
+	"github.com/threefoldtech/tfgrid-sdk-go/grid-client/deployer"
+	client "github.com/threefoldtech/tfgrid-sdk-go/grid-client/node"
+	gridstate "github.com/threefoldtech/tfgrid-sdk-go/grid-client/state"
+	"github.com/threefoldtech/tfgrid-sdk-go/grid-client/subi"
+	"github.com/threefoldtech/tfgrid-sdk-go/grid-client/zos"
+	"github.com/threefoldtech/tfgrid-sdk-go/rmb-sdk-go"
+)
+
+const (
+	// retryTimeout retries calls that timed out
+	retryTimeout = "timeout"
+	// retryConnection retries calls that failed because of a broken or refused connection
+	retryConnection = "connection"
+	// retryRMB retries every failed rmb call
+	retryRMB = "rmb"
+	// retrySubstrate retries every failed chain call, except for not found and chain module errors
+	retrySubstrate = "substrate"
+)
+
+var retryableErrorClasses = []string{retryTimeout, retryConnection, retryRMB, retrySubstrate}
+
+// retryPolicy describes how failed rmb and chain calls are retried
+type retryPolicy struct {
+	maxRetries     int
+	initialBackoff time.Duration
+	maxBackoff     time.Duration
+	classes        []string
+}
+
+// backoff returns the duration to wait before the given retry attempt, starting from 1
+func (p retryPolicy) backoff(attempt int) time.Duration {
+	backoff := p.initialBackoff
+	for i := 1; i < attempt && backoff < p.maxBackoff; i++ {
+		backoff *= 2
+	}
+
+	if backoff > p.maxBackoff {
+		return p.maxBackoff
+	}
+
+	return backoff
+}
+
+// retryable checks if an error of a call of the given class should be retried.
+// Chain module errors, like an insufficient balance, are the outcome of an applied extrinsic and are never retried
+func (p retryPolicy) retryable(err error, class string) bool {
+	if err == nil || errors.Is(err, context.Canceled) || isModuleError(err) {
+		return false
+	}
+
+	for _, c := range p.classes {
+		switch c {
+		case retryRMB, retrySubstrate:
+			if c == class && !errors.Is(err, substrate.ErrNotFound) && !errors.Is(err, substrate.ErrAccountNotFound) {
+				return true
+			}
+		case retryTimeout:
+			if isTimeoutError(err) {
+				return true
+			}
+		case retryConnection:
+			if isConnectionError(err) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// wait waits for the backoff of the given retry attempt, it returns false if the context is done first
+func (p retryPolicy) wait(ctx context.Context, attempt int) bool {
+	select {
+	case <-ctx.Done():
+		return false
+	case <-time.After(p.backoff(attempt)):
+		return true
+	}
+}
+
+// do runs fn until it succeeds, its error is not retryable, the retries are exhausted, or the context is done.
+// It's only used for reads and idempotent calls
+func (p retryPolicy) do(ctx context.Context, class string, fn func() error) error {
+	err := fn()
+	for attempt := 1; attempt <= p.maxRetries && p.retryable(err, class); attempt++ {
+		if !p.wait(ctx, attempt) {
+			return err
+		}
+
+		err = fn()
+	}
+
+	return err
+}
+
+// doWrite runs a call that isn't idempotent like do, but a failed attempt is only retried once applied reports that
+// the attempt didn't take effect, since a timed out call could still be applied. If applied reports the call took effect,
+// the call succeeded, and if applied fails, the error of the attempt is returned as is.
+func (p retryPolicy) doWrite(ctx context.Context, class string, fn func() error, applied func() (bool, error)) error {
+	err := fn()
+	attempt := 1
+	for ; attempt <= p.maxRetries && p.retryable(err, class); attempt++ {
+		if !p.wait(ctx, attempt) {
+			return err
+		}
+
+		done, checkErr := applied()
+		if checkErr != nil {
+			return err
+		}
+		if done {
+			return nil
+		}
+
+		err = fn()
+	}
+
+	// a retry could fail because the previous attempt was applied meanwhile, like a duplicate contract
+	if err != nil && attempt > 1 && !errors.Is(err, context.Canceled) {
+		if done, checkErr := applied(); checkErr == nil && done {
+			return nil
+		}
+	}
+
+	return err
+}
+
+// maxDuration returns the maximum time a call with the given attempt timeout could take with all of its retries
+func (p retryPolicy) maxDuration(attemptTimeout time.Duration) time.Duration {
+	total := attemptTimeout
+	for attempt := 1; attempt <= p.maxRetries; attempt++ {
+		total += attemptTimeout + p.backoff(attempt)
+	}
+
+	return total
+}
+
+// moduleErrorRegex matches the errors of the chain modules, which are reported with their names, like InsufficientBalance
+var moduleErrorRegex = regexp.MustCompile(`^[A-Z][a-z]+[A-Za-z0-9]*$`)
+
+// isModuleError checks if the error was returned by a chain module for an applied extrinsic
+func isModuleError(err error) bool {
+	msg := errors.Cause(err).Error()
+	return moduleErrorRegex.MatchString(msg) || strings.Contains(msg, "module error")
+}
+
+func isTimeoutError(err error) bool {
+	if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, syscall.ETIMEDOUT) {
+		return true
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true
+	}
+
+	msg := strings.ToLower(err.Error())
+	return strings.Contains(msg, "timeout") || strings.Contains(msg, "timed out") || strings.Contains(msg, "deadline exceeded")
+}
+
+// isConnectionError checks if the call failed because the connection couldn't be established or was broken
+func isConnectionError(err error) bool {
+	if errors.Is(err, io.EOF) ||
+		errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, syscall.ECONNREFUSED) ||
+		errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, syscall.EPIPE) ||
+		errors.Is(err, net.ErrClosed) {
+		return true
+	}
+
+	// only dialing errors, the other operations errors are covered by the errors above or are timeouts
+	var opErr *net.OpError
+	if errors.As(err, &opErr) && opErr.Op == "dial" {
+		return true
+	}
+
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) && dnsErr.IsNotFound {
+		return true
+	}
+
+	msg := strings.ToLower(err.Error())
+	for _, s := range []string{"connection refused", "connection reset", "broken pipe", "no such host", "websocket: close", "use of closed network connection"} {
+		if strings.Contains(msg, s) {
+			return true
+		}
+	}
+
+	return false
+}
+
+const (
+	rmbDeploymentDeploy = "zos.deployment.deploy"
+	rmbDeploymentUpdate = "zos.deployment.update"
+	rmbDeploymentDelete = "zos.deployment.delete"
+	rmbDeploymentGet    = "zos.deployment.get"
+)
+
+// retryingRMB is an rmb client that retries failed calls, each attempt gets its own timeout
+type retryingRMB struct {
+	rmb.Client
+	policy  retryPolicy
+	timeout time.Duration
+}
+
+// Call calls the given function on the given twin, retrying according to the retry policy.
+// Deployments are only sent again if the node doesn't have them, and deletions are never retried.
+func (r *retryingRMB) Call(ctx context.Context, twin uint32, fn string, data interface{}, result interface{}) error {
+	call := func() error {
+		ctx, cancel := context.WithTimeout(ctx, r.timeout)
+		defer cancel()
+
+		return r.Client.Call(ctx, twin, fn, data, result)
+	}
+
+	switch fn {
+	case rmbDeploymentDeploy, rmbDeploymentUpdate:
+		return r.policy.doWrite(ctx, retryRMB, call, func() (bool, error) {
+			return r.deploymentApplied(ctx, twin, data)
+		})
+	case rmbDeploymentDelete:
+		return call()
+	}
+
+	return r.policy.do(ctx, retryRMB, call)
+}
+
+// deploymentApplied checks if the node of the given twin has the version of the deployment that was sent to it
+func (r *retryingRMB) deploymentApplied(ctx context.Context, twin uint32, data interface{}) (bool, error) {
+	dl, ok := data.(zos.Deployment)
+	if !ok {
+		return false, errors.Errorf("unexpected deployment type %T", data)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+
+	var current zos.Deployment
+	err := r.Client.Call(ctx, twin, rmbDeploymentGet, map[string]interface{}{"contract_id": dl.ContractID}, &current)
+	if err != nil && strings.Contains(strings.ToLower(err.Error()), "not found") {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	return current.Version == dl.Version, nil
+}
+
+// contractsLookup looks up the contracts created by a failed write call, it's implemented by *subi.SubstrateImpl
+type contractsLookup interface {
+	GetContractWithHash(node uint32, hash substrate.HexHash) (uint64, error)
+	GetNodeRentContract(node uint32) (uint64, error)
+}
+
+var _ contractsLookup = (*subi.SubstrateImpl)(nil)
+
+// retryingSubstrate is a substrate client that retries failed contracts and twins calls.
+// Its retries stop once its context is done, see withRetryContext.
+type retryingSubstrate struct {
+	subi.SubstrateExt
+	policy retryPolicy
+	ctx    context.Context
+}
+
+func (s *retryingSubstrate) context() context.Context {
+	if s.ctx == nil {
+		return context.Background()
+	}
+	return s.ctx
+}
+
+// do runs a chain read with the retry policy
+func (s *retryingSubstrate) do(fn func() error) error {
+	return s.policy.do(s.context(), retrySubstrate, fn)
+}
+
+// write runs a chain write with the retry policy, a failed attempt is only retried if applied reports it didn't take effect
+func (s *retryingSubstrate) write(fn func() error, applied func() (bool, error)) error {
+	return s.policy.doWrite(s.context(), retrySubstrate, fn, applied)
+}
+
+// ownContract checks if the contract with the given id belongs to the twin of the identity
+func (s *retryingSubstrate) ownContract(identity substrate.Identity, id uint64) (bool, error) {
+	twinID, err := s.SubstrateExt.GetTwinByPubKey(identity.PublicKey())
+	if err != nil {
+		return false, err
+	}
+
+	contract, err := s.SubstrateExt.GetContract(id)
+	if err != nil {
+		return false, err
+	}
+
+	return contract.TwinID() == twinID && contract.IsCreated(), nil
+}
+
+// nodeContractWithHash returns the id of the twin contract on the node with the given deployment hash, or zero if there is none
+func (s *retryingSubstrate) nodeContractWithHash(identity substrate.Identity, node uint32, hash string) (uint64, error) {
+	lookup, ok := s.SubstrateExt.(contractsLookup)
+	if !ok {
+		return 0, errors.New("substrate client doesn't support looking up contracts")
+	}
+
+	id, err := lookup.GetContractWithHash(node, substrate.NewHexHash(hash))
+	if errors.Is(err, substrate.ErrNotFound) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	if own, err := s.ownContract(identity, id); err != nil || !own {
+		return 0, err
+	}
+	return id, nil
+}
+
+// nameContract returns the id of the twin name contract with the given name, or zero if there is none
+func (s *retryingSubstrate) nameContract(identity substrate.Identity, name string) (uint64, error) {
+	id, err := s.SubstrateExt.GetContractIDByNameRegistration(name)
+	if errors.Is(err, substrate.ErrNotFound) || (err == nil && id == 0) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	if own, err := s.ownContract(identity, id); err != nil || !own {
+		return 0, err
+	}
+	return id, nil
+}
+
+// rentContract returns the id of the twin rent contract of the given node, or zero if there is none
+func (s *retryingSubstrate) rentContract(identity substrate.Identity, node uint32) (uint64, error) {
+	lookup, ok := s.SubstrateExt.(contractsLookup)
+	if !ok {
+		return 0, errors.New("substrate client doesn't support looking up contracts")
+	}
+
+	id, err := lookup.GetNodeRentContract(node)
+	if errors.Is(err, substrate.ErrNotFound) || (err == nil && id == 0) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	if own, err := s.ownContract(identity, id); err != nil || !own {
+		return 0, err
+	}
+	return id, nil
+}
+
+// contractsCanceled checks if none of the given contracts is valid anymore
+func (s *retryingSubstrate) contractsCanceled(contracts ...uint64) (bool, error) {
+	for _, id := range contracts {
+		valid, err := s.SubstrateExt.IsValidContract(id)
+		if err != nil || valid {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+// batchContracts returns the ids of the contracts of a batch if all of them were created.
+// It fails if only some of them were created, since the batch can't be retried as is
+func (s *retryingSubstrate) batchContracts(identity substrate.Identity, contractsData []substrate.BatchCreateContractData) ([]uint64, error) {
+	ids := make([]uint64, 0, len(contractsData))
+	for _, data := range contractsData {
+		var id uint64
+		var err error
+		if data.Name != "" {
+			id, err = s.nameContract(identity, data.Name)
+		} else {
+			id, err = s.nodeContractWithHash(identity, data.Node, data.Hash)
+		}
+		if err != nil {
+			return nil, err
+		}
+		if id != 0 {
+			ids = append(ids, id)
+		}
+	}
+
+	switch len(ids) {
+	case 0:
+		return nil, nil
+	case len(contractsData):
+		return ids, nil
+	}
+	return nil, errors.Errorf("only %d of the %d contracts of the batch were created", len(ids), len(contractsData))
+}
+
+// CancelContract cancels a contract
+func (s *retryingSubstrate) CancelContract(identity substrate.Identity, contractID uint64) error {
+	return s.write(
+		func() error { return s.SubstrateExt.CancelContract(identity, contractID) },
+		func() (bool, error) { return s.contractsCanceled(contractID) },
+	)
+}
+
+// CreateNodeContract creates a node contract
+func (s *retryingSubstrate) CreateNodeContract(identity substrate.Identity, node uint32, body string, hash string, publicIPs uint32, solutionProviderID *uint64) (id uint64, err error) {
+	err = s.write(
+		func() (err error) {
+			id, err = s.SubstrateExt.CreateNodeContract(identity, node, body, hash, publicIPs, solutionProviderID)
+			return err
+		},
+		func() (bool, error) {
+			found, err := s.nodeContractWithHash(identity, node, hash)
+			id = found
+			return found != 0, err
+		},
+	)
+	return
+}
+
+// UpdateNodeContract updates a node contract
+func (s *retryingSubstrate) UpdateNodeContract(identity substrate.Identity, contract uint64, body string, hash string) (id uint64, err error) {
+	err = s.write(
+		func() (err error) {
+			id, err = s.SubstrateExt.UpdateNodeContract(identity, contract, body, hash)
+			return err
+		},
+		func() (bool, error) {
+			current, err := s.SubstrateExt.GetContract(contract)
+			if err != nil {
+				return false, err
+			}
+			id = contract
+			return current.ContractType.NodeContract.DeploymentHash == substrate.NewHexHash(hash), nil
+		},
+	)
+	return
+}
+
+// CreateNameContract creates a name contract
+func (s *retryingSubstrate) CreateNameContract(identity substrate.Identity, name string) (id uint64, err error) {
+	err = s.write(
+		func() (err error) {
+			id, err = s.SubstrateExt.CreateNameContract(identity, name)
+			return err
+		},
+		func() (bool, error) {
+			found, err := s.nameContract(identity, name)
+			id = found
+			return found != 0, err
+		},
+	)
+	return
+}
+
+// createRentContract creates a rent contract, it's missing from subi.SubstrateExt
+func (s *retryingSubstrate) createRentContract(sub *subi.SubstrateImpl, identity substrate.Identity, node uint32, solutionProviderID *uint64) (id uint64, err error) {
+	err = s.write(
+		func() (err error) {
+			id, err = sub.CreateRentContract(identity, node, solutionProviderID)
+			return err
+		},
+		func() (bool, error) {
+			found, err := s.rentContract(identity, node)
+			id = found
+			return found != 0, err
+		},
+	)
+	return
+}
+
+// EnsureContractCanceled ensures a contract is canceled
+func (s *retryingSubstrate) EnsureContractCanceled(identity substrate.Identity, contractID uint64) error {
+	return s.write(
+		func() error { return s.SubstrateExt.EnsureContractCanceled(identity, contractID) },
+		func() (bool, error) { return s.contractsCanceled(contractID) },
+	)
+}
+
+// IsValidContract checks if a contract exists and is not deleted
+func (s *retryingSubstrate) IsValidContract(contractID uint64) (valid bool, err error) {
+	err = s.do(func() error {
+		valid, err = s.SubstrateExt.IsValidContract(contractID)
+		return err
+	})
+	return
+}
+
+// GetContract gets a contract
+func (s *retryingSubstrate) GetContract(id uint64) (contract subi.Contract, err error) {
+	err = s.do(func() error {
+		contract, err = s.SubstrateExt.GetContract(id)
+		return err
+	})
+	return
+}
+
+// GetNodeTwin gets the twin id of a node
+func (s *retryingSubstrate) GetNodeTwin(id uint32) (twin uint32, err error) {
+	err = s.do(func() error {
+		twin, err = s.SubstrateExt.GetNodeTwin(id)
+		return err
+	})
+	return
+}
+
+// GetTwinPK gets the public key of a twin
+func (s *retryingSubstrate) GetTwinPK(twinID uint32) (pk []byte, err error) {
+	err = s.do(func() error {
+		pk, err = s.SubstrateExt.GetTwinPK(twinID)
+		return err
+	})
+	return
+}
+
+// GetContractIDByNameRegistration gets the name contract id of a name
+func (s *retryingSubstrate) GetContractIDByNameRegistration(name string) (id uint64, err error) {
+	err = s.do(func() error {
+		id, err = s.SubstrateExt.GetContractIDByNameRegistration(name)
+		return err
+	})
+	return
+}
+
+// GetTFTPrice gets the tft price
+func (s *retryingSubstrate) GetTFTPrice() (price types.U32, err error) {
+	err = s.do(func() error {
+		price, err = s.SubstrateExt.GetTFTPrice()
+		return err
+	})
+	return
+}
+
+// BatchCreateContract creates a batch of contracts
+func (s *retryingSubstrate) BatchCreateContract(identity substrate.Identity, contractsData []substrate.BatchCreateContractData) (ids []uint64, index *int, err error) {
+	err = s.write(
+		func() (err error) {
+			ids, index, err = s.SubstrateExt.BatchCreateContract(identity, contractsData)
+			return err
+		},
+		func() (bool, error) {
+			found, err := s.batchContracts(identity, contractsData)
+			ids, index = found, nil
+			return len(found) != 0, err
+		},
+	)
+	return
+}
+
+// BatchAllCreateContract creates a batch of contracts atomically
+func (s *retryingSubstrate) BatchAllCreateContract(identity substrate.Identity, contractsData []substrate.BatchCreateContractData) (ids []uint64, err error) {
+	err = s.write(
+		func() (err error) {
+			ids, err = s.SubstrateExt.BatchAllCreateContract(identity, contractsData)
+			return err
+		},
+		func() (bool, error) {
+			found, err := s.batchContracts(identity, contractsData)
+			ids = found
+			return len(found) != 0, err
+		},
+	)
+	return
+}
+
+// BatchCancelContract cancels a batch of contracts atomically
+func (s *retryingSubstrate) BatchCancelContract(identity substrate.Identity, contracts []uint64) error {
+	return s.write(
+		func() error { return s.SubstrateExt.BatchCancelContract(identity, contracts) },
+		func() (bool, error) { return s.contractsCanceled(contracts...) },
+	)
+}
+
+// applyRetryPolicy makes the node clients, the chain calls and the deployers of the client retry failed calls
+func applyRetryPolicy(tfPluginClient *deployer.TFPluginClient, policy retryPolicy) error {
+	if policy.maxRetries <= 0 {
+		return nil
+	}
+
+	if policy.initialBackoff <= 0 || policy.maxBackoff < policy.initialBackoff {
+		return fmt.Errorf("invalid retry backoff, initial backoff %s must be positive and not more than max backoff %s", policy.initialBackoff, policy.maxBackoff)
+	}
+
+	rmbClient := &retryingRMB{
+		Client:  tfPluginClient.RMB,
+		policy:  policy,
+		timeout: tfPluginClient.RMBTimeout,
+	}
+
+	tfPluginClient.SubstrateConn = &retryingSubstrate{SubstrateExt: tfPluginClient.SubstrateConn, policy: policy}
+	tfPluginClient.NcPool = client.NewNodeClientPool(rmbClient, policy.maxDuration(tfPluginClient.RMBTimeout))
+
+	tfPluginClient.DeploymentDeployer = deployer.NewDeploymentDeployer(tfPluginClient)
+	tfPluginClient.NetworkDeployer = deployer.NewNetworkDeployer(tfPluginClient)
+	tfPluginClient.GatewayFQDNDeployer = deployer.NewGatewayFqdnDeployer(tfPluginClient)
+	tfPluginClient.K8sDeployer = deployer.NewK8sDeployer(tfPluginClient)
+	tfPluginClient.GatewayNameDeployer = deployer.NewGatewayNameDeployer(tfPluginClient)
+
+	tfPluginClient.State = gridstate.NewState(tfPluginClient.NcPool, tfPluginClient.SubstrateConn)
+	tfPluginClient.Calculator = calculator.NewCalculator(tfPluginClient.SubstrateConn, tfPluginClient.Identity)
+
+	return nil
+}
+
+// withRetryContext returns a copy of the plugin client whose chain calls stop retrying once the given context is done,
+// so the deadline of a resource operation bounds the retries of its chain calls too
+func withRetryContext(ctx context.Context, tfPluginClient *deployer.TFPluginClient) *deployer.TFPluginClient {
+	sub, ok := tfPluginClient.SubstrateConn.(*retryingSubstrate)
+	if !ok {
+		return tfPluginClient
+	}
+
+	c := *tfPluginClient
+	c.SubstrateConn = &retryingSubstrate{SubstrateExt: sub.SubstrateExt, policy: sub.policy, ctx: ctx}
+
+	c.DeploymentDeployer = deployer.NewDeploymentDeployer(&c)
+	c.NetworkDeployer = deployer.NewNetworkDeployer(&c)
+	c.GatewayFQDNDeployer = deployer.NewGatewayFqdnDeployer(&c)
+	c.K8sDeployer = deployer.NewK8sDeployer(&c)
+	c.GatewayNameDeployer = deployer.NewGatewayNameDeployer(&c)
+	c.Calculator = calculator.NewCalculator(c.SubstrateConn, c.Identity)
+
+	return &c
+}
+
+// bindRetryContext makes the CRUD operations of the resource use a plugin client bound to the operation context,
+// see withRetryContext
+func bindRetryContext(r *schema.Resource) {
+	bind := func(fn func(context.Context, *schema.ResourceData, interface{}) diag.Diagnostics) func(context.Context, *schema.ResourceData, interface{}) diag.Diagnostics {
+		if fn == nil {
+			return nil
+		}
+
+		return func(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+			if tfPluginClient, ok := meta.(*deployer.TFPluginClient); ok {
+				meta = withRetryContext(ctx, tfPluginClient)
+			}
+			return fn(ctx, d, meta)
+		}
+	}
+
+	r.CreateContext = bind(r.CreateContext)
+	r.ReadContext = bind(r.ReadContext)
+	r.UpdateContext = bind(r.UpdateContext)
+	r.DeleteContext = bind(r.DeleteContext)
+}
+
+// substrateImpl returns the chain client behind the given substrate client, along with a retrying client running
+// chain calls with the retry policy of the substrate client if any. It's needed for the chain calls missing from subi.SubstrateExt
+func substrateImpl(sub subi.SubstrateExt) (*subi.SubstrateImpl, *retryingSubstrate, error) {
+	retrying, ok := sub.(*retryingSubstrate)
+	if !ok {
+		retrying = &retryingSubstrate{SubstrateExt: sub}
+	}
+
+	impl, ok := retrying.SubstrateExt.(*subi.SubstrateImpl)
+	if !ok {
+		return nil, nil, fmt.Errorf("failed to cast substrate client into substrate implementation")
+	}
+
+	return impl, retrying, nil
+}
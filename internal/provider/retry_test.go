@@ -0,0 +1,296 @@
+// Package provider is the terraform provider
+package provider
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/centrifuge/go-substrate-rpc-client/v4/types"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	substrate "github.com/threefoldtech/tfchain/clients/tfchain-client-go"
+	"github.com/threefoldtech/tfgrid-sdk-go/grid-client/subi"
+	"github.com/threefoldtech/tfgrid-sdk-go/grid-client/zos"
+	"github.com/threefoldtech/tfgrid-sdk-go/rmb-sdk-go"
+)
+
+func testRetryPolicy(classes ...string) retryPolicy {
+	return retryPolicy{
+		maxRetries:     3,
+		initialBackoff: time.Millisecond,
+		maxBackoff:     4 * time.Millisecond,
+		classes:        classes,
+	}
+}
+
+func TestRetryBackoff(t *testing.T) {
+	policy := retryPolicy{initialBackoff: time.Second, maxBackoff: 10 * time.Second}
+
+	cases := []struct {
+		attempt int
+		backoff time.Duration
+	}{
+		{1, time.Second},
+		{2, 2 * time.Second},
+		{3, 4 * time.Second},
+		{4, 8 * time.Second},
+		{5, 10 * time.Second},
+		{50, 10 * time.Second},
+	}
+
+	for _, c := range cases {
+		assert.Equal(t, c.backoff, policy.backoff(c.attempt), "attempt %d", c.attempt)
+	}
+}
+
+func TestRetryable(t *testing.T) {
+	dialErr := &net.OpError{Op: "dial", Net: "tcp", Err: syscall.ECONNREFUSED}
+	readErr := &net.OpError{Op: "read", Net: "tcp", Err: errors.New("permission denied")}
+
+	cases := []struct {
+		name      string
+		err       error
+		class     string
+		classes   []string
+		retryable bool
+	}{
+		{"no error", nil, retrySubstrate, []string{retrySubstrate}, false},
+		{"canceled", context.Canceled, retryRMB, []string{retryRMB, retryTimeout}, false},
+		{"deadline", errors.Wrap(context.DeadlineExceeded, "call"), retryRMB, []string{retryTimeout}, true},
+		{"timeout message", errors.New("request timed out"), retrySubstrate, []string{retryTimeout}, true},
+		{"timeout without class", context.DeadlineExceeded, retryRMB, []string{retryConnection}, false},
+		{"refused", dialErr, retrySubstrate, []string{retryConnection}, true},
+		{"reset", errors.Wrap(syscall.ECONNRESET, "read"), retryRMB, []string{retryConnection}, true},
+		{"eof", io.EOF, retryRMB, []string{retryConnection}, true},
+		{"other op error", readErr, retryRMB, []string{retryConnection}, false},
+		{"rmb class", errors.New("node error"), retryRMB, []string{retryRMB}, true},
+		{"other class", errors.New("node error"), retrySubstrate, []string{retryRMB}, false},
+		{"substrate class", errors.New("failed to submit extrinsic"), retrySubstrate, []string{retrySubstrate}, true},
+		{"not found", errors.Wrap(substrate.ErrNotFound, "contract"), retrySubstrate, []string{retrySubstrate}, false},
+		{"module error", errors.New("InsufficientBalance"), retrySubstrate, []string{retrySubstrate}, false},
+		{"wrapped module error", errors.Wrap(errors.New("NodeHasActiveContracts"), "cancel"), retrySubstrate, []string{retrySubstrate}, false},
+		{"unknown module error", fmt.Errorf("module error (26) with unknown code 200 occured"), retrySubstrate, []string{retrySubstrate}, false},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			assert.Equal(t, c.retryable, testRetryPolicy(c.classes...).retryable(c.err, c.class))
+		})
+	}
+}
+
+func TestRetryDo(t *testing.T) {
+	policy := testRetryPolicy(retryTimeout)
+
+	calls := 0
+	err := policy.do(context.Background(), retryRMB, func() error {
+		calls++
+		if calls < 3 {
+			return context.DeadlineExceeded
+		}
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 3, calls)
+
+	calls = 0
+	err = policy.do(context.Background(), retryRMB, func() error {
+		calls++
+		return context.DeadlineExceeded
+	})
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Equal(t, policy.maxRetries+1, calls, "the retries should be exhausted")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	calls = 0
+	_ = policy.do(ctx, retryRMB, func() error {
+		calls++
+		return context.DeadlineExceeded
+	})
+	assert.Equal(t, 1, calls, "a done context should stop the retries")
+}
+
+func TestRetryDoWrite(t *testing.T) {
+	policy := testRetryPolicy(retryTimeout)
+
+	t.Run("applied", func(t *testing.T) {
+		calls := 0
+		err := policy.doWrite(context.Background(), retrySubstrate,
+			func() error { calls++; return context.DeadlineExceeded },
+			func() (bool, error) { return true, nil },
+		)
+		assert.NoError(t, err)
+		assert.Equal(t, 1, calls, "an applied call shouldn't be sent again")
+	})
+
+	t.Run("not applied", func(t *testing.T) {
+		calls := 0
+		err := policy.doWrite(context.Background(), retrySubstrate,
+			func() error {
+				calls++
+				if calls == 1 {
+					return context.DeadlineExceeded
+				}
+				return nil
+			},
+			func() (bool, error) { return false, nil },
+		)
+		assert.NoError(t, err)
+		assert.Equal(t, 2, calls)
+	})
+
+	t.Run("unknown", func(t *testing.T) {
+		calls := 0
+		err := policy.doWrite(context.Background(), retrySubstrate,
+			func() error { calls++; return context.DeadlineExceeded },
+			func() (bool, error) { return false, errors.New("chain unreachable") },
+		)
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+		assert.Equal(t, 1, calls, "a call that could have been applied shouldn't be sent again")
+	})
+
+	t.Run("applied meanwhile", func(t *testing.T) {
+		calls, checks := 0, 0
+		err := policy.doWrite(context.Background(), retrySubstrate,
+			func() error {
+				calls++
+				if calls == 1 {
+					return context.DeadlineExceeded
+				}
+				return errors.New("ContractIsNotUnique")
+			},
+			func() (bool, error) { checks++; return checks > 1, nil },
+		)
+		assert.NoError(t, err)
+		assert.Equal(t, 2, calls)
+	})
+}
+
+// contractsSubstrate is a substrate client whose contract writes time out after taking effect
+type contractsSubstrate struct {
+	subi.SubstrateExt
+	twinID    uint32
+	hashes    map[string]uint64
+	names     map[string]uint64
+	canceled  map[uint64]bool
+	creations int
+}
+
+func (s *contractsSubstrate) GetTwinByPubKey(pk []byte) (uint32, error) {
+	return s.twinID, nil
+}
+
+func (s *contractsSubstrate) GetContract(id uint64) (subi.Contract, error) {
+	return subi.Contract{Contract: &substrate.Contract{
+		TwinID: types.U32(s.twinID),
+		State:  substrate.ContractState{IsCreated: !s.canceled[id]},
+	}}, nil
+}
+
+func (s *contractsSubstrate) GetContractWithHash(node uint32, hash substrate.HexHash) (uint64, error) {
+	if id, ok := s.hashes[fmt.Sprintf("%d-%s", node, hash.String())]; ok {
+		return id, nil
+	}
+	return 0, errors.Wrap(substrate.ErrNotFound, "contract not found")
+}
+
+func (s *contractsSubstrate) GetNodeRentContract(node uint32) (uint64, error) {
+	return 0, substrate.ErrNotFound
+}
+
+func (s *contractsSubstrate) GetContractIDByNameRegistration(name string) (uint64, error) {
+	if id, ok := s.names[name]; ok {
+		return id, nil
+	}
+	return 0, substrate.ErrNotFound
+}
+
+func (s *contractsSubstrate) IsValidContract(id uint64) (bool, error) {
+	return !s.canceled[id], nil
+}
+
+func (s *contractsSubstrate) CreateNodeContract(identity substrate.Identity, node uint32, body string, hash string, publicIPs uint32, solutionProviderID *uint64) (uint64, error) {
+	s.creations++
+	s.hashes[fmt.Sprintf("%d-%s", node, substrate.NewHexHash(hash).String())] = uint64(s.creations)
+	return 0, context.DeadlineExceeded
+}
+
+func (s *contractsSubstrate) CreateNameContract(identity substrate.Identity, name string) (uint64, error) {
+	s.creations++
+	return 0, errors.New("InsufficientBalance")
+}
+
+func (s *contractsSubstrate) CancelContract(identity substrate.Identity, contractID uint64) error {
+	s.canceled[contractID] = true
+	return errors.New("websocket: close 1006 (abnormal closure)")
+}
+
+func TestRetryingSubstrateWrites(t *testing.T) {
+	identity, err := substrate.NewIdentityFromSr25519Phrase(testMnemonic)
+	require.NoError(t, err)
+
+	fake := &contractsSubstrate{twinID: 1, hashes: map[string]uint64{}, names: map[string]uint64{}, canceled: map[uint64]bool{}}
+	sub := &retryingSubstrate{SubstrateExt: fake, policy: testRetryPolicy(retryTimeout, retryConnection, retrySubstrate)}
+
+	id, err := sub.CreateNodeContract(identity, 11, "", "hash", 0, nil)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(1), id, "the contract created by the timed out call should be returned")
+	assert.Equal(t, 1, fake.creations, "the contract shouldn't be created twice")
+
+	require.NoError(t, sub.CancelContract(identity, id))
+
+	fake.creations = 0
+	_, err = sub.CreateNameContract(identity, "name")
+	assert.Error(t, err)
+	assert.Equal(t, 1, fake.creations, "module errors shouldn't be retried")
+}
+
+// deploymentsRMB is an rmb client of a node whose deploy calls time out after taking effect
+type deploymentsRMB struct {
+	rmb.Client
+	deployments map[uint64]zos.Deployment
+	deploys     int
+}
+
+func (r *deploymentsRMB) Call(ctx context.Context, twin uint32, fn string, data interface{}, result interface{}) error {
+	switch fn {
+	case rmbDeploymentDeploy, rmbDeploymentUpdate:
+		r.deploys++
+		dl := data.(zos.Deployment)
+		r.deployments[dl.ContractID] = dl
+		return context.DeadlineExceeded
+	case rmbDeploymentGet:
+		dl, ok := r.deployments[data.(map[string]interface{})["contract_id"].(uint64)]
+		if !ok {
+			return errors.New("deployment not found")
+		}
+		*result.(*zos.Deployment) = dl
+		return nil
+	case rmbDeploymentDelete:
+		return context.DeadlineExceeded
+	}
+	return nil
+}
+
+func TestRetryingRMBWrites(t *testing.T) {
+	node := &deploymentsRMB{deployments: map[uint64]zos.Deployment{}}
+	r := &retryingRMB{Client: node, policy: testRetryPolicy(retryTimeout), timeout: time.Second}
+
+	err := r.Call(context.Background(), 1, rmbDeploymentDeploy, zos.Deployment{ContractID: 5}, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, node.deploys, "the deployment shouldn't be sent twice")
+
+	err = r.Call(context.Background(), 1, rmbDeploymentUpdate, zos.Deployment{ContractID: 5, Version: 1}, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, node.deploys)
+
+	err = r.Call(context.Background(), 1, rmbDeploymentDelete, map[string]interface{}{"contract_id": uint64(5)}, nil)
+	assert.ErrorIs(t, err, context.DeadlineExceeded, "deletions shouldn't be retried")
+}
@@ -5,7 +5,11 @@ import (
 	proxyTypes "github.com/threefoldtech/tfgrid-sdk-go/grid-proxy/pkg/types"
 )
 
-// Capacity struct for capacity (MRU, SRU, HRU)
+// cpuOverprovisionFactor is the factor by which the node virtual CPUs are allowed to be overprovisioned.
+// zos doesn't limit the total virtual CPUs of the workloads, only the virtual CPUs of a single vm to the node cores
+const cpuOverprovisionFactor = 2
+
+// Capacity struct for capacity (MRU, SRU, HRU, CRU)
 type Capacity struct {
 	MRU uint64
 	SRU uint64
@@ -17,14 +21,25 @@ func (c *Capacity) consume(r *Request) {
 	c.MRU -= r.Capacity.MRU
 	c.HRU -= r.Capacity.HRU
 	c.SRU -= r.Capacity.SRU
+	c.CRU -= r.Capacity.CRU
 }
 
+// freeCapacity returns the capacity of the node that is not used yet. The used capacity reported by the grid proxy could
+// exceed the total one (e.g. overprovisioned virtual CPUs, or reserved capacity), in which case nothing is free
 func freeCapacity(node *proxyTypes.Node) Capacity {
 	var res Capacity
 
-	res.MRU = uint64(node.TotalResources.MRU) - uint64(node.UsedResources.MRU)
-	res.HRU = uint64(node.TotalResources.HRU) - uint64(node.UsedResources.HRU)
-	res.SRU = uint64(node.TotalResources.SRU) - uint64(node.UsedResources.SRU)
-	res.CRU = node.TotalResources.CRU - node.UsedResources.CRU
+	res.MRU = free(uint64(node.TotalResources.MRU), uint64(node.UsedResources.MRU))
+	res.HRU = free(uint64(node.TotalResources.HRU), uint64(node.UsedResources.HRU))
+	res.SRU = free(uint64(node.TotalResources.SRU), uint64(node.UsedResources.SRU))
+	res.CRU = free(node.TotalResources.CRU*cpuOverprovisionFactor, node.UsedResources.CRU)
 	return res
 }
+
+// free returns the unused part of the total, without underflowing if the used exceeds the total
+func free(total, used uint64) uint64 {
+	if used > total {
+		return 0
+	}
+	return total - used
+}
@@ -13,11 +13,13 @@ var (
 			HRU: 1,
 			SRU: 2,
 			MRU: 3,
+			CRU: 2,
 		},
 		TotalResources: proxyTypes.Capacity{
 			HRU: 4,
 			SRU: 5,
 			MRU: 6,
+			CRU: 4,
 		},
 	}
 )
@@ -27,4 +29,43 @@ func TestFreeCapacity(t *testing.T) {
 	assert.Equal(t, cap.HRU, uint64(3), "hru")
 	assert.Equal(t, cap.SRU, uint64(3), "sru")
 	assert.Equal(t, cap.MRU, uint64(3), "mru")
+	assert.Equal(t, cap.CRU, uint64(6), "cru")
+}
+
+func TestFreeCapacityOverprovisionedCRU(t *testing.T) {
+	cap := freeCapacity(&proxyTypes.Node{
+		UsedResources: proxyTypes.Capacity{
+			CRU: 10,
+		},
+		TotalResources: proxyTypes.Capacity{
+			CRU: 4,
+		},
+	})
+	assert.Equal(t, cap.CRU, uint64(0), "cru")
+}
+
+func TestConsumeCRU(t *testing.T) {
+	cap := freeCapacity(&node)
+	cap.consume(&Request{
+		Capacity: Capacity{
+			CRU: 4,
+		},
+	})
+	assert.Equal(t, cap.CRU, uint64(2), "cru")
+}
+
+func TestFreeCapacityUsedExceedsTotal(t *testing.T) {
+	cap := freeCapacity(&proxyTypes.Node{
+		UsedResources: proxyTypes.Capacity{
+			HRU: 5,
+			SRU: 6,
+			MRU: 7,
+		},
+		TotalResources: proxyTypes.Capacity{
+			HRU: 4,
+			SRU: 5,
+			MRU: 6,
+		},
+	})
+	assert.Equal(t, cap, Capacity{}, "used capacity exceeding the total shouldn't underflow")
 }
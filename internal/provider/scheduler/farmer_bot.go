@@ -3,8 +3,9 @@ package scheduler
 import (
 	"context"
 	"fmt"
-	"log"
+	"time"
 
+	"github.com/hashicorp/terraform-plugin-log/tflog"
 	"github.com/pkg/errors"
 )
 
@@ -25,16 +26,28 @@ func (s *Scheduler) hasFarmerBot(ctx context.Context, farmID uint32) bool {
 
 	dst := info.farmerTwinID
 
-	service := fmt.Sprintf("farmerbot-%d", farmID)
-	var version string
-	err = s.rmbClient.CallWithSession(ctx, info.farmerTwinID, &service, FarmerBotVersionAction, nil, &version)
+	start := time.Now()
+	_, err = PingFarmerBot(ctx, s.rmbClient, farmID, dst)
 	if err != nil {
-		log.Printf("error while pinging farmerbot on farm %d with farmer twin %d. %s", farmID, dst, err.Error())
+		tflog.Debug(ctx, "error while pinging farmerbot", map[string]interface{}{
+			"farm_id":  farmID,
+			"twin_id":  dst,
+			"error":    err.Error(),
+			"duration": time.Since(start).String(),
+		})
 	}
 
 	return err == nil
 }
 
+// PingFarmerBot asks the farmerbot of the given farm for its version, an error is returned if the farmerbot doesn't answer
+func PingFarmerBot(ctx context.Context, client rmbClient, farmID uint32, farmerTwinID uint32) (string, error) {
+	service := fmt.Sprintf("farmerbot-%d", farmID)
+	var version string
+	err := client.CallWithSession(ctx, farmerTwinID, &service, FarmerBotVersionAction, nil, &version)
+	return version, err
+}
+
 func (n *Scheduler) farmerBotSchedule(ctx context.Context, r *Request) (uint32, error) {
 	ctx, cancel := context.WithTimeout(ctx, rmbTimeout)
 	defer cancel()
@@ -48,11 +61,16 @@ func (n *Scheduler) farmerBotSchedule(ctx context.Context, r *Request) (uint32,
 	var nodeID uint32
 
 	service := fmt.Sprintf("farmerbot-%d", r.FarmID)
+	start := time.Now()
 	if err := n.rmbClient.CallWithSession(ctx, info.farmerTwinID, &service, FarmerBotFindNodeAction, data, &nodeID); err != nil {
 		return 0, err
 	}
 
-	log.Printf("got a node with id %d", nodeID)
+	tflog.Debug(ctx, "farmerbot found a node", map[string]interface{}{
+		"farm_id":  r.FarmID,
+		"node_id":  nodeID,
+		"duration": time.Since(start).String(),
+	})
 	return nodeID, nil
 }
 
@@ -0,0 +1,39 @@
+// Package scheduler provides a simple scheduler interface to request deployments on nodes.
+package scheduler
+
+import (
+	"strings"
+
+	proxyTypes "github.com/threefoldtech/tfgrid-sdk-go/grid-proxy/pkg/types"
+)
+
+// matchesGPU checks if the gpu vendor and device names contain the requested ones, matching the grid proxy filters
+func (r *Request) matchesGPU(gpu proxyTypes.NodeGPU) bool {
+	return strings.Contains(strings.ToLower(gpu.Vendor), strings.ToLower(r.GPUVendor)) &&
+		strings.Contains(strings.ToLower(gpu.Device), strings.ToLower(r.GPUDevice))
+}
+
+// freeGPUs returns the ids of the node gpus matching the request that are neither used by a contract nor consumed by a previous request
+func (node *nodeInfo) freeGPUs(r *Request) []string {
+	ids := make([]string, 0)
+	for _, gpu := range node.Node.GPUs {
+		if gpu.Contract != 0 || node.usedGPUs[gpu.ID] || !r.matchesGPU(gpu) {
+			continue
+		}
+		ids = append(ids, gpu.ID)
+	}
+	return ids
+}
+
+// consumeGPUs marks the requested number of matching gpus of the node as used, and returns their ids
+func (node *nodeInfo) consumeGPUs(r *Request) []string {
+	if r.GPUCount == 0 {
+		return nil
+	}
+
+	ids := node.freeGPUs(r)[:r.GPUCount]
+	for _, id := range ids {
+		node.usedGPUs[id] = true
+	}
+	return ids
+}
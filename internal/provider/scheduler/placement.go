@@ -0,0 +1,142 @@
+// Package scheduler provides a simple scheduler interface to request deployments on nodes.
+package scheduler
+
+import (
+	"context"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Domain is a failure domain of the nodes used by the placement groups
+type Domain string
+
+const (
+	// DomainNode groups the requests by node
+	DomainNode Domain = "node"
+	// DomainFarm groups the requests by farm
+	DomainFarm Domain = "farm"
+	// DomainCountry groups the requests by country
+	DomainCountry Domain = "country"
+	// DomainCity groups the requests by city
+	DomainCity Domain = "city"
+)
+
+// SpreadDomains is the list of the domains a placement group could be spread by
+var SpreadDomains = []string{
+	string(DomainNode),
+	string(DomainFarm),
+	string(DomainCountry),
+	string(DomainCity),
+}
+
+// ColocationDomains is the list of the domains the requests of a placement group could share
+var ColocationDomains = []string{
+	string(DomainNode),
+	string(DomainFarm),
+}
+
+// location is the location of a node
+type location struct {
+	country string
+	city    string
+}
+
+// nodeDomains are the failure domains of a node
+type nodeDomains struct {
+	farmID   uint32
+	location location
+}
+
+// validateGroups makes sure the requests of each placement group have the same placement,
+// and that a placement is only set on grouped requests
+func validateGroups(reqs []Request) error {
+	groups := make(map[string]Request)
+	for _, r := range reqs {
+		if r.Group == "" {
+			if r.SpreadBy != "" || r.ColocateBy != "" {
+				return errors.Errorf("request %s has a placement without a group", r.Name)
+			}
+			continue
+		}
+
+		if r.SpreadBy != "" && r.ColocateBy != "" {
+			return errors.Errorf("request %s of group %s couldn't be both spread and colocated", r.Name, r.Group)
+		}
+
+		first, ok := groups[r.Group]
+		if !ok {
+			groups[r.Group] = r
+			continue
+		}
+
+		if first.SpreadBy != r.SpreadBy || first.ColocateBy != r.ColocateBy {
+			return errors.Errorf("requests %s and %s of group %s have different placements", first.Name, r.Name, r.Group)
+		}
+	}
+	return nil
+}
+
+// nodeDomains returns the failure domains of the given node
+func (n *Scheduler) nodeDomains(ctx context.Context, nodeID uint32) (nodeDomains, error) {
+	if node, ok := n.nodes[nodeID]; ok {
+		return nodeDomains{
+			farmID:   uint32(node.Node.FarmID),
+			location: location{country: node.Node.Country, city: node.Node.City},
+		}, nil
+	}
+
+	node, err := n.gridProxyClient.Node(ctx, nodeID)
+	if err != nil {
+		return nodeDomains{}, errors.Wrapf(err, "couldn't get node %d from the grid proxy", nodeID)
+	}
+
+	return nodeDomains{
+		farmID:   uint32(node.FarmID),
+		location: location{country: node.Country, city: node.City},
+	}, nil
+}
+
+// applyPlacement adds the constraints of the request placement group, given the nodes assigned to the other requests of the group
+func (n *Scheduler) applyPlacement(ctx context.Context, r *Request, groupNodes []uint32) error {
+	if r.SpreadBy == "" && r.ColocateBy == "" {
+		return nil
+	}
+
+	for _, nodeID := range groupNodes {
+		if r.SpreadBy == DomainNode {
+			r.NodeExclude = append(r.NodeExclude, nodeID)
+			continue
+		}
+
+		if r.ColocateBy == DomainNode {
+			r.nodeID = nodeID
+			continue
+		}
+
+		domains, err := n.nodeDomains(ctx, nodeID)
+		if err != nil {
+			return err
+		}
+
+		switch {
+		case r.SpreadBy == DomainFarm:
+			r.excludeFarms = append(r.excludeFarms, domains.farmID)
+		case r.SpreadBy == DomainCountry:
+			r.ExcludeCountries = append(r.ExcludeCountries, domains.location.country)
+		case r.SpreadBy == DomainCity:
+			r.excludeLocations = append(r.excludeLocations, domains.location)
+		case r.ColocateBy == DomainFarm:
+			if r.FarmID != 0 && r.FarmID != domains.farmID {
+				return errors.Errorf("group %s is colocated on farm %d, but the request farm is %d", r.Group, domains.farmID, r.FarmID)
+			}
+			r.FarmID = domains.farmID
+		}
+	}
+	return nil
+}
+
+// sameLocation checks if the two locations are the same city of the same country
+func sameLocation(a, b location) bool {
+	return strings.EqualFold(a.country, b.country) && strings.EqualFold(a.city, b.city)
+}
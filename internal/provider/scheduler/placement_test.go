@@ -0,0 +1,101 @@
+package scheduler
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	proxyTypes "github.com/threefoldtech/tfgrid-sdk-go/grid-proxy/pkg/types"
+)
+
+func newPlacementProxyMock() *GridProxyClientMock {
+	proxy := &GridProxyClientMock{}
+	nodes := []proxyTypes.Node{
+		{NodeID: 1, FarmID: 1, Country: "Belgium", City: "Ghent"},
+		{NodeID: 2, FarmID: 1, Country: "Belgium", City: "Ghent"},
+		{NodeID: 3, FarmID: 2, Country: "Belgium", City: "Brussels"},
+		{NodeID: 4, FarmID: 3, Country: "Egypt", City: "Cairo"},
+	}
+	for _, node := range nodes {
+		proxy.AddNode(uint32(node.NodeID), node)
+	}
+	for _, farmID := range []int{1, 2, 3} {
+		proxy.AddFarm(proxyTypes.Farm{FarmID: farmID})
+	}
+	return proxy
+}
+
+func TestPlacementSpread(t *testing.T) {
+	domains := map[Domain][]uint32{
+		DomainNode:    {1, 2, 3, 4},
+		DomainFarm:    {1, 3, 4},
+		DomainCity:    {1, 3, 4},
+		DomainCountry: {1, 4},
+	}
+
+	for domain, expected := range domains {
+		requests := make([]Request, 0)
+		for _, name := range []string{"r1", "r2", "r3", "r4"}[:len(expected)] {
+			requests = append(requests, Request{Name: name, Group: "ha", SpreadBy: domain})
+		}
+
+		scheduler := NewScheduler(newPlacementProxyMock(), 1, &RMBClientMock{}, StrategySpread)
+		assignment := map[string]uint32{}
+		err := scheduler.ProcessRequests(context.Background(), requests, assignment, map[string][]string{})
+		assert.NoError(t, err, domain)
+
+		nodes := make([]uint32, 0)
+		for _, r := range requests {
+			nodes = append(nodes, assignment[r.Name])
+		}
+		assert.Equal(t, expected, nodes, "each request should be in a different %s", domain)
+
+		scheduler = NewScheduler(newPlacementProxyMock(), 1, &RMBClientMock{}, StrategySpread)
+		requests = append(requests, Request{Name: "extra", Group: "ha", SpreadBy: domain})
+		err = scheduler.ProcessRequests(context.Background(), requests, assignment, map[string][]string{})
+		assert.Error(t, err, "all the %s domains are used by the assigned requests", domain)
+	}
+}
+
+func TestPlacementColocate(t *testing.T) {
+	scheduler := NewScheduler(newPlacementProxyMock(), 1, &RMBClientMock{}, StrategySpread)
+	assignment := map[string]uint32{"r1": 3}
+	err := scheduler.ProcessRequests(context.Background(), []Request{
+		{Name: "r1", Group: "node", ColocateBy: DomainNode},
+		{Name: "r2", Group: "node", ColocateBy: DomainNode},
+		{Name: "r3", Group: "farm", ColocateBy: DomainFarm},
+		{Name: "r4", Group: "farm", ColocateBy: DomainFarm, Distinct: true},
+	}, assignment, map[string][]string{})
+	assert.NoError(t, err)
+	assert.Equal(t, uint32(3), assignment["r2"], "r2 should share the node of the assigned r1")
+	assert.Equal(t, uint32(1), assignment["r3"])
+	assert.Equal(t, uint32(2), assignment["r4"], "r4 should be on another node of the farm of r3")
+
+	scheduler = NewScheduler(newPlacementProxyMock(), 1, &RMBClientMock{}, StrategySpread)
+	err = scheduler.ProcessRequests(context.Background(), []Request{
+		{Name: "r1", Group: "farm", ColocateBy: DomainFarm},
+		{Name: "r2", Group: "farm", ColocateBy: DomainFarm, FarmID: 3},
+	}, map[string]uint32{}, map[string][]string{})
+	assert.Error(t, err, "r2 farm conflicts with the group farm")
+}
+
+func TestValidateGroups(t *testing.T) {
+	assert.NoError(t, validateGroups([]Request{
+		{Name: "r1", Group: "g1", SpreadBy: DomainFarm},
+		{Name: "r2", Group: "g1", SpreadBy: DomainFarm},
+		{Name: "r3", Group: "g2", ColocateBy: DomainNode},
+		{Name: "r4"},
+	}))
+
+	invalid := map[string][]Request{
+		"no group":             {{Name: "r1", SpreadBy: DomainNode}},
+		"spread and colocated": {{Name: "r1", Group: "g", SpreadBy: DomainNode, ColocateBy: DomainFarm}},
+		"different placements": {
+			{Name: "r1", Group: "g", SpreadBy: DomainNode},
+			{Name: "r2", Group: "g", SpreadBy: DomainFarm},
+		},
+	}
+	for key, reqs := range invalid {
+		assert.Error(t, validateGroups(reqs), key)
+	}
+}
@@ -2,6 +2,8 @@
 package scheduler
 
 import (
+	"strings"
+
 	"github.com/threefoldtech/tfgrid-sdk-go/grid-client/zos"
 	proxyTypes "github.com/threefoldtech/tfgrid-sdk-go/grid-proxy/pkg/types"
 )
@@ -24,10 +26,55 @@ type Request struct {
 	Distinct       bool
 	Yggdrasil      bool
 	Wireguard      bool
+	GPUCount       uint32
+	GPUVendor      string
+	GPUDevice      string
+	Country        string
+	City           string
+	Region         string
+	// ExcludeCountries are the countries whose nodes must not be picked
+	ExcludeCountries []string
+	// Group is the placement group of the request, its requests are spread by SpreadBy or share the ColocateBy domain
+	Group      string
+	SpreadBy   Domain
+	ColocateBy Domain
+
+	// the constraints added by the placement group
+	nodeID           uint32
+	excludeFarms     []uint32
+	excludeLocations []location
+}
+
+// farmerBotSupported checks if the request could be scheduled by a farmerbot, which only supports the capacity and flags of the request
+func (r *Request) farmerBotSupported() bool {
+	return r.GPUCount == 0 && r.Country == "" && r.City == "" && r.Region == "" && len(r.ExcludeCountries) == 0 &&
+		r.nodeID == 0 && len(r.excludeFarms) == 0 && len(r.excludeLocations) == 0
+}
+
+// matchesLocation checks if the node location satisfies the request location constraints, matching the grid proxy filters.
+// The node region isn't returned by the grid proxy, so it's only filtered by the grid proxy
+func (r *Request) matchesLocation(node *proxyTypes.Node) bool {
+	if r.Country != "" && !strings.EqualFold(node.Country, r.Country) {
+		return false
+	}
+	if r.City != "" && !strings.EqualFold(node.City, r.City) {
+		return false
+	}
+	for _, country := range r.ExcludeCountries {
+		if strings.EqualFold(node.Country, country) {
+			return false
+		}
+	}
+	for _, l := range r.excludeLocations {
+		if sameLocation(l, location{country: node.Country, city: node.City}) {
+			return false
+		}
+	}
+	return true
 }
 
 func (r *Request) constructFilter(twinID uint64) (f proxyTypes.NodeFilter) {
-	// this filter only lacks certification type, which is validated after.
+	// this filter only lacks certification type, excluded countries, farms and cities, which are validated after.
 	// grid proxy should support filtering a node by certification type.
 	f.Status = []string{statusUP}
 	f.AvailableFor = &twinID
@@ -35,6 +82,19 @@ func (r *Request) constructFilter(twinID uint64) (f proxyTypes.NodeFilter) {
 	if r.FarmID != 0 {
 		f.FarmIDs = []uint64{uint64(r.FarmID)}
 	}
+	if r.nodeID != 0 {
+		nodeID := uint64(r.nodeID)
+		f.NodeID = &nodeID
+	}
+	if r.Country != "" {
+		f.Country = &r.Country
+	}
+	if r.City != "" {
+		f.City = &r.City
+	}
+	if r.Region != "" {
+		f.Region = &r.Region
+	}
 	if r.Capacity.HRU != 0 {
 		f.FreeHRU = &r.Capacity.HRU
 	}
@@ -44,6 +104,10 @@ func (r *Request) constructFilter(twinID uint64) (f proxyTypes.NodeFilter) {
 	if r.Capacity.MRU != 0 {
 		f.FreeMRU = &r.Capacity.MRU
 	}
+	if r.Capacity.CRU != 0 {
+		// grid proxy doesn't support filtering by free cru, it's validated after
+		f.TotalCRU = &r.Capacity.CRU
+	}
 	if r.PublicConfig {
 		f.Domain = &trueVal
 	}
@@ -54,6 +118,18 @@ func (r *Request) constructFilter(twinID uint64) (f proxyTypes.NodeFilter) {
 	if r.Dedicated {
 		f.Rentable = &trueVal
 	}
+	if r.GPUCount != 0 {
+		// the number of gpus matching the vendor and device is validated after
+		count := uint64(r.GPUCount)
+		f.NumGPU = &count
+		f.GpuAvailable = &trueVal
+		if r.GPUVendor != "" {
+			f.GpuVendorName = &r.GPUVendor
+		}
+		if r.GPUDevice != "" {
+			f.GpuDeviceName = &r.GPUDevice
+		}
+	}
 
 	if r.Yggdrasil || r.Wireguard || r.PublicConfig || r.PublicIpsCount != 0 {
 		f.Features = []string{zos.NetworkType, zos.ZMachineType}
@@ -40,7 +40,10 @@ func TestFulfilsFail(t *testing.T) {
 	nodeInfo := nodeInfo{
 		FreeCapacity: &cap,
 		Node: types.Node{
-			FarmID: 1,
+			FarmID:         1,
+			TotalResources: node.TotalResources,
+			Country:        "Belgium",
+			City:           "Ghent",
 			PublicConfig: types.PublicConfig{
 				Ipv4:   "",
 				Domain: "",
@@ -52,10 +55,14 @@ func TestFulfilsFail(t *testing.T) {
 			MRU: 3,
 			SRU: 3,
 			HRU: 3,
+			CRU: 2,
 		},
-		FarmID:         1,
-		PublicIpsCount: 0,
-		PublicConfig:   false,
+		FarmID:           1,
+		PublicIpsCount:   0,
+		PublicConfig:     false,
+		Country:          "belgium",
+		City:             "Ghent",
+		ExcludeCountries: []string{"Egypt"},
 	}
 	farmInfo := farmInfo{
 		freeIPs: 1,
@@ -63,12 +70,17 @@ func TestFulfilsFail(t *testing.T) {
 	assert.Equal(t, nodeInfo.fulfils(&req, farmInfo), true, "this request should be successful")
 
 	violations := map[string]func(r *Request){
-		"mru":              func(r *Request) { r.Capacity.MRU = 4 },
-		"sru":              func(r *Request) { r.Capacity.SRU = 9 },
-		"hru":              func(r *Request) { r.Capacity.HRU = 4 },
-		"farm_id":          func(r *Request) { r.FarmID = 2 },
-		"public_ips_count": func(r *Request) { r.PublicIpsCount = 3 },
-		"public_config":    func(r *Request) { r.PublicConfig = true },
+		"mru":               func(r *Request) { r.Capacity.MRU = 4 },
+		"sru":               func(r *Request) { r.Capacity.SRU = 9 },
+		"hru":               func(r *Request) { r.Capacity.HRU = 4 },
+		"cru":               func(r *Request) { r.Capacity.CRU = 7 },
+		"cru_cores":         func(r *Request) { r.Capacity.CRU = 5 },
+		"farm_id":           func(r *Request) { r.FarmID = 2 },
+		"public_ips_count":  func(r *Request) { r.PublicIpsCount = 3 },
+		"public_config":     func(r *Request) { r.PublicConfig = true },
+		"country":           func(r *Request) { r.Country = "Egypt" },
+		"city":              func(r *Request) { r.City = "Cairo" },
+		"exclude_countries": func(r *Request) { r.ExcludeCountries = []string{"egypt", "belgium"} },
 	}
 	for key, fn := range violations {
 		cp := req
@@ -84,12 +96,16 @@ func TestConstructFilter(t *testing.T) {
 			MRU: 1,
 			SRU: 2,
 			HRU: 3,
+			CRU: 4,
 		},
 		Name:           "a",
 		FarmID:         1,
 		PublicIpsCount: 1,
 		PublicConfig:   false,
 		Certified:      true,
+		Country:        "Belgium",
+		City:           "Ghent",
+		Region:         "Europe",
 	}
 
 	con := r.constructFilter(1)
@@ -97,8 +113,10 @@ func TestConstructFilter(t *testing.T) {
 	assert.Equal(t, *con.FreeMRU, uint64(1), "construct-filter-mru")
 	assert.Equal(t, *con.FreeSRU, uint64(2), "construct-filter-sru")
 	assert.Equal(t, *con.FreeHRU, uint64(3), "construct-filter-hru")
-	assert.Empty(t, con.Country, "construct-filter-country")
-	assert.Empty(t, con.City, "construct-filter-city")
+	assert.Equal(t, *con.TotalCRU, uint64(4), "construct-filter-cru")
+	assert.Equal(t, *con.Country, "Belgium", "construct-filter-country")
+	assert.Equal(t, *con.City, "Ghent", "construct-filter-city")
+	assert.Equal(t, *con.Region, "Europe", "construct-filter-region")
 	assert.Equal(t, con.FarmIDs, []uint64{uint64(r.FarmID)}, "construct-filter-farm-ids")
 	assert.Equal(t, *con.FreeIPs, uint64(1), "construct-filter-free-ips")
 	assert.Empty(t, con.IPv4, "construct-filter-ipv4")
@@ -107,4 +125,40 @@ func TestConstructFilter(t *testing.T) {
 	assert.Empty(t, con.Rentable, "construct-filter-rentable")
 	assert.Empty(t, con.RentedBy, "construct-filter-rented-by")
 	assert.Equal(t, *con.AvailableFor, uint64(1), "construct-filter-available-for")
+	assert.Empty(t, con.NumGPU, "construct-filter-num-gpu")
+	assert.Empty(t, con.GpuAvailable, "construct-filter-gpu-available")
+
+	r.GPUCount = 2
+	r.GPUVendor = "nvidia"
+	con = r.constructFilter(1)
+	assert.Equal(t, *con.NumGPU, uint64(2), "construct-filter-num-gpu")
+	assert.Equal(t, *con.GpuAvailable, true, "construct-filter-gpu-available")
+	assert.Equal(t, *con.GpuVendorName, "nvidia", "construct-filter-gpu-vendor")
+	assert.Empty(t, con.GpuDeviceName, "construct-filter-gpu-device")
+}
+
+func TestFulfilsGPUs(t *testing.T) {
+	cap := freeCapacity(&node)
+	nodeInfo := nodeInfo{
+		FreeCapacity: &cap,
+		Node: types.Node{
+			FarmID: 1,
+			GPUs: []types.NodeGPU{
+				{ID: "0000:0e:00.0/1002/744c", Vendor: "Advanced Micro Devices, Inc. [AMD/ATI]", Device: "Navi 31"},
+				{ID: "0000:0f:00.0/10de/2204", Vendor: "NVIDIA Corporation", Device: "GA102 [GeForce RTX 3090]"},
+				{ID: "0000:10:00.0/10de/2204", Vendor: "NVIDIA Corporation", Device: "GA102 [GeForce RTX 3090]", Contract: 10},
+			},
+		},
+		usedGPUs: map[string]bool{},
+	}
+	farm := farmInfo{}
+
+	assert.True(t, nodeInfo.fulfils(&Request{GPUCount: 2}, farm), "two gpus are free")
+	assert.False(t, nodeInfo.fulfils(&Request{GPUCount: 3}, farm), "gpus used by contracts are not free")
+	assert.True(t, nodeInfo.fulfils(&Request{GPUCount: 1, GPUVendor: "nvidia", GPUDevice: "rtx 3090"}, farm), "vendor and device should match case insensitively")
+	assert.False(t, nodeInfo.fulfils(&Request{GPUCount: 2, GPUVendor: "nvidia"}, farm), "only one nvidia gpu is free")
+
+	r := &Request{GPUCount: 1, GPUVendor: "nvidia"}
+	assert.Equal(t, []string{"0000:0f:00.0/10de/2204"}, nodeInfo.consumeGPUs(r))
+	assert.False(t, nodeInfo.fulfils(r, farm), "the nvidia gpu is consumed")
 }
@@ -4,13 +4,18 @@ package scheduler
 import (
 	"context"
 	"fmt"
-	"math/rand"
+	"strings"
+	"time"
 
+	"github.com/hashicorp/terraform-plugin-log/tflog"
 	"github.com/pkg/errors"
 	proxy "github.com/threefoldtech/tfgrid-sdk-go/grid-proxy/pkg/client"
 	proxyTypes "github.com/threefoldtech/tfgrid-sdk-go/grid-proxy/pkg/types"
 )
 
+// rentedNodesLimit is the maximum number of nodes rented by the twin fetched by the rented first strategy
+const rentedNodesLimit = 50
+
 // NoNodesFoundErr for empty nodes returned from scheduler
 var NoNodesFoundErr = errors.New("couldn't find a node satisfying the given requirements")
 
@@ -25,12 +30,20 @@ type Scheduler struct {
 	twinID          uint64
 	gridProxyClient proxy.Client
 	rmbClient       rmbClient
+	strategy        Strategy
+	// reservedGPUs are the gpus of each node already assigned to requests, which are not deployed yet
+	reservedGPUs map[uint32][]string
 }
 
 // nodeInfo related to scheduling
 type nodeInfo struct {
 	FreeCapacity *Capacity
 	Node         proxyTypes.Node
+	// usedGPUs are the ids of the node gpus consumed by the scheduled requests
+	usedGPUs map[string]bool
+	// regions are the regions the node is known to be in, as the grid proxy node doesn't have a region.
+	// A node is in a region if it was returned by a grid proxy query filtered by this region
+	regions map[string]bool
 }
 
 type farmInfo struct {
@@ -48,26 +61,35 @@ func (node *nodeInfo) fulfils(r *Request, farm farmInfo) bool {
 	if r.Capacity.MRU > node.FreeCapacity.MRU ||
 		r.Capacity.HRU > node.FreeCapacity.HRU ||
 		r.Capacity.SRU > node.FreeCapacity.SRU ||
+		r.Capacity.CRU > node.FreeCapacity.CRU ||
+		r.Capacity.CRU > node.Node.TotalResources.CRU ||
 		(r.FarmID != 0 && node.Node.FarmID != int(r.FarmID)) ||
+		(r.nodeID != 0 && uint32(node.Node.NodeID) != r.nodeID) ||
+		contains(r.excludeFarms, uint32(node.Node.FarmID)) ||
 		(r.PublicConfig && node.Node.PublicConfig.Domain == "") ||
 		(r.PublicIpsCount > uint32(farm.freeIPs)) ||
 		(r.Dedicated && !node.Node.Dedicated) ||
 		(r.Certified && node.Node.CertificationType != "Certified") ||
+		(r.GPUCount > uint32(len(node.freeGPUs(r)))) ||
+		!r.matchesLocation(&node.Node) ||
+		(r.Region != "" && !node.regions[strings.ToLower(r.Region)]) ||
 		contains(r.NodeExclude, uint32(node.Node.NodeID)) {
 		return false
 	}
 	return true
 }
 
-// NewScheduler generates a new scheduler
-func NewScheduler(gridProxyClient proxy.Client, twinID uint64, rmbClient rmbClient) Scheduler {
+// NewScheduler generates a new scheduler picking nodes using the given strategy
+func NewScheduler(gridProxyClient proxy.Client, twinID uint64, rmbClient rmbClient, strategy Strategy) Scheduler {
 	return Scheduler{
 		nodes:           map[uint32]nodeInfo{},
 		gridProxyClient: gridProxyClient,
 
-		twinID:    twinID,
-		farms:     make(map[uint32]farmInfo),
-		rmbClient: rmbClient,
+		twinID:       twinID,
+		farms:        make(map[uint32]farmInfo),
+		rmbClient:    rmbClient,
+		strategy:     strategy,
+		reservedGPUs: make(map[uint32][]string),
 	}
 }
 
@@ -108,31 +130,47 @@ func getPublicIPsCount(publicIPs []proxyTypes.PublicIP) uint64 {
 }
 
 func (n *Scheduler) getNode(ctx context.Context, r *Request) uint32 {
-	nodes := make([]uint32, 0, len(n.nodes))
+	candidates := make([]uint32, 0, len(n.nodes))
 	for node := range n.nodes {
-		nodes = append(nodes, node)
-	}
-	rand.Shuffle(len(nodes), func(i, j int) { nodes[i], nodes[j] = nodes[j], nodes[i] })
-	for _, node := range nodes {
 		farm, err := n.getFarmInfo(ctx, uint32(n.nodes[node].Node.FarmID))
 		if err != nil {
 			continue
 		}
 		nodeInfo := n.nodes[node]
 		if nodeInfo.fulfils(r, farm) {
-			return node
+			candidates = append(candidates, node)
 		}
 	}
-	return 0
+	if len(candidates) == 0 {
+		return 0
+	}
+
+	n.sortCandidates(candidates, r)
+	return candidates[0]
 }
 
-func (n *Scheduler) addNodes(nodes []proxyTypes.Node) {
+// addNodes adds the nodes returned by a grid proxy query to the scheduler nodes, recording that they are in the region the query was filtered by if any
+func (n *Scheduler) addNodes(nodes []proxyTypes.Node, region *string) {
 	for _, node := range nodes {
-		if _, ok := n.nodes[uint32(node.NodeID)]; !ok {
+		if info, ok := n.nodes[uint32(node.NodeID)]; ok {
+			if region != nil {
+				info.regions[strings.ToLower(*region)] = true
+			}
+		} else {
 			cap := freeCapacity(&node)
+			usedGPUs := make(map[string]bool)
+			for _, id := range n.reservedGPUs[uint32(node.NodeID)] {
+				usedGPUs[id] = true
+			}
+			regions := make(map[string]bool)
+			if region != nil {
+				regions[strings.ToLower(*region)] = true
+			}
 			n.nodes[uint32(node.NodeID)] = nodeInfo{
 				FreeCapacity: &cap,
 				Node:         node,
+				usedGPUs:     usedGPUs,
+				regions:      regions,
 			}
 		}
 	}
@@ -140,32 +178,50 @@ func (n *Scheduler) addNodes(nodes []proxyTypes.Node) {
 
 // Schedule makes sure there's at least one node that satisfies the given request
 func (n *Scheduler) Schedule(ctx context.Context, r *Request) (uint32, error) {
-	if r.FarmID != 0 {
+	nodeID, _, err := n.schedule(ctx, r)
+	return nodeID, err
+}
+
+// schedule assigns a node to the request, and returns the ids of the gpus assigned to it.
+// Requests with gpus or location constraints are always scheduled using the grid proxy, as the farmerbot doesn't support them
+func (n *Scheduler) schedule(ctx context.Context, r *Request) (uint32, []string, error) {
+	if r.FarmID != 0 && r.farmerBotSupported() {
 		if n.hasFarmerBot(ctx, r.FarmID) {
-			return n.farmerBotSchedule(ctx, r)
+			nodeID, err := n.farmerBotSchedule(ctx, r)
+			return nodeID, nil, err
 		}
 	}
 	return n.gridProxySchedule(ctx, r)
 }
 
-func (n *Scheduler) gridProxySchedule(ctx context.Context, r *Request) (uint32, error) {
+func (n *Scheduler) gridProxySchedule(ctx context.Context, r *Request) (uint32, []string, error) {
 	f := r.constructFilter(n.twinID)
-	l := proxyTypes.Limit{
-		Size:     10,
-		Page:     1,
-		RetCount: false,
+	l := n.strategy.nodesLimit()
+
+	if n.strategy == StrategyRentedFirst {
+		if err := n.addRentedNodes(ctx, f); err != nil {
+			return 0, nil, err
+		}
 	}
 
 	node := n.getNode(ctx, r)
 	for node == 0 {
+		start := time.Now()
 		nodes, _, err := n.gridProxyClient.Nodes(ctx, f, l)
 		if err != nil {
-			return 0, errors.Wrap(err, "couldn't list nodes from the grid proxy")
+			return 0, nil, errors.Wrap(err, "couldn't list nodes from the grid proxy")
 		}
+		tflog.Debug(ctx, "listed nodes from the grid proxy", map[string]interface{}{
+			"request":  r.Name,
+			"page":     l.Page,
+			"size":     l.Size,
+			"count":    len(nodes),
+			"duration": time.Since(start).String(),
+		})
 		if len(nodes) == 0 {
-			return 0, NoNodesFoundErr
+			return 0, nil, NoNodesFoundErr
 		}
-		n.addNodes(nodes)
+		n.addNodes(nodes, f.Region)
 		node = n.getNode(ctx, r)
 		if l.Page == 1 && l.Size == 10 {
 			l.Page = 2
@@ -173,12 +229,36 @@ func (n *Scheduler) gridProxySchedule(ctx context.Context, r *Request) (uint32,
 			l.Size *= 2
 		}
 	}
-	n.nodes[node].FreeCapacity.consume(r)
-	n.consumePublicIPs(uint32(n.nodes[node].Node.FarmID), r.PublicIpsCount)
-	return node, nil
+	nodeInfo := n.nodes[node]
+	nodeInfo.FreeCapacity.consume(r)
+	gpus := nodeInfo.consumeGPUs(r)
+	n.consumePublicIPs(uint32(nodeInfo.Node.FarmID), r.PublicIpsCount)
+	return node, gpus, nil
+}
+
+// addRentedNodes adds the nodes rented by the twin matching the given filter to the scheduler nodes
+func (n *Scheduler) addRentedNodes(ctx context.Context, f proxyTypes.NodeFilter) error {
+	f.RentedBy = &n.twinID
+	nodes, _, err := n.gridProxyClient.Nodes(ctx, f, proxyTypes.Limit{
+		Size: rentedNodesLimit,
+		Page: 1,
+	})
+	if err != nil {
+		return errors.Wrap(err, "couldn't list rented nodes from the grid proxy")
+	}
+
+	n.addNodes(nodes, f.Region)
+	return nil
 }
 
-func (s *Scheduler) ProcessRequests(ctx context.Context, reqs []Request, assignment map[string]uint32) error {
+// ProcessRequests assigns nodes to the requests missing from the assignment, and records the gpus assigned to the requests asking for gpus.
+// The gpus previously assigned to requests are never assigned again, and the assigned requests are kept on their nodes
+// while still constraining the placement of the other requests of their groups
+func (s *Scheduler) ProcessRequests(ctx context.Context, reqs []Request, assignment map[string]uint32, gpus map[string][]string) error {
+	if err := validateGroups(reqs); err != nil {
+		return err
+	}
+
 	assignedNodes := []uint32{}
 	for _, node := range assignment {
 		if !contains(assignedNodes, node) {
@@ -186,18 +266,48 @@ func (s *Scheduler) ProcessRequests(ctx context.Context, reqs []Request, assignm
 		}
 	}
 
+	for name, ids := range gpus {
+		node := assignment[name]
+		s.reservedGPUs[node] = append(s.reservedGPUs[node], ids...)
+	}
+
+	// the nodes assigned to the requests of each placement group
+	groupNodes := make(map[string][]uint32)
 	for _, r := range reqs {
+		if node, ok := assignment[r.Name]; ok && r.Group != "" {
+			groupNodes[r.Group] = append(groupNodes[r.Group], node)
+		}
+	}
+
+	for _, r := range reqs {
+		if _, ok := assignment[r.Name]; ok {
+			continue
+		}
 		if r.Distinct {
 			r.NodeExclude = append(r.NodeExclude, assignedNodes...)
 		}
-		node, err := s.Schedule(ctx, &r)
+		if err := s.applyPlacement(ctx, &r, groupNodes[r.Group]); err != nil {
+			return errors.Wrapf(err, "couldn't apply the placement group of request %s", r.Name)
+		}
+		node, nodeGPUs, err := s.schedule(ctx, &r)
 		if err != nil {
 			return errors.Wrapf(err, "couldn't schedule request %s", r.Name)
 		}
+		tflog.Debug(ctx, "scheduled request", map[string]interface{}{
+			"request": r.Name,
+			"node_id": node,
+			"gpus":    nodeGPUs,
+		})
 		assignment[r.Name] = node
+		if len(nodeGPUs) != 0 {
+			gpus[r.Name] = nodeGPUs
+		}
 		if !contains(assignedNodes, node) {
 			assignedNodes = append(assignedNodes, node)
 		}
+		if r.Group != "" {
+			groupNodes[r.Group] = append(groupNodes[r.Group], node)
+		}
 	}
 	return nil
 }
@@ -3,6 +3,7 @@ package scheduler
 import (
 	"context"
 	"fmt"
+	"strings"
 	"testing"
 
 	"github.com/pkg/errors"
@@ -71,6 +72,10 @@ func (m *GridProxyClientMock) Node(ctx context.Context, nodeID uint32) (res prox
 	for _, node := range m.nodes {
 		if uint32(node.NodeID) == nodeID {
 			res = proxyTypes.NodeWithNestedCapacity{
+				NodeID:  node.NodeID,
+				FarmID:  node.FarmID,
+				Country: node.Country,
+				City:    node.City,
 				Capacity: proxyTypes.CapacityResult{
 					Total: node.TotalResources,
 					Used:  node.UsedResources,
@@ -116,7 +121,7 @@ func TestSchedulerEmpty(t *testing.T) {
 	rmbClient := &RMBClientMock{
 		hasFarmerBot: false,
 	}
-	scheduler := NewScheduler(proxy, 1, rmbClient)
+	scheduler := NewScheduler(proxy, 1, rmbClient, StrategyRandom)
 	_, err := scheduler.Schedule(context.Background(), &Request{
 		Capacity: Capacity{
 			MRU: 1,
@@ -168,7 +173,7 @@ func TestSchedulerSuccess(t *testing.T) {
 			},
 		},
 	})
-	scheduler := NewScheduler(proxy, 1, rmbClient)
+	scheduler := NewScheduler(proxy, 1, rmbClient, StrategyRandom)
 	nodeID, err := scheduler.Schedule(context.Background(), &Request{
 		Capacity: Capacity{
 			HRU: 3,
@@ -222,7 +227,7 @@ func TestSchedulerSuccessOn4thPage(t *testing.T) {
 			},
 		},
 	})
-	scheduler := NewScheduler(proxy, 1, rmbClient)
+	scheduler := NewScheduler(proxy, 1, rmbClient, StrategyRandom)
 	nodeID, err := scheduler.Schedule(context.Background(), &Request{
 		Capacity: Capacity{
 			HRU: 3,
@@ -285,7 +290,7 @@ func TestSchedulerFailure(t *testing.T) {
 		"domain": func(r *Request) { r.PublicConfig = true },
 	}
 	for key, fn := range violations {
-		scheduler := NewScheduler(proxy, 1, rmbClient)
+		scheduler := NewScheduler(proxy, 1, rmbClient, StrategyRandom)
 		cp := req
 		fn(&cp)
 		_, err := scheduler.Schedule(context.Background(), &cp)
@@ -326,7 +331,7 @@ func TestSchedulerFailureAfterSuccess(t *testing.T) {
 			},
 		},
 	})
-	scheduler := NewScheduler(proxy, 1, rmbClient)
+	scheduler := NewScheduler(proxy, 1, rmbClient, StrategyRandom)
 	nodeID, err := scheduler.Schedule(context.Background(), &Request{
 		Capacity: Capacity{
 			HRU: 2,
@@ -391,7 +396,7 @@ func TestSchedulerSuccessAfterSuccess(t *testing.T) {
 			},
 		},
 	})
-	scheduler := NewScheduler(proxy, 1, rmbClient)
+	scheduler := NewScheduler(proxy, 1, rmbClient, StrategyRandom)
 	nodeID, err := scheduler.Schedule(context.Background(), &Request{
 		Capacity: Capacity{
 			HRU: 2,
@@ -440,7 +445,7 @@ func TestExcludingNodes(t *testing.T) {
 	proxy.AddFarm(proxyTypes.Farm{
 		FarmID: 1,
 	})
-	scheduler := NewScheduler(proxy, 1, rmbClient)
+	scheduler := NewScheduler(proxy, 1, rmbClient, StrategyRandom)
 	node, err := scheduler.Schedule(context.Background(), &Request{
 		NodeExclude: []uint32{1},
 	})
@@ -488,11 +493,209 @@ func TestDistinctNodes(t *testing.T) {
 			Distinct: true,
 		},
 	}
-	scheduler := NewScheduler(proxy, 1, rmbClient)
+	scheduler := NewScheduler(proxy, 1, rmbClient, StrategyRandom)
 	assignment := map[string]uint32{}
-	err := scheduler.ProcessRequests(context.Background(), requests, assignment)
+	err := scheduler.ProcessRequests(context.Background(), requests, assignment, map[string][]string{})
 	assert.NoError(t, err)
 	assert.NotEqual(t, assignment["r1"], assignment["r2"])
 	assert.NotEqual(t, assignment["r1"], assignment["r3"])
 	assert.NotEqual(t, assignment["r2"], assignment["r3"])
 }
+
+func TestSchedulerSaturatedCRU(t *testing.T) {
+	proxy := &GridProxyClientMock{}
+	rmbClient := &RMBClientMock{
+		hasFarmerBot: false,
+	}
+	proxy.AddNode(1, proxyTypes.Node{
+		NodeID: 1,
+		FarmID: 1,
+		TotalResources: proxyTypes.Capacity{
+			CRU: 4,
+		},
+		UsedResources: proxyTypes.Capacity{
+			CRU: 8,
+		},
+	})
+	proxy.AddNode(2, proxyTypes.Node{
+		NodeID: 2,
+		FarmID: 1,
+		TotalResources: proxyTypes.Capacity{
+			CRU: 4,
+		},
+	})
+	proxy.AddFarm(proxyTypes.Farm{
+		FarmID: 1,
+	})
+	scheduler := NewScheduler(proxy, 1, rmbClient, StrategyRandom)
+	nodeID, err := scheduler.Schedule(context.Background(), &Request{
+		Name: "req",
+		Capacity: Capacity{
+			CRU: 1,
+		},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, nodeID, uint32(2), "node 1 cpus are saturated")
+}
+
+func TestSchedulerConsumesCRU(t *testing.T) {
+	proxy := &GridProxyClientMock{}
+	rmbClient := &RMBClientMock{
+		hasFarmerBot: false,
+	}
+	proxy.AddNode(1, proxyTypes.Node{
+		NodeID: 1,
+		FarmID: 1,
+		TotalResources: proxyTypes.Capacity{
+			CRU: 2,
+		},
+	})
+	proxy.AddNode(2, proxyTypes.Node{
+		NodeID: 2,
+		FarmID: 1,
+		TotalResources: proxyTypes.Capacity{
+			CRU: 2,
+		},
+	})
+	proxy.AddFarm(proxyTypes.Farm{
+		FarmID: 1,
+	})
+	requests := []Request{
+		{
+			Name:     "r1",
+			Capacity: Capacity{CRU: 2},
+		},
+		{
+			Name:     "r2",
+			Capacity: Capacity{CRU: 2},
+		},
+		{
+			Name:     "r3",
+			Capacity: Capacity{CRU: 2},
+		},
+	}
+	scheduler := NewScheduler(proxy, 1, rmbClient, StrategyPack)
+	assignment := map[string]uint32{}
+	err := scheduler.ProcessRequests(context.Background(), requests, assignment, map[string][]string{})
+	assert.NoError(t, err)
+	assert.Equal(t, assignment["r1"], uint32(1))
+	assert.Equal(t, assignment["r2"], uint32(1), "node 1 could hold twice its cores")
+	assert.Equal(t, assignment["r3"], uint32(2), "node 1 cpus are consumed by the previous requests")
+}
+
+func TestSchedulerAssignsGPUs(t *testing.T) {
+	proxy := &GridProxyClientMock{}
+	rmbClient := &RMBClientMock{
+		hasFarmerBot: false,
+	}
+	proxy.AddNode(1, proxyTypes.Node{
+		NodeID: 1,
+		FarmID: 1,
+		GPUs: []proxyTypes.NodeGPU{
+			{ID: "0000:0e:00.0/10de/2204", Vendor: "NVIDIA Corporation"},
+			{ID: "0000:0f:00.0/10de/2204", Vendor: "NVIDIA Corporation"},
+			{ID: "0000:10:00.0/10de/2204", Vendor: "NVIDIA Corporation"},
+		},
+	})
+	proxy.AddFarm(proxyTypes.Farm{
+		FarmID: 1,
+	})
+	requests := []Request{
+		{
+			Name:     "r2",
+			GPUCount: 1,
+		},
+		{
+			Name:     "r3",
+			GPUCount: 1,
+		},
+		{
+			Name: "r4",
+		},
+	}
+	scheduler := NewScheduler(proxy, 1, rmbClient, StrategyRandom)
+	assignment := map[string]uint32{"r1": 1}
+	gpus := map[string][]string{"r1": {"0000:0e:00.0/10de/2204"}}
+	err := scheduler.ProcessRequests(context.Background(), requests, assignment, gpus)
+	assert.NoError(t, err)
+	assert.Equal(t, gpus["r2"], []string{"0000:0f:00.0/10de/2204"}, "the gpu of the assigned request should be reserved")
+	assert.Equal(t, gpus["r3"], []string{"0000:10:00.0/10de/2204"})
+	assert.NotContains(t, gpus, "r4", "requests without gpus shouldn't be assigned gpus")
+
+	scheduler = NewScheduler(proxy, 1, rmbClient, StrategyRandom)
+	err = scheduler.ProcessRequests(context.Background(), []Request{{Name: "r5", GPUCount: 1}}, assignment, gpus)
+	assert.Error(t, err, "all gpus are assigned")
+}
+
+func TestSchedulerExcludesCountries(t *testing.T) {
+	proxy := &GridProxyClientMock{}
+	rmbClient := &RMBClientMock{
+		hasFarmerBot: true,
+		nodeID:       1,
+	}
+	proxy.AddNode(1, proxyTypes.Node{
+		NodeID:  1,
+		FarmID:  1,
+		Country: "Egypt",
+	})
+	proxy.AddNode(2, proxyTypes.Node{
+		NodeID:  2,
+		FarmID:  1,
+		Country: "Belgium",
+	})
+	proxy.AddFarm(proxyTypes.Farm{
+		FarmID: 1,
+	})
+	scheduler := NewScheduler(proxy, 1, rmbClient, StrategyRandom)
+	nodeID, err := scheduler.Schedule(context.Background(), &Request{
+		Name:             "req",
+		FarmID:           1,
+		ExcludeCountries: []string{"egypt"},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, nodeID, uint32(2), "nodes in excluded countries shouldn't be picked, even by the farmerbot")
+}
+
+// regionsGridProxyClientMock is a grid proxy returning only the nodes of the region the query is filtered by
+type regionsGridProxyClientMock struct {
+	GridProxyClientMock
+	regions map[uint32]string
+}
+
+func (m *regionsGridProxyClientMock) Nodes(ctx context.Context, filter proxyTypes.NodeFilter, pagination proxyTypes.Limit) (res []proxyTypes.Node, totalCount int, err error) {
+	nodes, totalCount, err := m.GridProxyClientMock.Nodes(ctx, filter, pagination)
+	if filter.Region == nil {
+		return nodes, totalCount, err
+	}
+
+	res = make([]proxyTypes.Node, 0)
+	for _, node := range nodes {
+		if strings.EqualFold(m.regions[uint32(node.NodeID)], *filter.Region) {
+			res = append(res, node)
+		}
+	}
+	return res, len(res), err
+}
+
+func TestSchedulerRegionWithCachedNodes(t *testing.T) {
+	proxy := &regionsGridProxyClientMock{regions: map[uint32]string{1: "Europe", 2: "Africa"}}
+	proxy.AddNode(1, proxyTypes.Node{NodeID: 1, FarmID: 1})
+	proxy.AddNode(2, proxyTypes.Node{NodeID: 2, FarmID: 1})
+	proxy.AddFarm(proxyTypes.Farm{FarmID: 1})
+
+	reqs := []Request{
+		{Name: "europe", Region: "Europe"},
+		{Name: "africa", Region: "Africa"},
+		{Name: "any"},
+		{Name: "europe_again", Region: "europe"},
+	}
+
+	scheduler := NewScheduler(proxy, 1, &RMBClientMock{}, StrategySpread)
+	assignment := map[string]uint32{}
+	err := scheduler.ProcessRequests(context.Background(), reqs, assignment, map[string][]string{})
+	assert.NoError(t, err)
+	assert.Equal(t, uint32(1), assignment["europe"])
+	assert.Equal(t, uint32(2), assignment["africa"], "a cached node of another region shouldn't be picked")
+	assert.Contains(t, []uint32{1, 2}, assignment["any"])
+	assert.Equal(t, uint32(1), assignment["europe_again"], "a cached node returned for the region should be reused")
+}
@@ -0,0 +1,151 @@
+// Package scheduler provides a simple scheduler interface to request deployments on nodes.
+package scheduler
+
+import (
+	"math/rand"
+	"sort"
+
+	proxyTypes "github.com/threefoldtech/tfgrid-sdk-go/grid-proxy/pkg/types"
+)
+
+// Strategy is the strategy used by the scheduler to pick a node among the eligible nodes
+type Strategy string
+
+const (
+	// StrategyRandom picks a random eligible node
+	StrategyRandom Strategy = "random"
+	// StrategySpread picks the least loaded eligible node
+	StrategySpread Strategy = "spread"
+	// StrategyPack picks the most loaded eligible node
+	StrategyPack Strategy = "pack"
+	// StrategyCheapest picks the eligible node with the lowest price for the request
+	StrategyCheapest Strategy = "cheapest"
+	// StrategyRentedFirst picks an eligible node rented by the twin if any
+	StrategyRentedFirst Strategy = "rented_first"
+)
+
+// Strategies is the list of the supported strategies
+var Strategies = []string{
+	string(StrategyRandom),
+	string(StrategySpread),
+	string(StrategyPack),
+	string(StrategyCheapest),
+	string(StrategyRentedFirst),
+}
+
+// nodesLimit returns the grid proxy pagination used to list the candidate nodes of the strategy,
+// so the best candidates are fetched first
+func (s Strategy) nodesLimit() proxyTypes.Limit {
+	l := proxyTypes.Limit{
+		Size: 10,
+		Page: 1,
+	}
+
+	switch s {
+	case StrategySpread:
+		l.SortBy, l.SortOrder = "used_mru", proxyTypes.SortOrderAsc
+	case StrategyPack:
+		l.SortBy, l.SortOrder = "used_mru", proxyTypes.SortOrderDesc
+	case StrategyCheapest:
+		l.SortBy, l.SortOrder = "price_usd", proxyTypes.SortOrderAsc
+	}
+
+	return l
+}
+
+// load returns the used fraction of the node capacity, considering its most used resource
+func (node *nodeInfo) load() float64 {
+	total := node.Node.TotalResources
+	resources := []struct{ free, total uint64 }{
+		{node.FreeCapacity.CRU, total.CRU * cpuOverprovisionFactor},
+		{node.FreeCapacity.MRU, uint64(total.MRU)},
+		{node.FreeCapacity.SRU, uint64(total.SRU)},
+		{node.FreeCapacity.HRU, uint64(total.HRU)},
+	}
+
+	var load float64
+	for _, r := range resources {
+		if r.total == 0 {
+			continue
+		}
+		load = max(load, 1-float64(r.free)/float64(r.total))
+	}
+
+	return load
+}
+
+// rentedBy checks if the node is rented by the given twin
+func (node *nodeInfo) rentedBy(twinID uint64) bool {
+	return node.Node.Rented && uint64(node.Node.RentedByTwinID) == twinID
+}
+
+// price returns the monthly price in USD of placing the request on the node. Capacity on nodes rented by the twin is already paid by the rent contract,
+// dedicated requests rent the whole node, and shared requests pay for their share of the node
+func (node *nodeInfo) price(r *Request, twinID uint64) float64 {
+	if node.rentedBy(twinID) {
+		return 0
+	}
+
+	if r.Dedicated {
+		return node.Node.PriceUsd
+	}
+
+	total := node.Node.TotalResources
+	resources := []struct{ requested, total uint64 }{
+		{r.Capacity.CRU, total.CRU},
+		{r.Capacity.MRU, uint64(total.MRU)},
+		{r.Capacity.SRU, uint64(total.SRU)},
+		{r.Capacity.HRU, uint64(total.HRU)},
+	}
+
+	var share float64
+	for _, res := range resources {
+		if res.total == 0 {
+			continue
+		}
+		share = max(share, float64(res.requested)/float64(res.total))
+	}
+
+	return node.Node.PriceUsd * share
+}
+
+// score returns the score of the node for the request using the strategy, the lower the better
+func (s Strategy) score(node *nodeInfo, r *Request, twinID uint64) float64 {
+	switch s {
+	case StrategySpread:
+		return node.load()
+	case StrategyPack:
+		return -node.load()
+	case StrategyCheapest:
+		return node.price(r, twinID)
+	case StrategyRentedFirst:
+		if node.rentedBy(twinID) {
+			return 0
+		}
+		return 1
+	default:
+		return 0
+	}
+}
+
+// sortCandidates orders the eligible nodes from the best to the worst for the request.
+// Nodes are shuffled for the random strategy, otherwise they are ordered by their score then their id, so the result is deterministic
+func (n *Scheduler) sortCandidates(candidates []uint32, r *Request) {
+	if n.strategy == StrategyRandom || n.strategy == "" {
+		rand.Shuffle(len(candidates), func(i, j int) { candidates[i], candidates[j] = candidates[j], candidates[i] })
+		return
+	}
+
+	scores := make(map[uint32]float64, len(candidates))
+	for _, nodeID := range candidates {
+		node := n.nodes[nodeID]
+		scores[nodeID] = n.strategy.score(&node, r, n.twinID)
+	}
+
+	sort.Slice(candidates, func(i, j int) bool {
+		if scores[candidates[i]] != scores[candidates[j]] {
+			return scores[candidates[i]] < scores[candidates[j]]
+		}
+		return candidates[i] < candidates[j]
+	})
+}
@@ -0,0 +1,125 @@
+package scheduler
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	proxyTypes "github.com/threefoldtech/tfgrid-sdk-go/grid-proxy/pkg/types"
+	"github.com/threefoldtech/zos/pkg/gridtypes"
+)
+
+func newStrategyProxyMock(nodes ...proxyTypes.Node) *GridProxyClientMock {
+	proxy := &GridProxyClientMock{}
+	for _, node := range nodes {
+		node.FarmID = 1
+		proxy.AddNode(uint32(node.NodeID), node)
+	}
+	proxy.AddFarm(proxyTypes.Farm{
+		FarmID: 1,
+	})
+	return proxy
+}
+
+func nodeWithUsedMRU(nodeID int, usedMRU gridtypes.Unit) proxyTypes.Node {
+	return proxyTypes.Node{
+		NodeID: nodeID,
+		TotalResources: proxyTypes.Capacity{
+			MRU: 10,
+		},
+		UsedResources: proxyTypes.Capacity{
+			MRU: usedMRU,
+		},
+	}
+}
+
+func TestStrategySpread(t *testing.T) {
+	proxy := newStrategyProxyMock(nodeWithUsedMRU(1, 8), nodeWithUsedMRU(2, 2), nodeWithUsedMRU(3, 5))
+	scheduler := NewScheduler(proxy, 1, &RMBClientMock{}, StrategySpread)
+
+	nodeID, err := scheduler.Schedule(context.Background(), &Request{
+		Name:     "req",
+		Capacity: Capacity{MRU: 1},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, uint32(2), nodeID, "the least loaded node should be picked")
+}
+
+func TestStrategyPack(t *testing.T) {
+	proxy := newStrategyProxyMock(nodeWithUsedMRU(1, 8), nodeWithUsedMRU(2, 2), nodeWithUsedMRU(3, 5))
+	scheduler := NewScheduler(proxy, 1, &RMBClientMock{}, StrategyPack)
+
+	nodeID, err := scheduler.Schedule(context.Background(), &Request{
+		Name:     "req",
+		Capacity: Capacity{MRU: 1},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, uint32(1), nodeID, "the most loaded node should be picked")
+
+	nodeID, err = scheduler.Schedule(context.Background(), &Request{
+		Name:     "req2",
+		Capacity: Capacity{MRU: 3},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, uint32(3), nodeID, "the most loaded node that still fits should be picked")
+}
+
+func TestStrategyCheapest(t *testing.T) {
+	expensive := nodeWithUsedMRU(1, 0)
+	expensive.PriceUsd = 100
+	cheap := nodeWithUsedMRU(2, 0)
+	cheap.PriceUsd = 50
+	proxy := newStrategyProxyMock(expensive, cheap)
+	scheduler := NewScheduler(proxy, 1, &RMBClientMock{}, StrategyCheapest)
+
+	nodeID, err := scheduler.Schedule(context.Background(), &Request{
+		Name:     "req",
+		Capacity: Capacity{MRU: 5},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, uint32(2), nodeID, "the cheapest node should be picked")
+
+	rented := nodeWithUsedMRU(3, 0)
+	rented.PriceUsd = 200
+	rented.Rented = true
+	rented.RentedByTwinID = 1
+	proxy = newStrategyProxyMock(expensive, cheap, rented)
+	scheduler = NewScheduler(proxy, 1, &RMBClientMock{}, StrategyCheapest)
+
+	nodeID, err = scheduler.Schedule(context.Background(), &Request{
+		Name:     "req",
+		Capacity: Capacity{MRU: 5},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, uint32(3), nodeID, "capacity on a node rented by the twin is already paid")
+}
+
+func TestStrategyRentedFirst(t *testing.T) {
+	rentedByOther := nodeWithUsedMRU(2, 0)
+	rentedByOther.Rented = true
+	rentedByOther.RentedByTwinID = 7
+	rentedByTwin := nodeWithUsedMRU(3, 0)
+	rentedByTwin.Rented = true
+	rentedByTwin.RentedByTwinID = 1
+	proxy := newStrategyProxyMock(nodeWithUsedMRU(1, 0), rentedByOther, rentedByTwin)
+	scheduler := NewScheduler(proxy, 1, &RMBClientMock{}, StrategyRentedFirst)
+
+	nodeID, err := scheduler.Schedule(context.Background(), &Request{
+		Name: "req",
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, uint32(3), nodeID, "the node rented by the twin should be picked")
+}
+
+func TestStrategyDeterministic(t *testing.T) {
+	proxy := newStrategyProxyMock(nodeWithUsedMRU(3, 0), nodeWithUsedMRU(1, 0), nodeWithUsedMRU(2, 0))
+
+	for i := 0; i < 10; i++ {
+		scheduler := NewScheduler(proxy, 1, &RMBClientMock{}, StrategySpread)
+		nodeID, err := scheduler.Schedule(context.Background(), &Request{
+			Name: "req",
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, uint32(1), nodeID, "ties should be broken by the node id")
+	}
+}
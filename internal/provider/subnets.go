@@ -0,0 +1,195 @@
+// Package provider is the terraform provider
+package provider
+
+import (
+	"context"
+	"slices"
+	"strconv"
+	"sync"
+
+	"github.com/hashicorp/go-multierror"
+	"github.com/pkg/errors"
+	"github.com/threefoldtech/tfgrid-sdk-go/grid-client/deployer"
+	"github.com/threefoldtech/tfgrid-sdk-go/grid-client/workloads"
+	"github.com/threefoldtech/tfgrid-sdk-go/grid-client/zos"
+)
+
+// subnetsWorkers is the maximum number of network deployments fetched concurrently while rebuilding the networks subnets
+const subnetsWorkers = 10
+
+// subnetsLock serializes merging subnets into the networks state, since resources are processed concurrently
+var subnetsLock sync.Mutex
+
+// recordNetworkSubnets merges the given node subnets of a network into the client's networks state.
+// It is used to rebuild the networks state from the nodes ip ranges kept in the terraform state of every managed resource.
+func recordNetworkSubnets(tfPluginClient *deployer.TFPluginClient, networkName string, nodesSubnets map[uint32]string) {
+	if len(networkName) == 0 || len(nodesSubnets) == 0 {
+		return
+	}
+
+	subnetsLock.Lock()
+	defer subnetsLock.Unlock()
+
+	nodesIPRange := make(map[uint32]zos.IPNet)
+	network := tfPluginClient.State.Networks.GetNetwork(networkName)
+	for nodeID, subnet := range network.Subnets {
+		if ipRange, err := zos.ParseIPNet(subnet); err == nil {
+			nodesIPRange[nodeID] = ipRange
+		}
+	}
+
+	for nodeID, subnet := range nodesSubnets {
+		if ipRange, err := zos.ParseIPNet(subnet); err == nil {
+			nodesIPRange[nodeID] = ipRange
+		}
+	}
+
+	tfPluginClient.State.Networks.UpdateNetworkSubnets(networkName, nodesIPRange)
+}
+
+// ensureNetworkSubnets makes sure the subnets of the given nodes in a network are known,
+// otherwise the network subnets are rebuilt from the twin's network contracts on the grid.
+func ensureNetworkSubnets(ctx context.Context, tfPluginClient *deployer.TFPluginClient, networkName string, nodeIDs ...uint32) error {
+	if len(networkName) == 0 {
+		return nil
+	}
+
+	network := tfPluginClient.State.Networks.GetNetwork(networkName)
+
+	missing := false
+	for _, nodeID := range nodeIDs {
+		if len(network.GetNodeSubnet(nodeID)) == 0 {
+			missing = true
+			break
+		}
+	}
+
+	if !missing {
+		return nil
+	}
+
+	net, err := loadNetworkFromGrid(ctx, tfPluginClient, networkName)
+	if err != nil {
+		return errors.Wrapf(err, "couldn't load subnets of network '%s' from the grid", networkName)
+	}
+
+	nodesSubnets := make(map[uint32]string)
+	for nodeID, ipRange := range net.GetNodesIPRange() {
+		nodesSubnets[nodeID] = ipRange.String()
+	}
+	recordNetworkSubnets(tfPluginClient, networkName, nodesSubnets)
+
+	return nil
+}
+
+// networkContract is a network node contract of the twin
+type networkContract struct {
+	name       string
+	nodeID     uint32
+	contractID uint64
+}
+
+// nodeNetworkSubnets returns the node subnets of the network workload, other workloads have no subnets
+func nodeNetworkSubnets(wl zos.Workload, nodeID uint32) (map[uint32]string, error) {
+	var nodesIPRange map[uint32]zos.IPNet
+	switch wl.Type {
+	case zos.NetworkType:
+		n, err := workloads.NewNetworkFromWorkload(wl, nodeID)
+		if err != nil {
+			return nil, err
+		}
+		nodesIPRange = n.GetNodesIPRange()
+	case zos.NetworkLightType:
+		n, err := workloads.NewNetworkLightFromWorkload(wl, nodeID)
+		if err != nil {
+			return nil, err
+		}
+		nodesIPRange = n.GetNodesIPRange()
+	}
+
+	nodesSubnets := make(map[uint32]string)
+	for id, ipRange := range nodesIPRange {
+		nodesSubnets[id] = ipRange.String()
+	}
+	return nodesSubnets, nil
+}
+
+// rebuildNetworksSubnets merges the subnets of all the twin's network contracts on the grid into the client's networks state.
+// It's called when the provider is configured, so plans and reads see the subnets reserved by every network of the twin,
+// not only the ones kept in the state backend. The subnets of the nodes that couldn't be reached are returned as errors.
+func rebuildNetworksSubnets(ctx context.Context, tfPluginClient *deployer.TFPluginClient) error {
+	contracts, err := tfPluginClient.ContractsGetter.ListContractsByTwinID([]string{contractStateCreated, contractStateGracePeriod})
+	if err != nil {
+		return errors.Wrapf(err, "couldn't list contracts of twin %d", tfPluginClient.TwinID)
+	}
+
+	networkContracts := make([]networkContract, 0)
+	for _, contract := range contracts.NodeContracts {
+		deploymentData, err := workloads.ParseDeploymentData(contract.DeploymentData)
+		if err != nil || !slices.Contains([]string{workloads.NetworkType, zos.NetworkLightType}, deploymentData.Type) {
+			continue
+		}
+
+		contractID, err := strconv.ParseUint(contract.ContractID, 10, 64)
+		if err != nil {
+			return errors.Wrapf(err, "couldn't parse contract id '%s'", contract.ContractID)
+		}
+
+		networkContracts = append(networkContracts, networkContract{
+			name:       deploymentData.Name,
+			nodeID:     contract.NodeID,
+			contractID: contractID,
+		})
+	}
+
+	var (
+		errs error
+		mu   sync.Mutex
+		wg   sync.WaitGroup
+	)
+	sem := make(chan struct{}, subnetsWorkers)
+	for _, contract := range networkContracts {
+		wg.Add(1)
+		go func(contract networkContract) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			if err := rebuildNetworkContractSubnets(ctx, tfPluginClient, contract); err != nil {
+				mu.Lock()
+				errs = multierror.Append(errs, err)
+				mu.Unlock()
+			}
+		}(contract)
+	}
+	wg.Wait()
+
+	return errs
+}
+
+// rebuildNetworkContractSubnets merges the subnets of the network deployment of the given contract into the client's networks state
+func rebuildNetworkContractSubnets(ctx context.Context, tfPluginClient *deployer.TFPluginClient, contract networkContract) error {
+	nodeClient, err := tfPluginClient.NcPool.GetNodeClient(tfPluginClient.SubstrateConn, contract.nodeID)
+	if err != nil {
+		return errors.Wrapf(err, "failed to get node client '%d'", contract.nodeID)
+	}
+
+	dl, err := nodeClient.DeploymentGet(ctx, contract.contractID)
+	if err != nil {
+		return errors.Wrapf(err, "couldn't get network deployment %d from node %d", contract.contractID, contract.nodeID)
+	}
+
+	for _, wl := range dl.Workloads {
+		if wl.Name != contract.name {
+			continue
+		}
+
+		nodesSubnets, err := nodeNetworkSubnets(wl, contract.nodeID)
+		if err != nil {
+			return errors.Wrapf(err, "couldn't load network '%s' subnets from node %d", contract.name, contract.nodeID)
+		}
+		recordNetworkSubnets(tfPluginClient, contract.name, nodesSubnets)
+	}
+
+	return nil
+}
@@ -0,0 +1,174 @@
+// Package provider is the terraform provider
+package provider
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/hashicorp/go-multierror"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	client "github.com/threefoldtech/tfgrid-sdk-go/grid-client/node"
+	"github.com/threefoldtech/tfgrid-sdk-go/grid-client/subi"
+	"github.com/threefoldtech/tfgrid-sdk-go/grid-client/workloads"
+)
+
+// vmConfigAttributes are the grid_vm attributes that are mapped directly into the vm workload
+var vmConfigAttributes = []string{
+	"name", "flist", "flist_checksum", "publicip", "publicip6", "ip", "mycelium_ip_seed", "cpu", "memory",
+	"rootfs_size", "entrypoint", "mounts", "env_vars", "planetary", "corex", "gpus", "description",
+}
+
+// vmComputedAttributes are the grid_vm attributes that are computed from the deployed vm workload
+var vmComputedAttributes = []string{
+	"computedip", "computedip6", "planetary_ip", "mycelium_ip", "console_url",
+}
+
+// newVMDeploymentFromSchema reads the vm resource configuration data from the schema.ResourceData, converts them into
+// a deployment holding the vm and its disks, and returns this deployment.
+func newVMDeploymentFromSchema(ctx context.Context, d *schema.ResourceData, ncPool client.NodeClientGetter, sub subi.SubstrateExt) (*workloads.Deployment, error) {
+	networkName := d.Get("network_name").(string)
+	nodeID := uint32(d.Get("node").(int))
+
+	light, err := isZosLight(ctx, nodeID, ncPool, sub)
+	if err != nil {
+		return nil, err
+	}
+
+	name := d.Get("name").(string)
+	solutionType := d.Get("solution_type").(string)
+	if solutionType == "" {
+		solutionType = fmt.Sprintf("vm/%s", name)
+	}
+
+	disks, err := newDisksFromMaps(d.Get("disks").([]interface{}))
+	if err != nil {
+		return nil, err
+	}
+
+	vmMap := make(map[string]interface{})
+	for _, attr := range vmConfigAttributes {
+		vmMap[attr] = d.Get(attr)
+	}
+
+	zlogs := make([]interface{}, 0)
+	for _, output := range d.Get("zlogs").([]interface{}) {
+		zlogs = append(zlogs, map[string]interface{}{"zmachine": name, "output": output})
+	}
+	vmMap["zlogs"] = zlogs
+
+	vms, vmsLight, err := newVMsFromMaps([]interface{}{vmMap}, networkName, nodeID, light)
+	if err != nil {
+		return nil, err
+	}
+
+	solutionProviderVal := uint64(d.Get("solution_provider").(int))
+	var solutionProvider *uint64
+	if solutionProviderVal != 0 {
+		solutionProvider = &solutionProviderVal
+	}
+
+	var contractID uint64
+	nodeDeploymentID := map[uint32]uint64{}
+	if d.Id() != "" {
+		contractID, err = strconv.ParseUint(d.Id(), 10, 64)
+		if err != nil {
+			return nil, err
+		}
+		nodeDeploymentID[nodeID] = contractID
+	}
+
+	dl := workloads.Deployment{
+		Name:             name,
+		NodeID:           nodeID,
+		SolutionProvider: solutionProvider,
+		SolutionType:     solutionType,
+		Disks:            disks,
+		Vms:              vms,
+		VmsLight:         vmsLight,
+		NetworkName:      networkName,
+		IPrange:          d.Get("ip_range").(string),
+		ContractID:       contractID,
+		NodeDeploymentID: nodeDeploymentID,
+	}
+
+	return &dl, nil
+}
+
+// syncContractsVM updates the terraform local state with the latest changes to the vm and its disks
+func syncContractsVM(r *schema.ResourceData, d *workloads.Deployment) (errors error) {
+	vms, err := vmsToMaps(d)
+	if err != nil {
+		return err
+	}
+
+	if len(vms) != 0 {
+		vmMap := vms[0].(map[string]interface{})
+
+		zlogs := make([]interface{}, 0)
+		if zlogsIf, ok := vmMap["zlogs"].([]interface{}); ok {
+			for _, zlog := range zlogsIf {
+				zlogs = append(zlogs, zlog.(map[string]interface{})["output"])
+			}
+		}
+		vmMap["zlogs"] = zlogs
+
+		for _, attr := range append(append([]string{"zlogs"}, vmConfigAttributes...), vmComputedAttributes...) {
+			// vm light workloads don't have public ips nor planetary ips
+			value, ok := vmMap[attr]
+			if !ok {
+				continue
+			}
+
+			if err := r.Set(attr, value); err != nil {
+				errors = multierror.Append(errors, fmt.Errorf("failed to set %s with error: %w", attr, err))
+			}
+		}
+	}
+
+	disks := make([]interface{}, 0)
+	for _, d := range d.Disks {
+		disk, err := workloads.ToMap(d)
+		if err != nil {
+			return err
+		}
+		disks = append(disks, disk)
+	}
+
+	err = r.Set("disks", disks)
+	if err != nil {
+		errors = multierror.Append(errors, fmt.Errorf("failed to set disks with error: %w", err))
+	}
+
+	err = r.Set("node", d.NodeID)
+	if err != nil {
+		errors = multierror.Append(errors, fmt.Errorf("failed to set node with error: %w", err))
+	}
+
+	err = r.Set("network_name", d.NetworkName)
+	if err != nil {
+		errors = multierror.Append(errors, fmt.Errorf("failed to set network name with error: %w", err))
+	}
+
+	err = r.Set("solution_type", d.SolutionType)
+	if err != nil {
+		errors = multierror.Append(errors, fmt.Errorf("failed to set solution type with error: %w", err))
+	}
+
+	var solutionProvider int
+	if d.SolutionProvider != nil {
+		solutionProvider = int(*d.SolutionProvider)
+	}
+	err = r.Set("solution_provider", solutionProvider)
+	if err != nil {
+		errors = multierror.Append(errors, fmt.Errorf("failed to set solution provider with error: %w", err))
+	}
+
+	err = r.Set("ip_range", d.IPrange)
+	if err != nil {
+		errors = multierror.Append(errors, fmt.Errorf("failed to set ip range with error: %w", err))
+	}
+
+	r.SetId(fmt.Sprint(d.ContractID))
+	return
+}
@@ -0,0 +1,125 @@
+// Package provider is the terraform provider
+package provider
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/hashicorp/go-multierror"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/threefoldtech/tfgrid-sdk-go/grid-client/workloads"
+)
+
+// newZDBDeploymentFromSchema reads the zdb resource configuration data from the schema.ResourceData, converts them into
+// a deployment holding the zdb, and returns this deployment.
+func newZDBDeploymentFromSchema(d *schema.ResourceData) (*workloads.Deployment, error) {
+	nodeID := uint32(d.Get("node").(int))
+
+	name := d.Get("name").(string)
+	solutionType := d.Get("solution_type").(string)
+	if solutionType == "" {
+		solutionType = fmt.Sprintf("zdb/%s", name)
+	}
+
+	zdb := workloads.ZDB{
+		Name:        name,
+		Password:    d.Get("password").(string),
+		Public:      d.Get("public").(bool),
+		SizeGB:      uint64(d.Get("size").(int)),
+		Description: d.Get("description").(string),
+		Mode:        d.Get("mode").(string),
+	}
+
+	solutionProviderVal := uint64(d.Get("solution_provider").(int))
+	var solutionProvider *uint64
+	if solutionProviderVal != 0 {
+		solutionProvider = &solutionProviderVal
+	}
+
+	var contractID uint64
+	var err error
+	nodeDeploymentID := map[uint32]uint64{}
+	if d.Id() != "" {
+		contractID, err = strconv.ParseUint(d.Id(), 10, 64)
+		if err != nil {
+			return nil, err
+		}
+		nodeDeploymentID[nodeID] = contractID
+	}
+
+	dl := workloads.Deployment{
+		Name:             name,
+		NodeID:           nodeID,
+		SolutionProvider: solutionProvider,
+		SolutionType:     solutionType,
+		Zdbs:             []workloads.ZDB{zdb},
+		ContractID:       contractID,
+		NodeDeploymentID: nodeDeploymentID,
+	}
+
+	return &dl, nil
+}
+
+// syncContractsZDB updates the terraform local state with the latest changes to the zdb
+func syncContractsZDB(r *schema.ResourceData, d *workloads.Deployment) (errors error) {
+	if len(d.Zdbs) != 0 {
+		zdb := d.Zdbs[0]
+
+		err := r.Set("size", zdb.SizeGB)
+		if err != nil {
+			errors = multierror.Append(errors, fmt.Errorf("failed to set size with error: %w", err))
+		}
+
+		err = r.Set("public", zdb.Public)
+		if err != nil {
+			errors = multierror.Append(errors, fmt.Errorf("failed to set public with error: %w", err))
+		}
+
+		err = r.Set("mode", zdb.Mode)
+		if err != nil {
+			errors = multierror.Append(errors, fmt.Errorf("failed to set mode with error: %w", err))
+		}
+
+		err = r.Set("description", zdb.Description)
+		if err != nil {
+			errors = multierror.Append(errors, fmt.Errorf("failed to set description with error: %w", err))
+		}
+
+		err = r.Set("ips", zdb.IPs)
+		if err != nil {
+			errors = multierror.Append(errors, fmt.Errorf("failed to set ips with error: %w", err))
+		}
+
+		err = r.Set("port", zdb.Port)
+		if err != nil {
+			errors = multierror.Append(errors, fmt.Errorf("failed to set port with error: %w", err))
+		}
+
+		err = r.Set("namespace", zdb.Namespace)
+		if err != nil {
+			errors = multierror.Append(errors, fmt.Errorf("failed to set namespace with error: %w", err))
+		}
+	}
+
+	err := r.Set("node", d.NodeID)
+	if err != nil {
+		errors = multierror.Append(errors, fmt.Errorf("failed to set node with error: %w", err))
+	}
+
+	err = r.Set("solution_type", d.SolutionType)
+	if err != nil {
+		errors = multierror.Append(errors, fmt.Errorf("failed to set solution type with error: %w", err))
+	}
+
+	var solutionProvider int
+	if d.SolutionProvider != nil {
+		solutionProvider = int(*d.SolutionProvider)
+	}
+	err = r.Set("solution_provider", solutionProvider)
+	if err != nil {
+		errors = multierror.Append(errors, fmt.Errorf("failed to set solution provider with error: %w", err))
+	}
+
+	r.SetId(fmt.Sprint(d.ContractID))
+	return
+}
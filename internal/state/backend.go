@@ -0,0 +1,217 @@
+package state
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+	"slices"
+	"sync"
+
+	"github.com/hashicorp/go-multierror"
+	"github.com/pkg/errors"
+)
+
+const (
+	// NoneBackend keeps the state in memory only, the networks subnets are rebuilt from terraform state and the grid.
+	// A state file left by older versions of the provider is read once as a migration source.
+	NoneBackend = "none"
+	// LocalBackend stores the state in a local file
+	LocalBackend = "local"
+	// HTTPBackend stores the state in a remote http server
+	HTTPBackend = "http"
+)
+
+// Backend is a state that could be loaded from and saved to a storage
+type Backend interface {
+	Getter
+	// Load loads the state from the storage
+	Load() error
+	// Save saves the state to the storage
+	Save() error
+}
+
+// Config is the configuration used to select and create a state backend
+type Config struct {
+	// Backend is the backend type, one of: none local http. Defaults to local
+	Backend string
+	// Path is the state file path or directory of the local backend, or the migration source of the none backend
+	Path string
+	// URL is the state url of the http backend
+	URL string
+	// Username is the basic auth username of the http backend
+	Username string
+	// Password is the basic auth password of the http backend
+	Password string
+	// LockURL is the lock url of the http backend, the state isn't locked if it's empty
+	LockURL string
+	// UnlockURL is the unlock url of the http backend, defaults to the lock url
+	UnlockURL string
+}
+
+// NewBackend creates a new state backend from the given config
+func NewBackend(cfg Config) (Backend, error) {
+	switch cfg.Backend {
+	case NoneBackend:
+		st := NewReadOnlyFileState(cfg.Path)
+		return &st, nil
+	case "", LocalBackend:
+		st := NewLocalFileState(cfg.Path)
+		return &st, nil
+	case HTTPBackend:
+		if cfg.URL == "" {
+			return nil, fmt.Errorf("url is required for the %s state backend", HTTPBackend)
+		}
+		st := NewHTTPState(cfg.URL, cfg.Username, cfg.Password)
+		if cfg.LockURL != "" {
+			st.WithLock(cfg.LockURL, cfg.UnlockURL)
+		}
+		return &st, nil
+	default:
+		return nil, fmt.Errorf("unsupported state backend: %s", cfg.Backend)
+	}
+}
+
+// Manager holds the state backends opened by the provider configurations, so they could be saved once the provider is done.
+// Aliased providers served by the same process share one opened backend for each state file or url, so they share
+// the same state and don't lock it against each other. A shared backend is saved and closed when its last user releases it.
+type Manager struct {
+	mu       sync.Mutex
+	backends map[string]*openedBackend
+	// keys are the keys of the opened backends in the order they were opened
+	keys []string
+}
+
+// openedBackend is a loaded backend and the number of providers using it
+type openedBackend struct {
+	backend Backend
+	refs    int
+}
+
+// NewManager generates a new state manager with no backend
+func NewManager() *Manager {
+	return &Manager{backends: make(map[string]*openedBackend)}
+}
+
+// backendKey identifies the storage of a backend, backends with the same key share one opened backend
+func backendKey(backend Backend) (string, error) {
+	switch b := backend.(type) {
+	case *LocalFileState:
+		path, err := filepath.Abs(b.Path())
+		if err != nil {
+			return "", errors.Wrapf(err, "failed to resolve state file path: %s", b.Path())
+		}
+
+		if b.readOnly {
+			return fmt.Sprintf("%s:%s", NoneBackend, path), nil
+		}
+		return fmt.Sprintf("%s:%s", LocalBackend, path), nil
+	case *HTTPState:
+		return fmt.Sprintf("%s:%s", HTTPBackend, b.url), nil
+	default:
+		return "", fmt.Errorf("unsupported state backend type: %T", backend)
+	}
+}
+
+// Open creates and loads the backend of the given config, and keeps it to be saved later.
+// If a backend of the same state file or url is already opened, it's returned instead, and it has to be released
+// once more before it's saved and closed
+func (m *Manager) Open(cfg Config) (Backend, error) {
+	backend, err := NewBackend(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := backendKey(backend)
+	if err != nil {
+		return nil, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if opened, ok := m.backends[key]; ok {
+		opened.refs++
+		return opened.backend, nil
+	}
+
+	if err := backend.Load(); err != nil {
+		// release the lock if it was acquired before failing to load the state
+		if closeErr := closeBackend(backend); closeErr != nil {
+			err = multierror.Append(err, closeErr)
+		}
+		return nil, err
+	}
+
+	m.backends[key] = &openedBackend{backend: backend, refs: 1}
+	m.keys = append(m.keys, key)
+
+	return backend, nil
+}
+
+// Release releases a backend returned by Open. The backend is saved and closed when all the providers sharing it released it
+func (m *Manager) Release(backend Backend) error {
+	key, err := backendKey(backend)
+	if err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	opened, ok := m.backends[key]
+	if !ok || opened.backend != backend {
+		return fmt.Errorf("state backend %s is not opened", key)
+	}
+
+	opened.refs--
+	if opened.refs > 0 {
+		return nil
+	}
+
+	m.remove(key)
+	return saveAndClose(backend)
+}
+
+// Save saves the opened backends that weren't released yet, and closes the ones holding resources like locks
+func (m *Manager) Save() (errors error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, key := range m.keys {
+		if err := saveAndClose(m.backends[key].backend); err != nil {
+			errors = multierror.Append(errors, err)
+		}
+	}
+
+	m.backends = make(map[string]*openedBackend)
+	m.keys = nil
+
+	return
+}
+
+// remove forgets the opened backend of the given key, the manager lock must be held
+func (m *Manager) remove(key string) {
+	delete(m.backends, key)
+	m.keys = slices.DeleteFunc(m.keys, func(k string) bool { return k == key })
+}
+
+// saveAndClose saves the backend, and closes it if it holds resources like locks
+func saveAndClose(backend Backend) (errs error) {
+	if err := backend.Save(); err != nil {
+		errs = multierror.Append(errs, err)
+	}
+
+	if err := closeBackend(backend); err != nil {
+		errs = multierror.Append(errs, err)
+	}
+
+	return
+}
+
+// closeBackend closes the backend if it holds resources like locks
+func closeBackend(backend Backend) error {
+	if closer, ok := backend.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
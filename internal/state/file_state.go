@@ -3,7 +3,9 @@ package state
 
 import (
 	"encoding/json"
+	"fmt"
 	"os"
+	"path/filepath"
 	"reflect"
 
 	"github.com/pkg/errors"
@@ -19,46 +21,99 @@ type Getter interface {
 const (
 	// FileName is a static file name for state that is generated beside the .tf file
 	FileName = "state.json"
+	// SchemaVersion is the current version of the state file schema.
+	// State files written before versioning was introduced have no version and are treated as version 0.
+	SchemaVersion = 1
+
+	lockFileSuffix = ".lock"
 )
 
+// ErrLocked is returned when the state file is locked by another process
+var ErrLocked = errors.New("state file is locked by another process")
+
+// fileContent is the schema of the state file
+type fileContent struct {
+	Version int                      `json:"version"`
+	State   map[string]state.Network `json:"State"`
+}
+
 // LocalFileState struct is the local state file
 type LocalFileState struct {
-	st *state.NetworkState
+	path     string
+	readOnly bool
+	lock     *os.File
+	st       *state.NetworkState
 }
 
-// NewLocalFileState generates a new local state
-func NewLocalFileState() LocalFileState {
-	return LocalFileState{}
+// NewLocalFileState generates a new local state stored at the given path.
+// If the path is empty, the state is stored in FileName in the current directory,
+// and if the path is a directory, the state is stored in FileName inside it.
+func NewLocalFileState(path string) LocalFileState {
+	if path == "" {
+		path = FileName
+	}
+
+	if info, err := os.Stat(path); err == nil && info.IsDir() {
+		path = filepath.Join(path, FileName)
+	}
 
+	return LocalFileState{path: path}
 }
 
-// Load loads state from state.json file
-func (f *LocalFileState) Load(FileName string) error {
-	// os.OpenFile(FileName, os.O_CREATE, 0644)
-	f.st = &state.NetworkState{}
-	_, err := os.Stat(FileName)
-	if err != nil && os.IsNotExist(err) {
-		_, err = os.OpenFile(FileName, os.O_CREATE, 0644)
-		if err != nil {
+// NewReadOnlyFileState generates a local state that is only read from the given path and never written.
+// It is used to migrate the subnets of state files written by older versions of the provider.
+func NewReadOnlyFileState(path string) LocalFileState {
+	st := NewLocalFileState(path)
+	st.readOnly = true
+	return st
+}
+
+// Path returns the path of the state file
+func (f *LocalFileState) Path() string {
+	return f.path
+}
+
+// Load acquires the state file lock and loads state from the state file.
+// The lock is held until Close is called or the process exits, so that two
+// processes can't modify the same state file at the same time.
+// Aliased providers served by the same process share one loaded state through the Manager, so they don't lock the file twice.
+// Read only states are loaded without acquiring the lock.
+func (f *LocalFileState) Load() error {
+	if !f.readOnly {
+		if err := f.acquireLock(); err != nil {
 			return err
 		}
+	}
+
+	f.st = &state.NetworkState{}
+	content, err := os.ReadFile(f.path)
+	if os.IsNotExist(err) {
 		return nil
 	}
-	content, err := os.ReadFile(FileName)
 	if err != nil {
-		return err
+		return errors.Wrapf(err, "failed to read file: %s", f.path)
 	}
 
-	err = json.Unmarshal(content, &f.st)
-	if err != nil {
-		return err
+	if len(content) == 0 {
+		return nil
+	}
+
+	var fc fileContent
+	if err := json.Unmarshal(content, &fc); err != nil {
+		return errors.Wrapf(err, "failed to parse file: %s", f.path)
+	}
+
+	if fc.Version > SchemaVersion {
+		return fmt.Errorf("state file %s has schema version %d, which is newer than the supported version %d", f.path, fc.Version, SchemaVersion)
 	}
+
+	f.st.State = fc.State
 	return nil
 }
 
 // GetState returns the current state
 func (f *LocalFileState) GetState() *state.NetworkState {
-	if reflect.DeepEqual(f.st, &state.NetworkState{}) {
+	if f.st == nil || reflect.DeepEqual(f.st, &state.NetworkState{}) {
 		state := &state.NetworkState{
 			State: make(map[string]state.Network),
 		}
@@ -67,20 +122,95 @@ func (f *LocalFileState) GetState() *state.NetworkState {
 	return f.st
 }
 
-// Save saves the state to the state,json file
-func (f *LocalFileState) Save(FileName string) error {
-	if content, err := json.Marshal(f.st); err == nil {
-		err = os.WriteFile(FileName, content, 0644)
-		if err != nil {
-			return errors.Wrapf(err, "failed to write file: %s", FileName)
-		}
-	} else {
-		return errors.Wrapf(err, "failed to save file: %s", FileName)
+// Save saves the state to the state file.
+// The state is written to a temporary file first, then renamed over the state file,
+// so a crash in the middle of writing never leaves a partially written state file.
+func (f *LocalFileState) Save() error {
+	if f.readOnly {
+		return nil
+	}
+
+	if f.lock == nil {
+		return fmt.Errorf("failed to save file: %s, state lock is not held", f.path)
+	}
+
+	content, err := json.Marshal(fileContent{Version: SchemaVersion, State: f.GetState().State})
+	if err != nil {
+		return errors.Wrapf(err, "failed to save file: %s", f.path)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(f.path), filepath.Base(f.path)+".*.tmp")
+	if err != nil {
+		return errors.Wrapf(err, "failed to create temporary file for: %s", f.path)
 	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(content); err != nil {
+		tmp.Close()
+		return errors.Wrapf(err, "failed to write file: %s", tmp.Name())
+	}
+
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return errors.Wrapf(err, "failed to sync file: %s", tmp.Name())
+	}
+
+	if err := tmp.Close(); err != nil {
+		return errors.Wrapf(err, "failed to close file: %s", tmp.Name())
+	}
+
+	if err := os.Chmod(tmp.Name(), 0644); err != nil {
+		return errors.Wrapf(err, "failed to set permissions of file: %s", tmp.Name())
+	}
+
+	if err := os.Rename(tmp.Name(), f.path); err != nil {
+		return errors.Wrapf(err, "failed to write file: %s", f.path)
+	}
+
 	return nil
 }
 
-// Delete deletes state,json file
-func (f *LocalFileState) Delete(FileName string) error {
-	return os.Remove(FileName)
+// Close releases the state file lock
+func (f *LocalFileState) Close() error {
+	if f.lock == nil {
+		return nil
+	}
+
+	lock := f.lock
+	f.lock = nil
+
+	if err := unlockFile(lock); err != nil {
+		lock.Close()
+		return errors.Wrapf(err, "failed to unlock file: %s", lock.Name())
+	}
+
+	return lock.Close()
+}
+
+// Delete deletes the state file
+func (f *LocalFileState) Delete() error {
+	return os.Remove(f.path)
+}
+
+func (f *LocalFileState) acquireLock() error {
+	if f.lock != nil {
+		return nil
+	}
+
+	lockPath := f.path + lockFileSuffix
+	lock, err := os.OpenFile(lockPath, os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		return errors.Wrapf(err, "failed to open lock file: %s", lockPath)
+	}
+
+	if err := lockFile(lock); err != nil {
+		lock.Close()
+		if errors.Is(err, ErrLocked) {
+			return fmt.Errorf("%w: %s is held, make sure no other terraform run is using the same state file %s", ErrLocked, lockPath, f.path)
+		}
+		return errors.Wrapf(err, "failed to lock file: %s", lockPath)
+	}
+
+	f.lock = lock
+	return nil
 }
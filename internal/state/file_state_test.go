@@ -0,0 +1,205 @@
+package state
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/threefoldtech/tfgrid-sdk-go/grid-client/state"
+)
+
+func TestNewLocalFileStatePath(t *testing.T) {
+	dir := t.TempDir()
+
+	st := NewLocalFileState(dir)
+	assert.Equal(t, filepath.Join(dir, FileName), st.Path(), "a directory path should hold the default file name")
+
+	path := filepath.Join(dir, "custom.json")
+	st = NewLocalFileState(path)
+	assert.Equal(t, path, st.Path())
+
+	st = NewLocalFileState("")
+	assert.Equal(t, FileName, st.Path())
+}
+
+func TestLocalFileStateSaveLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), FileName)
+
+	st := NewLocalFileState(path)
+	require.NoError(t, st.Load())
+	assert.Empty(t, st.GetState().State, "a missing file should be loaded as an empty state")
+
+	st.GetState().State["net"] = state.Network{Subnets: map[uint32]string{1: "10.1.2.0/24"}}
+	require.NoError(t, st.Save())
+	require.NoError(t, st.Close())
+
+	content, err := os.ReadFile(path)
+	require.NoError(t, err)
+
+	var fc fileContent
+	require.NoError(t, json.Unmarshal(content, &fc))
+	assert.Equal(t, SchemaVersion, fc.Version, "the file should be written with the current schema version")
+	assert.Equal(t, st.GetState().State, fc.State)
+
+	loaded := NewLocalFileState(path)
+	require.NoError(t, loaded.Load())
+	defer loaded.Close()
+	assert.Equal(t, st.GetState(), loaded.GetState())
+}
+
+func TestLocalFileStateVersions(t *testing.T) {
+	cases := map[string]struct {
+		content string
+		valid   bool
+	}{
+		"unversioned": {`{"State": {"net": {"Subnets": {"1": "10.1.2.0/24"}}}}`, true},
+		"current":     {`{"version": 1, "State": {"net": {"Subnets": {"1": "10.1.2.0/24"}}}}`, true},
+		"newer":       {`{"version": 2, "State": {}}`, false},
+		"invalid":     {`{"State": `, false},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), FileName)
+			require.NoError(t, os.WriteFile(path, []byte(c.content), 0644))
+
+			st := NewLocalFileState(path)
+			defer st.Close()
+
+			err := st.Load()
+			if !c.valid {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, "10.1.2.0/24", st.GetState().State["net"].Subnets[1])
+		})
+	}
+}
+
+func TestLocalFileStateLock(t *testing.T) {
+	path := filepath.Join(t.TempDir(), FileName)
+
+	first := NewLocalFileState(path)
+	require.NoError(t, first.Load())
+
+	// a second state of the same file in the same process, like an aliased provider, is locked out too
+	second := NewLocalFileState(path)
+	err := second.Load()
+	assert.True(t, errors.Is(err, ErrLocked), "the file is locked by the first state: %v", err)
+	assert.Error(t, second.Save(), "saving without holding the lock should fail")
+
+	require.NoError(t, first.Close())
+	require.NoError(t, second.Load(), "the lock should be released on close")
+	require.NoError(t, second.Close())
+}
+
+func TestLocalFileStateAtomicSave(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, FileName)
+	require.NoError(t, os.WriteFile(path, []byte(`{"version": 1, "State": {"old": {}}}`), 0600))
+
+	st := NewLocalFileState(path)
+	require.NoError(t, st.Load())
+	defer st.Close()
+
+	st.GetState().State["net"] = state.Network{Subnets: map[uint32]string{1: "10.1.2.0/24"}}
+	require.NoError(t, st.Save())
+
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	names := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+	assert.ElementsMatch(t, []string{FileName, FileName + lockFileSuffix}, names, "no temporary file should be left")
+
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0644), info.Mode().Perm())
+
+	loaded := NewReadOnlyFileState(path)
+	require.NoError(t, loaded.Load())
+	assert.Contains(t, loaded.GetState().State, "net")
+	assert.Contains(t, loaded.GetState().State, "old")
+}
+
+func TestReadOnlyFileState(t *testing.T) {
+	path := filepath.Join(t.TempDir(), FileName)
+	require.NoError(t, os.WriteFile(path, []byte(`{"State": {"net": {}}}`), 0644))
+
+	locked := NewLocalFileState(path)
+	require.NoError(t, locked.Load())
+	defer locked.Close()
+
+	st := NewReadOnlyFileState(path)
+	require.NoError(t, st.Load(), "read only states don't acquire the lock")
+	assert.Contains(t, st.GetState().State, "net")
+
+	st.GetState().State["other"] = state.Network{}
+	require.NoError(t, st.Save())
+
+	content, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.NotContains(t, string(content), "other", "read only states are never written")
+}
+
+func TestManagerSavesAllBackends(t *testing.T) {
+	dir := t.TempDir()
+	paths := []string{filepath.Join(dir, "a.json"), filepath.Join(dir, "b.json")}
+
+	m := NewManager()
+	for _, path := range paths {
+		backend, err := m.Open(Config{Backend: LocalBackend, Path: path})
+		require.NoError(t, err)
+		backend.GetState().State[filepath.Base(path)] = state.Network{}
+	}
+
+	alias, err := m.Open(Config{Backend: LocalBackend, Path: paths[0]})
+	require.NoError(t, err, "aliased providers share the opened state file")
+	assert.Contains(t, alias.GetState().State, filepath.Base(paths[0]))
+	alias.GetState().State["alias"] = state.Network{}
+
+	require.NoError(t, m.Save())
+	for _, path := range paths {
+		content, err := os.ReadFile(path)
+		require.NoError(t, err)
+		assert.Contains(t, string(content), filepath.Base(path))
+	}
+
+	content, err := os.ReadFile(paths[0])
+	require.NoError(t, err)
+	assert.Contains(t, string(content), "alias")
+}
+
+func TestManagerRelease(t *testing.T) {
+	path := filepath.Join(t.TempDir(), FileName)
+
+	m := NewManager()
+	first, err := m.Open(Config{Backend: LocalBackend, Path: path})
+	require.NoError(t, err)
+
+	second, err := m.Open(Config{Path: filepath.Dir(path)})
+	require.NoError(t, err)
+	require.True(t, first == second, "configs resolving to the same state file share one backend")
+	second.GetState().State["net"] = state.Network{}
+
+	require.NoError(t, m.Release(first))
+	_, err = os.Stat(path)
+	assert.True(t, errors.Is(err, os.ErrNotExist), "the state is saved only when the last provider releases it")
+
+	require.NoError(t, m.Release(second))
+	content, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Contains(t, string(content), "net")
+
+	other := NewLocalFileState(path)
+	require.NoError(t, other.Load(), "the lock is released with the last provider")
+	require.NoError(t, other.Close())
+
+	assert.Error(t, m.Release(first), "released backends are not opened anymore")
+}
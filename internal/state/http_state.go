@@ -0,0 +1,268 @@
+package state
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"os/user"
+	"reflect"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+	"github.com/threefoldtech/tfgrid-sdk-go/grid-client/state"
+)
+
+const (
+	httpStateTimeout = 30 * time.Second
+
+	// lockMethod and unlockMethod are the methods used to lock and unlock the state, the same as the terraform http backend
+	lockMethod   = "LOCK"
+	unlockMethod = "UNLOCK"
+)
+
+// lockInfo is the body of the lock and unlock requests, it follows the lock info of the terraform http backend
+type lockInfo struct {
+	ID        string    `json:"ID"`
+	Operation string    `json:"Operation"`
+	Who       string    `json:"Who"`
+	Created   time.Time `json:"Created"`
+	Path      string    `json:"Path"`
+}
+
+// HTTPState is a state stored in a remote http server.
+// The state is fetched with a GET request to the url, and stored with a POST request to the same url.
+// A 404 or 204 response on GET is treated as an empty state.
+//
+// If a lock url is set, the state is locked with a LOCK request to the lock url when loaded, and unlocked with
+// an UNLOCK request to the unlock url when closed, the same way as the terraform http backend.
+// A 409 or 423 response on LOCK means the state is locked by another process.
+// Without a lock url the state isn't locked, so two concurrent runs could overwrite each other's subnets reservations.
+type HTTPState struct {
+	url       string
+	lockURL   string
+	unlockURL string
+	username  string
+	password  string
+	client    *http.Client
+	lock      *lockInfo
+	st        *state.NetworkState
+}
+
+// NewHTTPState generates a new http state, username and password are used for basic authentication if set
+func NewHTTPState(url, username, password string) HTTPState {
+	return HTTPState{
+		url:      url,
+		username: username,
+		password: password,
+		client:   &http.Client{Timeout: httpStateTimeout},
+	}
+}
+
+// WithLock enables locking the state using the given lock and unlock urls, the unlock url defaults to the lock url
+func (h *HTTPState) WithLock(lockURL, unlockURL string) {
+	if unlockURL == "" {
+		unlockURL = lockURL
+	}
+	h.lockURL = lockURL
+	h.unlockURL = unlockURL
+}
+
+// Load acquires the state lock if locking is enabled, and loads state from the remote server.
+// The lock is held until Close is called
+func (h *HTTPState) Load() error {
+	if err := h.acquireLock(); err != nil {
+		return err
+	}
+
+	h.st = &state.NetworkState{}
+
+	req, err := h.newRequest(http.MethodGet, h.url, nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := h.client.Do(req)
+	if err != nil {
+		return errors.Wrapf(err, "failed to get state from: %s", h.url)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusNoContent {
+		return nil
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("failed to get state from: %s, unexpected status: %s", h.url, resp.Status)
+	}
+
+	content, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return errors.Wrapf(err, "failed to read state from: %s", h.url)
+	}
+
+	if len(bytes.TrimSpace(content)) == 0 {
+		return nil
+	}
+
+	return json.Unmarshal(content, &h.st)
+}
+
+// GetState returns the current state
+func (h *HTTPState) GetState() *state.NetworkState {
+	if h.st == nil || reflect.DeepEqual(h.st, &state.NetworkState{}) {
+		h.st = &state.NetworkState{
+			State: make(map[string]state.Network),
+		}
+	}
+	return h.st
+}
+
+// Save saves the state to the remote server
+func (h *HTTPState) Save() error {
+	content, err := json.Marshal(h.st)
+	if err != nil {
+		return errors.Wrapf(err, "failed to marshal state for: %s", h.url)
+	}
+
+	updateURL, err := h.updateURL()
+	if err != nil {
+		return err
+	}
+
+	req, err := h.newRequest(http.MethodPost, updateURL, content)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := h.client.Do(req)
+	if err != nil {
+		return errors.Wrapf(err, "failed to save state to: %s", h.url)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("failed to save state to: %s, unexpected status: %s", h.url, resp.Status)
+	}
+
+	return nil
+}
+
+// updateURL returns the url the state is saved to. While the state is locked, the lock id is sent in the ID query parameter,
+// the same as the terraform http backend, so servers enforcing the lock accept the update
+func (h *HTTPState) updateURL() (string, error) {
+	if h.lock == nil {
+		return h.url, nil
+	}
+
+	u, err := url.Parse(h.url)
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to parse state url: %s", h.url)
+	}
+
+	query := u.Query()
+	query.Set("ID", h.lock.ID)
+	u.RawQuery = query.Encode()
+
+	return u.String(), nil
+}
+
+// Close releases the state lock if held
+func (h *HTTPState) Close() error {
+	if h.lock == nil {
+		return nil
+	}
+
+	content, err := json.Marshal(h.lock)
+	if err != nil {
+		return errors.Wrapf(err, "failed to marshal lock info for: %s", h.unlockURL)
+	}
+
+	req, err := h.newRequest(unlockMethod, h.unlockURL, content)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := h.client.Do(req)
+	if err != nil {
+		return errors.Wrapf(err, "failed to unlock state at: %s", h.unlockURL)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("failed to unlock state at: %s, unexpected status: %s", h.unlockURL, resp.Status)
+	}
+
+	h.lock = nil
+	return nil
+}
+
+func (h *HTTPState) acquireLock() error {
+	if h.lockURL == "" || h.lock != nil {
+		return nil
+	}
+
+	info := lockInfo{
+		ID:        uuid.NewString(),
+		Operation: "provider",
+		Who:       lockOwner(),
+		Created:   time.Now().UTC(),
+		Path:      h.url,
+	}
+
+	content, err := json.Marshal(info)
+	if err != nil {
+		return errors.Wrapf(err, "failed to marshal lock info for: %s", h.lockURL)
+	}
+
+	req, err := h.newRequest(lockMethod, h.lockURL, content)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := h.client.Do(req)
+	if err != nil {
+		return errors.Wrapf(err, "failed to lock state at: %s", h.lockURL)
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		h.lock = &info
+		return nil
+	case http.StatusConflict, http.StatusLocked:
+		holder, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("%w: %s is locked, make sure no other terraform run is using the same state, lock info: %s", ErrLocked, h.lockURL, bytes.TrimSpace(holder))
+	default:
+		return fmt.Errorf("failed to lock state at: %s, unexpected status: %s", h.lockURL, resp.Status)
+	}
+}
+
+// lockOwner returns the user and host holding the lock
+func lockOwner() string {
+	host, _ := os.Hostname()
+	if u, err := user.Current(); err == nil {
+		return fmt.Sprintf("%s@%s", u.Username, host)
+	}
+	return host
+}
+
+func (h *HTTPState) newRequest(method, url string, body []byte) (*http.Request, error) {
+	req, err := http.NewRequest(method, url, bytes.NewReader(body))
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to create request for: %s", url)
+	}
+
+	if h.username != "" || h.password != "" {
+		req.SetBasicAuth(h.username, h.password)
+	}
+
+	return req, nil
+}
@@ -0,0 +1,147 @@
+package state
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/threefoldtech/tfgrid-sdk-go/grid-client/state"
+)
+
+// httpStateServer is a minimal http state server locking the state like the terraform http backend servers
+type httpStateServer struct {
+	mu       sync.Mutex
+	content  []byte
+	lockID   string
+	username string
+	password string
+}
+
+func (s *httpStateServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if username, password, _ := r.BasicAuth(); username != s.username || password != s.password {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	body, _ := io.ReadAll(r.Body)
+	switch r.Method {
+	case http.MethodGet:
+		if s.content == nil {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_, _ = w.Write(s.content)
+	case http.MethodPost:
+		if s.lockID != "" && r.URL.Query().Get("ID") != s.lockID {
+			w.WriteHeader(http.StatusConflict)
+			return
+		}
+		s.content = body
+	case lockMethod:
+		var info lockInfo
+		if err := json.Unmarshal(body, &info); err != nil || info.ID == "" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		if s.lockID != "" {
+			w.WriteHeader(http.StatusLocked)
+			_, _ = w.Write([]byte(s.lockID))
+			return
+		}
+		s.lockID = info.ID
+	case unlockMethod:
+		var info lockInfo
+		if err := json.Unmarshal(body, &info); err != nil || info.ID != s.lockID {
+			w.WriteHeader(http.StatusConflict)
+			return
+		}
+		s.lockID = ""
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func TestHTTPStateSaveLoad(t *testing.T) {
+	handler := &httpStateServer{username: "user", password: "pass"}
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	st := NewHTTPState(server.URL, "user", "pass")
+	require.NoError(t, st.Load())
+	assert.Empty(t, st.GetState().State, "a missing state should be loaded as an empty state")
+
+	st.GetState().State["net"] = state.Network{Subnets: map[uint32]string{1: "10.1.2.0/24"}}
+	require.NoError(t, st.Save())
+
+	loaded := NewHTTPState(server.URL, "user", "pass")
+	require.NoError(t, loaded.Load())
+	assert.Equal(t, st.GetState(), loaded.GetState())
+
+	unauthorized := NewHTTPState(server.URL, "user", "wrong")
+	assert.Error(t, unauthorized.Load())
+}
+
+func TestHTTPStateLock(t *testing.T) {
+	handler := &httpStateServer{}
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	first := NewHTTPState(server.URL, "", "")
+	first.WithLock(server.URL, "")
+	require.NoError(t, first.Load())
+	assert.NotEmpty(t, handler.lockID, "the state should be locked on load")
+
+	second := NewHTTPState(server.URL, "", "")
+	second.WithLock(server.URL, "")
+	err := second.Load()
+	assert.True(t, errors.Is(err, ErrLocked), "the state is locked by the first run: %v", err)
+
+	require.NoError(t, first.Save(), "the lock holder should be able to save the state")
+
+	unlocked := NewHTTPState(server.URL, "", "")
+	unlocked.st = &state.NetworkState{}
+	assert.Error(t, unlocked.Save(), "saving without the lock id should be rejected while the state is locked")
+
+	wrongLock := NewHTTPState(server.URL, "", "")
+	wrongLock.st = &state.NetworkState{}
+	wrongLock.lock = &lockInfo{ID: "wrong"}
+	assert.Error(t, wrongLock.Save(), "saving with a wrong lock id should be rejected while the state is locked")
+
+	require.NoError(t, first.Close())
+	assert.Empty(t, handler.lockID, "the state should be unlocked on close")
+
+	require.NoError(t, second.Load())
+	require.NoError(t, second.Close())
+}
+
+func TestHTTPStateUnlocked(t *testing.T) {
+	handler := &httpStateServer{}
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	first := NewHTTPState(server.URL, "", "")
+	second := NewHTTPState(server.URL, "", "")
+	require.NoError(t, first.Load())
+	require.NoError(t, second.Load(), "the state isn't locked without a lock url")
+	assert.Empty(t, handler.lockID)
+	assert.NoError(t, first.Close())
+}
+
+func TestManagerReleasesLockOnLoadFailure(t *testing.T) {
+	handler := &httpStateServer{content: []byte("not json")}
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	_, err := NewManager().Open(Config{Backend: HTTPBackend, URL: server.URL, LockURL: server.URL})
+	assert.Error(t, err)
+	assert.Empty(t, handler.lockID, "the lock should be released if the state couldn't be loaded")
+}
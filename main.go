@@ -34,16 +34,8 @@ func main() {
 	flag.BoolVar(&debugMode, "debug", false, "set to true to run the provider with support for debuggers like delve")
 	flag.Parse()
 
-	stateFile := state.NewLocalFileState()
-	err := stateFile.Load(state.FileName)
-	if err != nil {
-		log.Fatal(err.Error())
-	}
-
-	providerFunc, sub := provider.New(version, &stateFile)
-	if sub != nil {
-		defer sub.Close()
-	}
+	st := state.NewManager()
+	providerFunc, closeClients := provider.New(version, st)
 	opts := &plugin.ServeOpts{ProviderFunc: providerFunc}
 
 	if debugMode {
@@ -51,11 +43,13 @@ func main() {
 		// TODO: update this string with the full name of your provider as used in your configs
 		opts.ProviderAddr = "registry.terraform.io/hashicorp/scaffolding"
 		plugin.Serve(opts)
+		closeClients()
 		return
 	}
 
 	plugin.Serve(opts)
-	err = stateFile.Save(state.FileName)
+	closeClients()
+	err := st.Save()
 	if err != nil {
 		log.Fatal(err.Error())
 	}